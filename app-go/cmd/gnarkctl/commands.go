@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Artifact 证明生成产物, 与 chaincode-go/gnarkverify/output 中的 json 文件格式一致
+type Artifact struct {
+	VK            string `json:"vk"`
+	Proof         string `json:"proof"`
+	WitnessPublic string `json:"witnessPublic"`
+}
+
+// verifyRequest 与链码 VerifyProof 的请求格式一致
+type verifyRequest struct {
+	Protocol      string `json:"protocol"`
+	Curve         string `json:"curve"`
+	VK            string `json:"vk,omitempty"`
+	VKID          string `json:"vkId,omitempty"`
+	Proof         string `json:"proof"`
+	WitnessPublic string `json:"witnessPublic"`
+}
+
+// verifyResponse 链码 VerifyProof 返回结果中客户端关心的字段
+type verifyResponse struct {
+	Valid     bool   `json:"valid"`
+	Reason    string `json:"reason,omitempty"`
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+// txMode 写交易的执行方式, 由各写命令共享的 --evaluate 和 --wait 参数决定
+type txMode struct {
+	evaluate bool
+	wait     bool
+}
+
+func (m *txMode) register(flags *flag.FlagSet) {
+	flags.BoolVar(&m.evaluate, "evaluate", false, "evaluate on the gateway peer only, without submitting to the orderer")
+	flags.BoolVar(&m.wait, "wait", true, "wait for the commit status after submitting")
+}
+
+// invoke 按执行方式调用交易, 提交时在标准错误输出交易 ID 和提交状态
+func (m *txMode) invoke(s *session, stderr io.Writer, name string, args ...string) ([]byte, error) {
+	if m.evaluate {
+		return s.evaluate(name, args...)
+	}
+	result, txID, err := s.submit(name, m.wait, args...)
+	if err != nil {
+		return nil, err
+	}
+	if m.wait {
+		fmt.Fprintf(stderr, "transaction %s committed\n", txID)
+	} else {
+		fmt.Fprintf(stderr, "transaction %s submitted\n", txID)
+	}
+	return result, nil
+}
+
+func readArtifact(path string) (*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proof file: %v", err)
+	}
+	var artifact Artifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal proof file %s: %v", path, err)
+	}
+	return &artifact, nil
+}
+
+// requireFlags 检查必填参数
+func requireFlags(flags *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if flags.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("%s: --%s is required", flags.Name(), name)
+		}
+	}
+	return nil
+}
+
+// printJSON 缩进输出链码返回的 json, 非 json 结果原样输出
+func printJSON(stdout io.Writer, result []byte) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		out.Reset()
+		out.Write(result)
+	}
+	out.WriteByte('\n')
+	stdout.Write(out.Bytes())
+}
+
+func runInfo(s *session, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	result, err := s.evaluate("GetContractInfo")
+	if err != nil {
+		return err
+	}
+	printJSON(stdout, result)
+	return nil
+}
+
+// newVerifyRequest 由证明文件构造请求, 指定 vkID 时使用已登记的密钥而不携带 vk
+func newVerifyRequest(protocol, curve, vkID string, artifact *Artifact) *verifyRequest {
+	request := &verifyRequest{
+		Protocol:      protocol,
+		Curve:         curve,
+		VKID:          vkID,
+		Proof:         artifact.Proof,
+		WitnessPublic: artifact.WitnessPublic,
+	}
+	if vkID == "" {
+		request.VK = artifact.VK
+	}
+	return request
+}
+
+func runVerify(s *session, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	protocol := flags.String("protocol", "", "proof system: groth16 or plonk")
+	curve := flags.String("curve", "", "curve name, e.g. BN254")
+	file := flags.String("file", "", "proof file containing vk, proof and witnessPublic")
+	vkID := flags.String("vk-id", "", "verify with a registered verifying key instead of the vk in the file")
+	var mode txMode
+	mode.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "protocol", "curve", "file"); err != nil {
+		return err
+	}
+
+	artifact, err := readArtifact(*file)
+	if err != nil {
+		return err
+	}
+	requestJSON, err := json.Marshal(newVerifyRequest(*protocol, *curve, *vkID, artifact))
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify request: %v", err)
+	}
+	result, err := mode.invoke(s, stderr, "VerifyProof", string(requestJSON))
+	if err != nil {
+		return err
+	}
+	printJSON(stdout, result)
+
+	var response verifyResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal verify response: %v", err)
+	}
+	if !response.Valid {
+		return fmt.Errorf("proof rejected (%s): %s", response.ErrorCode, response.Reason)
+	}
+	return nil
+}
+
+func runRegisterVK(s *session, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("register-vk", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	id := flags.String("id", "", "verifying key id")
+	protocol := flags.String("protocol", "", "proof system: groth16 or plonk")
+	curve := flags.String("curve", "", "curve name, e.g. BN254")
+	file := flags.String("file", "", "proof file containing the vk")
+	config := flags.String("config", "", "verifying key config json submitted with the registration")
+	var mode txMode
+	mode.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(flags, "id", "protocol", "curve", "file"); err != nil {
+		return err
+	}
+
+	artifact, err := readArtifact(*file)
+	if err != nil {
+		return err
+	}
+	if _, err := mode.invoke(s, stderr, "RegisterVerifyingKey", *id, *protocol, *curve, artifact.VK, *config); err != nil {
+		return err
+	}
+	if mode.evaluate {
+		return nil
+	}
+	result, err := s.evaluate("GetVerifyingKey", *id)
+	if err != nil {
+		return err
+	}
+	printJSON(stdout, result)
+	return nil
+}
+
+func runQueryRecord(s *session, args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("query-record", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	txID := flags.String("tx-id", "", "id of the transaction that verified the proof")
+	proofHash := flags.String("proof-hash", "", "sha256 hex of the proof bytes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var result []byte
+	var err error
+	switch {
+	case *txID != "" && *proofHash != "":
+		return fmt.Errorf("query-record: --tx-id and --proof-hash must not both be set")
+	case *txID != "":
+		result, err = s.evaluate("GetVerificationRecord", *txID)
+	case *proofHash != "":
+		result, err = s.evaluate("GetVerificationRecordByProofHash", *proofHash)
+	default:
+		return fmt.Errorf("query-record: --tx-id or --proof-hash is required")
+	}
+	if err != nil {
+		return err
+	}
+	printJSON(stdout, result)
+	return nil
+}
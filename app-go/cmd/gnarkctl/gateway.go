@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// session 一次命令使用的网关连接
+type session struct {
+	conn     *grpc.ClientConn
+	gateway  *client.Gateway
+	contract *client.Contract
+}
+
+func newGrpcConnection(peer PeerConfig) (*grpc.ClientConn, error) {
+	certPEM, err := readPathFile(peer.TLSCACert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tls ca certificate: %v", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tls ca certificate: %v", err)
+	}
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, peer.HostAlias)
+
+	conn, err := grpc.NewClient(peer.Endpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grpc connection to %s: %v", peer.Endpoint, err)
+	}
+	return conn, nil
+}
+
+func newIdentity(mspID string, config IdentityConfig) (*identity.X509Identity, identity.Sign, error) {
+	certPEM, err := readPathFile(config.Cert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read client certificate: %v", err)
+	}
+	certificate, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse client certificate: %v", err)
+	}
+	id, err := identity.NewX509Identity(mspID, certificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create identity: %v", err)
+	}
+
+	keyPEM, err := readPathFile(config.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key: %v", err)
+	}
+	privateKey, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create signer: %v", err)
+	}
+	return id, sign, nil
+}
+
+// connect 按连接配置连接网关并获取链码合约
+func connect(profile *Profile) (*session, error) {
+	timeouts, err := profile.timeouts()
+	if err != nil {
+		return nil, err
+	}
+	id, sign, err := newIdentity(profile.MSPID, profile.Identity)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := newGrpcConnection(profile.Peer)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway, err := client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(conn),
+		client.WithEvaluateTimeout(timeouts.Evaluate),
+		client.WithEndorseTimeout(timeouts.Endorse),
+		client.WithSubmitTimeout(timeouts.Submit),
+		client.WithCommitStatusTimeout(timeouts.CommitStatus),
+	)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect gateway: %v", err)
+	}
+	contract := gateway.GetNetwork(profile.Channel).GetContract(profile.Chaincode)
+	return &session{conn: conn, gateway: gateway, contract: contract}, nil
+}
+
+func (s *session) close() {
+	s.gateway.Close()
+	s.conn.Close()
+}
+
+// evaluate 只在网关节点上执行交易, 不提交排序
+func (s *session) evaluate(name string, args ...string) ([]byte, error) {
+	result, err := s.contract.EvaluateTransaction(name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %v", name, err)
+	}
+	return result, nil
+}
+
+// submit 背书并提交交易, wait 为 true 时等待提交状态并在交易无效时报错
+func (s *session) submit(name string, wait bool, args ...string) ([]byte, string, error) {
+	result, commit, err := s.contract.SubmitAsync(name, client.WithArguments(args...))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to submit %s: %v", name, err)
+	}
+	txID := commit.TransactionID()
+	if !wait {
+		return result, txID, nil
+	}
+	status, err := commit.Status()
+	if err != nil {
+		return nil, txID, fmt.Errorf("failed to get commit status of %s: %v", txID, err)
+	}
+	if !status.Successful {
+		return nil, txID, fmt.Errorf("transaction %s failed to commit with status code %d (%s)", txID, int32(status.Code), status.Code)
+	}
+	return result, txID, nil
+}
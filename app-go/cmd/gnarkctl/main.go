@@ -0,0 +1,80 @@
+// gnarkctl 通过 Fabric Gateway 调用 gnarkverify 链码
+//
+//	gnarkctl [--profile profile.json] <command> [flags]
+//
+// 命令:
+//
+//	info          查询合约信息及支持的协议与曲线
+//	verify        验证证明文件, 默认提交交易并等待提交状态
+//	register-vk   登记证明文件中的验证密钥
+//	query-record  按交易 ID 或证明哈希查询验证记录
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(s *session, args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"info", "query contract info and supported schemes", runInfo},
+	{"verify", "verify a proof file, submitting by default", runVerify},
+	{"register-vk", "register the verifying key of a proof file", runRegisterVK},
+	{"query-record", "query a verification record by tx id or proof hash", runQueryRecord},
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: gnarkctl [--profile profile.json] <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-13s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	flags.PrintDefaults()
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gnarkctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	profileFlag := flags.String("profile", "", "connection profile, defaults to $GNARKCTL_PROFILE or "+defaultProfilePath)
+	flags.Usage = func() { usage(stderr, flags) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	name := flags.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		profile, err := loadProfile(profilePath(*profileFlag))
+		if err != nil {
+			return err
+		}
+		s, err := connect(profile)
+		if err != nil {
+			return err
+		}
+		defer s.close()
+		return cmd.run(s, flags.Args()[1:], stdout, stderr)
+	}
+	flags.Usage()
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "gnarkctl:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultProfilePath 未指定 --profile 且未设置 GNARKCTL_PROFILE 时使用的连接配置
+const defaultProfilePath = "profiles/test-network.json"
+
+// PeerConfig 网关节点地址及其 TLS 根证书
+type PeerConfig struct {
+	Endpoint  string `json:"endpoint"`
+	HostAlias string `json:"hostAlias"`
+	TLSCACert string `json:"tlsCACert"`
+}
+
+// IdentityConfig 客户端证书和私钥, 可以是文件或只包含一个文件的目录 (如 msp/signcerts)
+type IdentityConfig struct {
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// TimeoutConfig 网关各阶段的超时, 使用 time.ParseDuration 格式
+type TimeoutConfig struct {
+	Evaluate     string `json:"evaluate"`
+	Endorse      string `json:"endorse"`
+	Submit       string `json:"submit"`
+	CommitStatus string `json:"commitStatus"`
+}
+
+// Profile 连接配置, 文件中的相对路径相对于配置文件所在目录
+type Profile struct {
+	Channel   string         `json:"channel"`
+	Chaincode string         `json:"chaincode"`
+	MSPID     string         `json:"mspId"`
+	Peer      PeerConfig     `json:"peer"`
+	Identity  IdentityConfig `json:"identity"`
+	Timeouts  TimeoutConfig  `json:"timeouts"`
+}
+
+// Timeouts 解析后的超时
+type Timeouts struct {
+	Evaluate     time.Duration
+	Endorse      time.Duration
+	Submit       time.Duration
+	CommitStatus time.Duration
+}
+
+// profilePath 依次使用命令行参数、环境变量 GNARKCTL_PROFILE 和默认路径
+func profilePath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv("GNARKCTL_PROFILE"); env != "" {
+		return env
+	}
+	return defaultProfilePath
+}
+
+func loadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read connection profile: %v", err)
+	}
+	var profile Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal connection profile %s: %v", path, err)
+	}
+	for _, field := range []struct{ name, value string }{
+		{"channel", profile.Channel},
+		{"chaincode", profile.Chaincode},
+		{"mspId", profile.MSPID},
+		{"peer.endpoint", profile.Peer.Endpoint},
+		{"peer.tlsCACert", profile.Peer.TLSCACert},
+		{"identity.cert", profile.Identity.Cert},
+		{"identity.key", profile.Identity.Key},
+	} {
+		if field.value == "" {
+			return nil, fmt.Errorf("connection profile %s is missing %s", path, field.name)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	profile.Peer.TLSCACert = resolvePath(dir, profile.Peer.TLSCACert)
+	profile.Identity.Cert = resolvePath(dir, profile.Identity.Cert)
+	profile.Identity.Key = resolvePath(dir, profile.Identity.Key)
+	return &profile, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// timeouts 解析超时配置, 未设置的项使用 fabric-gateway 示例中的默认值
+func (p *Profile) timeouts() (*Timeouts, error) {
+	timeouts := &Timeouts{
+		Evaluate:     5 * time.Second,
+		Endorse:      15 * time.Second,
+		Submit:       5 * time.Second,
+		CommitStatus: time.Minute,
+	}
+	for _, item := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"evaluate", p.Timeouts.Evaluate, &timeouts.Evaluate},
+		{"endorse", p.Timeouts.Endorse, &timeouts.Endorse},
+		{"submit", p.Timeouts.Submit, &timeouts.Submit},
+		{"commitStatus", p.Timeouts.CommitStatus, &timeouts.CommitStatus},
+	} {
+		if item.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(item.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s timeout %q: %v", item.name, item.value, err)
+		}
+		*item.dst = d
+	}
+	return timeouts, nil
+}
+
+// readPathFile 读取文件, 若为目录则读取其中第一个文件
+func readPathFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				return os.ReadFile(filepath.Join(path, entry.Name()))
+			}
+		}
+		return nil, fmt.Errorf("no files in directory %s", path)
+	}
+	return os.ReadFile(path)
+}
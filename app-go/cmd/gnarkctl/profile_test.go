@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "profiles", "test.json")
+	writeFile(t, path, `{
+		"channel": "mychannel",
+		"chaincode": "gnarkverify",
+		"mspId": "Org1MSP",
+		"peer": {"endpoint": "localhost:7051", "hostAlias": "peer0.org1.example.com", "tlsCACert": "../tls/ca.crt"},
+		"identity": {"cert": "/abs/signcerts", "key": "keystore"},
+		"timeouts": {"endorse": "30s"}
+	}`)
+
+	profile, err := loadProfile(path)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "tls", "ca.crt"), profile.Peer.TLSCACert)
+	require.Equal(t, "/abs/signcerts", profile.Identity.Cert)
+	require.Equal(t, filepath.Join(dir, "profiles", "keystore"), profile.Identity.Key)
+
+	timeouts, err := profile.timeouts()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, timeouts.Evaluate)
+	require.Equal(t, 30*time.Second, timeouts.Endorse)
+	require.Equal(t, time.Minute, timeouts.CommitStatus)
+
+	profile.Timeouts.Submit = "soon"
+	_, err = profile.timeouts()
+	require.ErrorContains(t, err, "invalid submit timeout")
+
+	writeFile(t, path, `{"channel": "mychannel", "chaincode": "gnarkverify"}`)
+	_, err = loadProfile(path)
+	require.ErrorContains(t, err, "missing mspId")
+}
+
+func TestProfileShippedWithRepo(t *testing.T) {
+	profile, err := loadProfile(filepath.Join("..", "..", defaultProfilePath))
+	require.NoError(t, err)
+	require.Equal(t, "gnarkverify", profile.Chaincode)
+	_, err = profile.timeouts()
+	require.NoError(t, err)
+}
+
+func TestReadPathFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "signcerts", "cert.pem"), "cert")
+
+	data, err := readPathFile(filepath.Join(dir, "signcerts"))
+	require.NoError(t, err)
+	require.Equal(t, "cert", string(data))
+
+	data, err = readPathFile(filepath.Join(dir, "signcerts", "cert.pem"))
+	require.NoError(t, err)
+	require.Equal(t, "cert", string(data))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0o755))
+	_, err = readPathFile(filepath.Join(dir, "empty"))
+	require.ErrorContains(t, err, "no files")
+}
+
+func TestNewVerifyRequest(t *testing.T) {
+	artifact := &Artifact{VK: "vk", Proof: "proof", WitnessPublic: "witness"}
+
+	request := newVerifyRequest("groth16", "BN254", "", artifact)
+	require.Equal(t, "vk", request.VK)
+	require.Empty(t, request.VKID)
+
+	request = newVerifyRequest("groth16", "BN254", "product", artifact)
+	require.Empty(t, request.VK)
+	require.Equal(t, "product", request.VKID)
+	require.Equal(t, "proof", request.Proof)
+}
@@ -0,0 +1,194 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/rangecheck"
+	"github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// CredentialAttributes 凭证中的属性个数, 不足时以 0 补齐
+const CredentialAttributes = 4
+
+// Credential 选择性披露凭证: 证明持有发行方公钥 PublicKey 对属性向量的 EdDSA 签名,
+// 签名的消息为 MiMC(Attributes...). Disclose[i] 为 1 的属性在 Disclosed[i] 中公开, 其余为 0;
+// 下标为 Predicate 的属性须在 64 位内且不小于 Threshold, 只披露属性时取阈值 0.
+// Challenge 为验证方给出的挑战, 绑定到证明中, 使出示不能被他人或在其他交易中重放.
+// 签名和未披露的属性都不公开
+type Credential struct {
+	curveID    tedwards.ID
+	PublicKey  eddsa.PublicKey                         `gnark:",public"`
+	Disclose   [CredentialAttributes]frontend.Variable `gnark:",public"`
+	Disclosed  [CredentialAttributes]frontend.Variable `gnark:",public"`
+	Predicate  frontend.Variable                       `gnark:",public"`
+	Threshold  frontend.Variable                       `gnark:",public"`
+	Challenge  frontend.Variable                       `gnark:",public"`
+	Attributes [CredentialAttributes]frontend.Variable
+	Signature  eddsa.Signature
+}
+
+// NewCredential 返回 curve 上的电路结构
+func NewCredential(curve ecc.ID) (*Credential, error) {
+	id, err := edwardsCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{curveID: id}, nil
+}
+
+func (c *Credential) Define(api frontend.API) error {
+	bindGadget(api, c.Challenge)
+
+	curve, err := twistededwards.NewEdCurve(api, c.curveID)
+	if err != nil {
+		return err
+	}
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Attributes[:]...)
+	message := h.Sum()
+	h.Reset()
+	if err := eddsa.Verify(curve, c.Signature, message, c.PublicKey, &h); err != nil {
+		return err
+	}
+
+	selected, matches := frontend.Variable(0), frontend.Variable(0)
+	for i := range c.Attributes {
+		api.AssertIsBoolean(c.Disclose[i])
+		api.AssertIsEqual(c.Disclosed[i], api.Mul(c.Disclose[i], c.Attributes[i]))
+		match := api.IsZero(api.Sub(c.Predicate, i))
+		selected = api.Add(selected, api.Mul(match, c.Attributes[i]))
+		matches = api.Add(matches, match)
+	}
+	// Predicate 须是有效的属性下标
+	api.AssertIsEqual(matches, 1)
+	checker := rangecheck.New(api)
+	checker.Check(selected, RangeBits)
+	checker.Check(c.Threshold, RangeBits)
+	checker.Check(api.Sub(selected, c.Threshold), RangeBits)
+	return nil
+}
+
+// CredentialHash 发行方签名的消息 MiMC(attributes...), 属性不足 CredentialAttributes 个时以 0 补齐
+func CredentialHash(curve ecc.ID, attributes []*big.Int) (*big.Int, error) {
+	if len(attributes) > CredentialAttributes {
+		return nil, fmt.Errorf("credential has at most %d attributes", CredentialAttributes)
+	}
+	padded := make([]*big.Int, CredentialAttributes)
+	for i := range padded {
+		padded[i] = new(big.Int)
+		if i < len(attributes) {
+			padded[i] = attributes[i]
+		}
+	}
+	return MiMC(curve, padded...)
+}
+
+// SignCredential 发行方对属性向量签名, 结果可由 Credential 电路验证
+func SignCredential(curve ecc.ID, signer signature.Signer, attributes []*big.Int) ([]byte, error) {
+	message, err := CredentialHash(curve, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return SignEdDSA(curve, signer, message)
+}
+
+// credentialAssignment 公钥和签名为压缩编码的十六进制; 只给出 seed 时由种子生成发行方密钥并签名
+type credentialAssignment struct {
+	Attributes []Element `json:"attributes"`
+	Disclose   []bool    `json:"disclose,omitempty"`
+	Predicate  uint64    `json:"predicate"`
+	Threshold  uint64    `json:"threshold"`
+	Challenge  Element   `json:"challenge"`
+	PublicKey  string    `json:"publicKey,omitempty"`
+	Signature  string    `json:"signature,omitempty"`
+	Seed       string    `json:"seed,omitempty"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "credential",
+		Description: "selective disclosure of attributes signed by a public eddsa issuer key, with one attribute at least a public threshold",
+		Decode:      decodeCredential,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(credentialAssignment{
+				// 国家代码、年龄、证件号、有效期
+				Attributes: []Element{NewElement(big.NewInt(86)), NewElement(big.NewInt(30)), NewElement(big.NewInt(110105199501010000)), NewElement(big.NewInt(20301231))},
+				Disclose:   []bool{true, false, false, false},
+				Predicate:  1,
+				Threshold:  18,
+				Challenge:  NewElement(big.NewInt(20250808)),
+				Seed:       "gnarkverify",
+			})
+		},
+	})
+}
+
+func decodeCredential(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input credentialAssignment
+	if err := decodeJSON("credential", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if len(input.Attributes) > CredentialAttributes || len(input.Disclose) > CredentialAttributes {
+		return nil, nil, fmt.Errorf("credential has at most %d attributes", CredentialAttributes)
+	}
+	if input.Predicate >= CredentialAttributes {
+		return nil, nil, fmt.Errorf("predicate %d out of range [0, %d)", input.Predicate, CredentialAttributes)
+	}
+	if err := checkElement(curve, "challenge", &input.Challenge); err != nil {
+		return nil, nil, err
+	}
+	attributes := make([]*big.Int, CredentialAttributes)
+	for i := range attributes {
+		attributes[i] = new(big.Int)
+		if i < len(input.Attributes) {
+			if err := checkElement(curve, fmt.Sprintf("attributes[%d]", i), &input.Attributes[i]); err != nil {
+				return nil, nil, err
+			}
+			attributes[i] = &input.Attributes[i].Int
+		}
+	}
+	selected := attributes[input.Predicate]
+	if !selected.IsUint64() || selected.Uint64() < input.Threshold {
+		return nil, nil, fmt.Errorf("attribute %d is less than the threshold", input.Predicate)
+	}
+	message, err := CredentialHash(curve, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, sig, err := eddsaKeyAndSignature("credential", curve, input.Seed, input.PublicKey, input.Signature, message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shape, err := NewCredential(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment, _ := NewCredential(curve)
+	for i := range attributes {
+		disclose := i < len(input.Disclose) && input.Disclose[i]
+		assignment.Attributes[i] = attributes[i]
+		assignment.Disclose[i], assignment.Disclosed[i] = 0, 0
+		if disclose {
+			assignment.Disclose[i], assignment.Disclosed[i] = 1, attributes[i]
+		}
+	}
+	assignment.Predicate = input.Predicate
+	assignment.Threshold = input.Threshold
+	assignment.Challenge = &input.Challenge.Int
+	if err := assignEdDSA(assignment.curveID, &assignment.PublicKey, &assignment.Signature, publicKey, sig); err != nil {
+		return nil, nil, err
+	}
+	return shape, assignment, nil
+}
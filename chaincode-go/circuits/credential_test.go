@@ -0,0 +1,74 @@
+package circuits
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredential(t *testing.T) {
+	curve := ecc.BN254
+	issuer, err := NewEdDSAKey(curve, "issuer")
+	require.NoError(t, err)
+	attributes := []*big.Int{big.NewInt(86), big.NewInt(30), big.NewInt(123456789)}
+	sig, err := SignCredential(curve, issuer, attributes)
+	require.NoError(t, err)
+	input := credentialAssignment{
+		Attributes: []Element{NewElement(attributes[0]), NewElement(attributes[1]), NewElement(attributes[2])},
+		Disclose:   []bool{true},
+		Predicate:  1,
+		Threshold:  18,
+		Challenge:  NewElement(big.NewInt(42)),
+		PublicKey:  hex.EncodeToString(issuer.Public().Bytes()),
+		Signature:  hex.EncodeToString(sig),
+	}
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	shape, assignment, err := decodeCredential(curve, data)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	x, y, err := EdDSAPublicKey(curve, issuer.Public().Bytes())
+	require.NoError(t, err)
+	credential := assignment.(*Credential)
+	require.Equal(t, x, new(big.Int).SetBytes(credential.PublicKey.A.X.([]byte)))
+	require.Equal(t, y, new(big.Int).SetBytes(credential.PublicKey.A.Y.([]byte)))
+	_, _, err = EdDSAPublicKey(curve, []byte{1, 2, 3})
+	require.ErrorContains(t, err, "invalid eddsa public key")
+
+	// 公开的属性与签名的属性不符
+	wrong := *credential
+	wrong.Disclosed[0] = 87
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	// 未披露的属性不能在公开输入中出现
+	wrong = *credential
+	wrong.Disclosed[2] = attributes[2]
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	// 阈值高于属性值
+	wrong = *credential
+	wrong.Threshold = 31
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	// 无效的属性下标
+	wrong = *credential
+	wrong.Predicate = CredentialAttributes
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	// 修改未披露的属性后签名无效
+	wrong = *credential
+	wrong.Attributes[2] = 987654321
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+	input.Threshold = 31
+	data, err = json.Marshal(input)
+	require.NoError(t, err)
+	_, _, err = decodeCredential(curve, data)
+	require.ErrorContains(t, err, "less than the threshold")
+	_, _, err = decodeCredential(curve, []byte(`{"attributes": [1, 2, 3, 4, 5], "seed": "issuer"}`))
+	require.ErrorContains(t, err, "at most 4 attributes")
+	_, _, err = decodeCredential(curve, []byte(`{"attributes": [1], "predicate": 4, "seed": "issuer"}`))
+	require.ErrorContains(t, err, "out of range")
+}
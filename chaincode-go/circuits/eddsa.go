@@ -0,0 +1,212 @@
+package circuits
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	tedwards "github.com/consensys/gnark-crypto/ecc/twistededwards"
+	"github.com/consensys/gnark-crypto/signature"
+	cryptoeddsa "github.com/consensys/gnark-crypto/signature/eddsa"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/signature/eddsa"
+)
+
+// edwardsCurves 各曲线标量域上定义的扭曲爱德华曲线
+var edwardsCurves = map[ecc.ID]tedwards.ID{
+	ecc.BN254:     tedwards.BN254,
+	ecc.BLS12_377: tedwards.BLS12_377,
+	ecc.BLS12_381: tedwards.BLS12_381,
+	ecc.BW6_633:   tedwards.BW6_633,
+	ecc.BW6_761:   tedwards.BW6_761,
+	ecc.BLS24_315: tedwards.BLS24_315,
+	ecc.BLS24_317: tedwards.BLS24_317,
+}
+
+func edwardsCurve(curve ecc.ID) (tedwards.ID, error) {
+	id, ok := edwardsCurves[curve]
+	if !ok {
+		return 0, fmt.Errorf("no twisted edwards curve on %s", curve)
+	}
+	return id, nil
+}
+
+// EdDSA 证明持有公开公钥 PublicKey 对公开消息 Message 的 EdDSA 签名 (MiMC 哈希)
+type EdDSA struct {
+	curveID   tedwards.ID
+	PublicKey eddsa.PublicKey   `gnark:",public"`
+	Message   frontend.Variable `gnark:",public"`
+	Signature eddsa.Signature
+}
+
+// NewEdDSA 返回 curve 上的电路结构
+func NewEdDSA(curve ecc.ID) (*EdDSA, error) {
+	id, err := edwardsCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return &EdDSA{curveID: id}, nil
+}
+
+func (c *EdDSA) Define(api frontend.API) error {
+	curve, err := twistededwards.NewEdCurve(api, c.curveID)
+	if err != nil {
+		return err
+	}
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	return eddsa.Verify(curve, c.Signature, c.Message, c.PublicKey, &h)
+}
+
+// seedReader 由种子派生确定性的随机流, 仅用于生成演示和测试用的密钥
+type seedReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *seedReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		var block [8]byte
+		binary.BigEndian.PutUint64(block[:], r.counter)
+		r.counter++
+		digest := sha256.Sum256(append(append([]byte{}, r.seed...), block[:]...))
+		r.buf = append(r.buf, digest[:]...)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// NewEdDSAKey 由种子确定性地生成 curve 上的 EdDSA 密钥, 相同种子得到相同密钥
+func NewEdDSAKey(curve ecc.ID, seed string) (signature.Signer, error) {
+	id, err := edwardsCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader = &seedReader{seed: []byte(seed)}
+	return cryptoeddsa.New(id, reader)
+}
+
+// SignEdDSA 使用 MiMC 对域元素消息签名, 结果可由 EdDSA 电路验证
+func SignEdDSA(curve ecc.ID, signer signature.Signer, message *big.Int) ([]byte, error) {
+	mimc, err := mimcHash(curve)
+	if err != nil {
+		return nil, err
+	}
+	if message.Sign() < 0 || message.Cmp(curve.ScalarField()) >= 0 {
+		return nil, fmt.Errorf("message is out of the %s scalar field", curve)
+	}
+	return signer.Sign(elementBytes(curve, message), mimc.New())
+}
+
+// eddsaAssignment 公钥和签名为压缩编码的十六进制; 只给出 seed 时由种子生成密钥并签名
+type eddsaAssignment struct {
+	Message   Element `json:"message"`
+	PublicKey string  `json:"publicKey,omitempty"`
+	Signature string  `json:"signature,omitempty"`
+	Seed      string  `json:"seed,omitempty"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "eddsa",
+		Description: "eddsa (mimc) signature on a public message under a public key",
+		Decode:      decodeEdDSA,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(eddsaAssignment{Message: NewElement(big.NewInt(20250808)), Seed: "gnarkverify"})
+		},
+	})
+}
+
+func decodeEdDSA(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input eddsaAssignment
+	if err := decodeJSON("eddsa", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if err := checkElement(curve, "message", &input.Message); err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, sig, err := eddsaKeyAndSignature("eddsa", curve, input.Seed, input.PublicKey, input.Signature, &input.Message.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shape, err := NewEdDSA(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment, _ := NewEdDSA(curve)
+	assignment.Message = &input.Message.Int
+	if err := assignEdDSA(assignment.curveID, &assignment.PublicKey, &assignment.Signature, publicKey, sig); err != nil {
+		return nil, nil, err
+	}
+	return shape, assignment, nil
+}
+
+// eddsaKeyAndSignature 返回压缩编码的公钥和签名: 只给出 seed 时由种子生成密钥并对 message 签名,
+// 否则解码十六进制的 publicKey 和 signature
+func eddsaKeyAndSignature(name string, curve ecc.ID, seed, publicKeyHex, signatureHex string, message *big.Int) ([]byte, []byte, error) {
+	switch {
+	case seed != "" && publicKeyHex == "" && signatureHex == "":
+		signer, err := NewEdDSAKey(curve, seed)
+		if err != nil {
+			return nil, nil, err
+		}
+		sig, err := SignEdDSA(curve, signer, message)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to sign message: %v", err)
+		}
+		return signer.Public().Bytes(), sig, nil
+	case seed == "" && publicKeyHex != "" && signatureHex != "":
+		publicKey, err := hex.DecodeString(publicKeyHex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode public key from hex: %v", err)
+		}
+		sig, err := hex.DecodeString(signatureHex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode signature from hex: %v", err)
+		}
+		return publicKey, sig, nil
+	default:
+		return nil, nil, fmt.Errorf("%s assignment needs either seed, or publicKey and signature", name)
+	}
+}
+
+// assignEdDSA 填入公钥和签名, gnark 的 Assign 在编码错误时 panic, 这里转为错误返回
+func assignEdDSA(curveID tedwards.ID, publicKey *eddsa.PublicKey, signature *eddsa.Signature, publicKeyBytes, sig []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid eddsa public key or signature: %v", r)
+		}
+	}()
+	publicKey.Assign(curveID, publicKeyBytes)
+	signature.Assign(curveID, sig)
+	return nil
+}
+
+// EdDSAPublicKey 解码压缩编码的公钥, 返回电路中 PublicKey.A 的坐标
+func EdDSAPublicKey(curve ecc.ID, buf []byte) (x *big.Int, y *big.Int, err error) {
+	id, err := edwardsCurve(curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			x, y, err = nil, nil, fmt.Errorf("invalid eddsa public key: %v", r)
+		}
+	}()
+	var publicKey eddsa.PublicKey
+	publicKey.Assign(id, buf)
+	return new(big.Int).SetBytes(publicKey.A.X.([]byte)), new(big.Int).SetBytes(publicKey.A.Y.([]byte)), nil
+}
@@ -0,0 +1,43 @@
+package circuits
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEdDSA(t *testing.T) {
+	curve := ecc.BLS12_381
+	signer, err := NewEdDSAKey(curve, "alice")
+	require.NoError(t, err)
+	same, err := NewEdDSAKey(curve, "alice")
+	require.NoError(t, err)
+	require.Equal(t, signer.Public().Bytes(), same.Public().Bytes())
+
+	message := big.NewInt(42)
+	sig, err := SignEdDSA(curve, signer, message)
+	require.NoError(t, err)
+	data, err := json.Marshal(eddsaAssignment{
+		Message:   NewElement(message),
+		PublicKey: hex.EncodeToString(signer.Public().Bytes()),
+		Signature: hex.EncodeToString(sig),
+	})
+	require.NoError(t, err)
+	shape, assignment, err := decodeEdDSA(curve, data)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	// 签名与消息不符
+	assignment.(*EdDSA).Message = 43
+	require.Error(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	_, _, err = decodeEdDSA(curve, []byte(`{"message": 42}`))
+	require.ErrorContains(t, err, "needs either seed")
+	_, _, err = decodeEdDSA(curve, []byte(`{"message": 42, "publicKey": "00", "signature": "00"}`))
+	require.ErrorContains(t, err, "invalid eddsa")
+}
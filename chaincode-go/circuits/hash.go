@@ -0,0 +1,52 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/hash"
+)
+
+// mimcHashes 各曲线标量域上的 MiMC, 与电路中的 std/hash/mimc 一致
+var mimcHashes = map[ecc.ID]hash.Hash{
+	ecc.BN254:     hash.MIMC_BN254,
+	ecc.BLS12_377: hash.MIMC_BLS12_377,
+	ecc.BLS12_381: hash.MIMC_BLS12_381,
+	ecc.BW6_633:   hash.MIMC_BW6_633,
+	ecc.BW6_761:   hash.MIMC_BW6_761,
+	ecc.BLS24_315: hash.MIMC_BLS24_315,
+	ecc.BLS24_317: hash.MIMC_BLS24_317,
+}
+
+func mimcHash(curve ecc.ID) (hash.Hash, error) {
+	h, ok := mimcHashes[curve]
+	if !ok {
+		return 0, fmt.Errorf("mimc is not available on curve %s", curve)
+	}
+	return h, nil
+}
+
+// elementBytes 将域元素编码为 MiMC 的一个输入块 (大端, 定长)
+func elementBytes(curve ecc.ID, v *big.Int) []byte {
+	buf := make([]byte, (curve.ScalarField().BitLen()+7)/8)
+	return v.FillBytes(buf)
+}
+
+// MiMC 在电路外计算域元素序列的 MiMC 哈希, 结果与电路中依次 Write 后 Sum 相同
+func MiMC(curve ecc.ID, inputs ...*big.Int) (*big.Int, error) {
+	mimc, err := mimcHash(curve)
+	if err != nil {
+		return nil, err
+	}
+	h := mimc.New()
+	for _, input := range inputs {
+		if input.Sign() < 0 || input.Cmp(curve.ScalarField()) >= 0 {
+			return nil, fmt.Errorf("mimc input is out of the %s scalar field", curve)
+		}
+		if _, err := h.Write(elementBytes(curve, input)); err != nil {
+			return nil, fmt.Errorf("failed to hash: %v", err)
+		}
+	}
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
@@ -0,0 +1,292 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// JoinSplitNotes 每次转账花费和生成的票据个数
+const JoinSplitNotes = 2
+
+// 票据的约定: 所有者公钥 pk = MiMC(secret), 承诺 cm = MiMC(amount, pk, rho), nullifier = MiMC(secret, rho).
+// 新票据的 rho = MiMC(nf_0, nf_1, j) 由本次花费的两个 nullifier 和输出下标 j 确定, 不由发送方选取.
+// 链码保证每个 nullifier 只被消费一次, 因此 rho 不会重复, 发送方不能给同一所有者造出 nullifier 相同的两个票据
+
+// NotePublicKey 由花费密钥计算票据所有者公钥
+func NotePublicKey(curve ecc.ID, secret *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret)
+}
+
+// NoteCommitment 计算票据承诺, 即票据树中的叶子
+func NoteCommitment(curve ecc.ID, amount uint64, pk, rho *big.Int) (*big.Int, error) {
+	return MiMC(curve, new(big.Int).SetUint64(amount), pk, rho)
+}
+
+// NoteNullifier 计算花费票据时公开的 nullifier
+func NoteNullifier(curve ecc.ID, secret, rho *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret, rho)
+}
+
+// NoteRho 计算 join-split 第 index 个新票据的 rho, 接收方由交易公开的 nullifier 得到
+func NoteRho(curve ecc.ID, nullifiers [JoinSplitNotes]*big.Int, index int) (*big.Int, error) {
+	return MiMC(curve, nullifiers[0], nullifiers[1], big.NewInt(int64(index)))
+}
+
+// JoinSplitInput 花费的票据, 金额为 0 的票据视为占位, 不检查其在票据树中
+type JoinSplitInput struct {
+	Amount frontend.Variable
+	Secret frontend.Variable
+	Rho    frontend.Variable
+	Index  frontend.Variable
+	Path   []frontend.Variable
+}
+
+// JoinSplitOutput 生成的票据, rho 在电路中由 nullifier 导出
+type JoinSplitOutput struct {
+	Amount frontend.Variable
+	PK     frontend.Variable
+}
+
+// JoinSplit 花费两个票据并生成两个新票据, 金额和所有者都不公开. 满足
+// in[0] + in[1] + ValueIn = out[0] + out[1] + ValueOut, 金额都在 [0, 2^64) 内;
+// ValueIn 和 ValueOut 为公开的铸造和销毁金额, 普通转账时为 0
+type JoinSplit struct {
+	Root        frontend.Variable                 `gnark:",public"`
+	Nullifiers  [JoinSplitNotes]frontend.Variable `gnark:",public"`
+	Commitments [JoinSplitNotes]frontend.Variable `gnark:",public"`
+	ValueIn     frontend.Variable                 `gnark:",public"`
+	ValueOut    frontend.Variable                 `gnark:",public"`
+	Inputs      [JoinSplitNotes]JoinSplitInput    `gnark:",secret"`
+	Outputs     [JoinSplitNotes]JoinSplitOutput   `gnark:",secret"`
+}
+
+// NewJoinSplit 返回票据树深度为 depth 的电路结构
+func NewJoinSplit(depth int) *JoinSplit {
+	c := &JoinSplit{}
+	for i := range c.Inputs {
+		c.Inputs[i].Path = make([]frontend.Variable, depth)
+	}
+	return c
+}
+
+func (c *JoinSplit) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	hash := func(inputs ...frontend.Variable) frontend.Variable {
+		h.Reset()
+		h.Write(inputs...)
+		return h.Sum()
+	}
+
+	balanceIn := c.ValueIn
+	balanceOut := c.ValueOut
+	api.ToBinary(c.ValueIn, 64)
+	api.ToBinary(c.ValueOut, 64)
+	for i, input := range c.Inputs {
+		api.ToBinary(input.Amount, 64)
+		pk := hash(input.Secret)
+		commitment := hash(input.Amount, pk, input.Rho)
+		root, err := merkleRootGadget(api, commitment, input.Index, input.Path)
+		if err != nil {
+			return err
+		}
+		// 金额为 0 时不要求票据在树中
+		api.AssertIsEqual(api.Mul(input.Amount, api.Sub(c.Root, root)), 0)
+		api.AssertIsEqual(c.Nullifiers[i], hash(input.Secret, input.Rho))
+		balanceIn = api.Add(balanceIn, input.Amount)
+	}
+	for i, output := range c.Outputs {
+		api.ToBinary(output.Amount, 64)
+		rho := hash(c.Nullifiers[0], c.Nullifiers[1], i)
+		api.AssertIsEqual(c.Commitments[i], hash(output.Amount, output.PK, rho))
+		balanceOut = api.Add(balanceOut, output.Amount)
+	}
+	api.AssertIsEqual(balanceIn, balanceOut)
+	return nil
+}
+
+type joinSplitInputAssignment struct {
+	Amount uint64    `json:"amount"`
+	Secret Element   `json:"secret"`
+	Rho    Element   `json:"rho"`
+	Index  uint64    `json:"index"`
+	Path   []Element `json:"path"`
+}
+
+type joinSplitOutputAssignment struct {
+	Amount uint64  `json:"amount"`
+	PK     Element `json:"pk"`
+}
+
+type joinSplitAssignment struct {
+	// Root 可选, 两个输入都是占位票据时用于指定公开的根
+	Root     *Element                                  `json:"root,omitempty"`
+	Inputs   [JoinSplitNotes]joinSplitInputAssignment  `json:"inputs"`
+	Outputs  [JoinSplitNotes]joinSplitOutputAssignment `json:"outputs"`
+	ValueIn  uint64                                    `json:"valueIn"`
+	ValueOut uint64                                    `json:"valueOut"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "joinsplit",
+		Description: "confidential transfer spending two mimc note commitments from a merkle tree and creating two new ones, with public mint and burn values",
+		Decode:      decodeJoinSplit,
+		Example:     exampleJoinSplit,
+	})
+}
+
+func exampleJoinSplit(curve ecc.ID) ([]byte, error) {
+	type note struct {
+		amount      uint64
+		secret, rho int64
+	}
+	notes := []note{{30, 11, 1}, {20, 12, 2}, {7, 13, 3}}
+	leaves := make([]*big.Int, len(notes))
+	for i, n := range notes {
+		pk, err := NotePublicKey(curve, big.NewInt(n.secret))
+		if err != nil {
+			return nil, err
+		}
+		if leaves[i], err = NoteCommitment(curve, n.amount, pk, big.NewInt(n.rho)); err != nil {
+			return nil, err
+		}
+	}
+	var input joinSplitAssignment
+	for i := range input.Inputs {
+		path, _, err := MerkleProof(curve, leaves, 8, i)
+		if err != nil {
+			return nil, err
+		}
+		input.Inputs[i] = joinSplitInputAssignment{
+			Amount: notes[i].amount,
+			Secret: NewElement(big.NewInt(notes[i].secret)),
+			Rho:    NewElement(big.NewInt(notes[i].rho)),
+			Index:  uint64(i),
+		}
+		for _, sibling := range path {
+			input.Inputs[i].Path = append(input.Inputs[i].Path, NewElement(sibling))
+		}
+	}
+	// 向 pk(21) 转 45, 找零 5 给第一个票据的所有者
+	receiver, err := NotePublicKey(curve, big.NewInt(21))
+	if err != nil {
+		return nil, err
+	}
+	change, err := NotePublicKey(curve, big.NewInt(11))
+	if err != nil {
+		return nil, err
+	}
+	input.Outputs[0] = joinSplitOutputAssignment{Amount: 45, PK: NewElement(receiver)}
+	input.Outputs[1] = joinSplitOutputAssignment{Amount: 5, PK: NewElement(change)}
+	return json.Marshal(input)
+}
+
+func decodeJoinSplit(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input joinSplitAssignment
+	if err := decodeJSON("joinsplit", data, &input); err != nil {
+		return nil, nil, err
+	}
+	depth := len(input.Inputs[0].Path)
+	if depth == 0 {
+		return nil, nil, fmt.Errorf("note tree path must not be empty")
+	}
+
+	assignment := NewJoinSplit(depth)
+	assignment.ValueIn = input.ValueIn
+	assignment.ValueOut = input.ValueOut
+	balanceIn := new(big.Int).SetUint64(input.ValueIn)
+	balanceOut := new(big.Int).SetUint64(input.ValueOut)
+	var root *big.Int
+	var nullifiers [JoinSplitNotes]*big.Int
+	if input.Root != nil {
+		if err := checkElement(curve, "root", input.Root); err != nil {
+			return nil, nil, err
+		}
+		root = &input.Root.Int
+	}
+	for i, in := range input.Inputs {
+		if len(in.Path) != depth {
+			return nil, nil, fmt.Errorf("inputs[%d] path has length %d, expected %d", i, len(in.Path), depth)
+		}
+		for name, e := range map[string]*Element{"secret": &in.Secret, "rho": &in.Rho} {
+			if err := checkElement(curve, fmt.Sprintf("inputs[%d].%s", i, name), e); err != nil {
+				return nil, nil, err
+			}
+		}
+		path := make([]*big.Int, depth)
+		for j := range in.Path {
+			if err := checkElement(curve, fmt.Sprintf("inputs[%d].path[%d]", i, j), &in.Path[j]); err != nil {
+				return nil, nil, err
+			}
+			path[j] = &in.Path[j].Int
+		}
+		pk, err := NotePublicKey(curve, &in.Secret.Int)
+		if err != nil {
+			return nil, nil, err
+		}
+		commitment, err := NoteCommitment(curve, in.Amount, pk, &in.Rho.Int)
+		if err != nil {
+			return nil, nil, err
+		}
+		noteRoot, err := MerkleRoot(curve, commitment, in.Index, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if in.Amount > 0 {
+			if root != nil && root.Cmp(noteRoot) != 0 {
+				return nil, nil, fmt.Errorf("inputs[%d] is not in the note tree with root %s", i, root)
+			}
+			root = noteRoot
+		}
+		nullifier, err := NoteNullifier(curve, &in.Secret.Int, &in.Rho.Int)
+		if err != nil {
+			return nil, nil, err
+		}
+		balanceIn.Add(balanceIn, new(big.Int).SetUint64(in.Amount))
+
+		nullifiers[i] = nullifier
+		assignment.Nullifiers[i] = nullifier
+		assignment.Inputs[i].Amount = in.Amount
+		assignment.Inputs[i].Secret = &in.Secret.Int
+		assignment.Inputs[i].Rho = &in.Rho.Int
+		assignment.Inputs[i].Index = in.Index
+		for j := range path {
+			assignment.Inputs[i].Path[j] = path[j]
+		}
+	}
+	if root == nil {
+		return nil, nil, fmt.Errorf("root must be set when no input note has a positive amount")
+	}
+	assignment.Root = root
+
+	for i, out := range input.Outputs {
+		if err := checkElement(curve, fmt.Sprintf("outputs[%d].pk", i), &out.PK); err != nil {
+			return nil, nil, err
+		}
+		rho, err := NoteRho(curve, nullifiers, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		commitment, err := NoteCommitment(curve, out.Amount, &out.PK.Int, rho)
+		if err != nil {
+			return nil, nil, err
+		}
+		balanceOut.Add(balanceOut, new(big.Int).SetUint64(out.Amount))
+
+		assignment.Commitments[i] = commitment
+		assignment.Outputs[i].Amount = out.Amount
+		assignment.Outputs[i].PK = &out.PK.Int
+	}
+	if balanceIn.Cmp(balanceOut) != 0 {
+		return nil, nil, fmt.Errorf("inputs and valueIn sum to %s, outputs and valueOut sum to %s", balanceIn, balanceOut)
+	}
+	return NewJoinSplit(depth), assignment, nil
+}
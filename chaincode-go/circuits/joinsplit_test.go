@@ -0,0 +1,76 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinSplitCircuit(t *testing.T) {
+	for _, curve := range []ecc.ID{ecc.BN254, ecc.BLS12_381} {
+		example, err := exampleJoinSplit(curve)
+		require.NoError(t, err)
+		shape, assignment, err := decodeJoinSplit(curve, example)
+		require.NoError(t, err)
+		require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()), curve)
+
+		// 输出金额多于输入、或以模数回绕的负金额凑平时约束不满足
+		joinSplit := assignment.(*JoinSplit)
+		wrong := *joinSplit
+		wrong.ValueOut = 1
+		require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+		wrong = *joinSplit
+		negative := new(big.Int).Sub(curve.ScalarField(), big.NewInt(5))
+		wrong.Outputs[0].Amount = 55
+		wrong.Outputs[1].Amount = negative
+		nullifiers := [JoinSplitNotes]*big.Int{joinSplit.Nullifiers[0].(*big.Int), joinSplit.Nullifiers[1].(*big.Int)}
+		for i, amount := range []*big.Int{big.NewInt(55), negative} {
+			rho, err := NoteRho(curve, nullifiers, i)
+			require.NoError(t, err)
+			wrong.Commitments[i], err = MiMC(curve, amount, wrong.Outputs[i].PK.(*big.Int), rho)
+			require.NoError(t, err)
+		}
+		require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+		// 发送方不能自选 rho: 两个输出给同一所有者且 rho 相同时承诺不满足约束
+		wrong = *joinSplit
+		wrong.Outputs[1].PK = wrong.Outputs[0].PK
+		for i := range wrong.Commitments {
+			wrong.Commitments[i], err = MiMC(curve, new(big.Int).SetUint64(joinSplit.Outputs[i].Amount.(uint64)), wrong.Outputs[i].PK.(*big.Int), big.NewInt(101))
+			require.NoError(t, err)
+		}
+		require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+		wrong = *joinSplit
+		wrong.Root = 1
+		require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+		var input joinSplitAssignment
+		require.NoError(t, json.Unmarshal(example, &input))
+		input.Outputs[0].Amount++
+		data, err := json.Marshal(input)
+		require.NoError(t, err)
+		_, _, err = decodeJoinSplit(curve, data)
+		require.ErrorContains(t, err, "sum to")
+
+		// 铸造: 两个占位输入, 只需给出根
+		require.NoError(t, json.Unmarshal(example, &input))
+		root := NewElement(joinSplit.Root.(*big.Int))
+		input.Root = &root
+		input.Inputs[0].Amount, input.Inputs[1].Amount = 0, 0
+		input.ValueIn = 50
+		data, err = json.Marshal(input)
+		require.NoError(t, err)
+		shape, assignment, err = decodeJoinSplit(curve, data)
+		require.NoError(t, err)
+		require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+		input.Root = nil
+		data, err = json.Marshal(input)
+		require.NoError(t, err)
+		_, _, err = decodeJoinSplit(curve, data)
+		require.ErrorContains(t, err, "root must be set")
+	}
+}
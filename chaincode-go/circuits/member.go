@@ -0,0 +1,147 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// Member 匿名成员授权: 证明 MiMC(Secret) 是根为 Root 的成员树中的叶子, 且 Nullifier = MiMC(Secret, Action).
+// 同一成员对同一操作只能得到一个 nullifier, 不同操作之间不可关联.
+// Message 为调用参数的摘要, 绑定到证明中, 他人转发证明时不能修改参数
+type Member struct {
+	Root      frontend.Variable `gnark:",public"`
+	Nullifier frontend.Variable `gnark:",public"`
+	Action    frontend.Variable `gnark:",public"`
+	Message   frontend.Variable `gnark:",public"`
+	Secret    frontend.Variable
+	Index     frontend.Variable
+	Path      []frontend.Variable
+}
+
+// NewMember 返回成员树深度为 depth 的电路结构
+func NewMember(depth int) *Member {
+	return &Member{Path: make([]frontend.Variable, depth)}
+}
+
+func (c *Member) Define(api frontend.API) error {
+	bindGadget(api, c.Message)
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Secret)
+	root, err := merkleRootGadget(api, h.Sum(), c.Index, c.Path)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Root, root)
+
+	h.Reset()
+	h.Write(c.Secret, c.Action)
+	api.AssertIsEqual(c.Nullifier, h.Sum())
+	return nil
+}
+
+// MemberCommitment 管理员登记到成员树中的叶子 MiMC(secret)
+func MemberCommitment(curve ecc.ID, secret *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret)
+}
+
+// MemberNullifier 成员执行操作 action 时的 nullifier MiMC(secret, action)
+func MemberNullifier(curve ecc.ID, secret, action *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret, action)
+}
+
+type memberAssignment struct {
+	Secret  Element   `json:"secret"`
+	Action  Element   `json:"action"`
+	Message Element   `json:"message"`
+	Index   uint64    `json:"index"`
+	Path    []Element `json:"path"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "member",
+		Description: "anonymous authorization: membership of mimc(secret) in a member tree with nullifier mimc(secret, action) and a public message",
+		Decode:      decodeMember,
+		Example:     exampleMember,
+	})
+}
+
+func exampleMember(curve ecc.ID) ([]byte, error) {
+	members := make([]*big.Int, 5)
+	for i := range members {
+		var err error
+		if members[i], err = MemberCommitment(curve, big.NewInt(int64(2000+i))); err != nil {
+			return nil, err
+		}
+	}
+	const index = 2
+	path, _, err := MerkleProof(curve, members, 8, index)
+	if err != nil {
+		return nil, err
+	}
+	input := memberAssignment{
+		Secret:  NewElement(big.NewInt(2000 + index)),
+		Action:  NewElement(big.NewInt(11)),
+		Message: NewElement(big.NewInt(12345)),
+		Index:   index,
+	}
+	for _, sibling := range path {
+		input.Path = append(input.Path, NewElement(sibling))
+	}
+	return json.Marshal(input)
+}
+
+func decodeMember(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input memberAssignment
+	if err := decodeJSON("member", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if len(input.Path) == 0 {
+		return nil, nil, fmt.Errorf("member tree path must not be empty")
+	}
+	for name, e := range map[string]*Element{"secret": &input.Secret, "action": &input.Action, "message": &input.Message} {
+		if err := checkElement(curve, name, e); err != nil {
+			return nil, nil, err
+		}
+	}
+	path := make([]*big.Int, len(input.Path))
+	for i := range input.Path {
+		if err := checkElement(curve, fmt.Sprintf("path[%d]", i), &input.Path[i]); err != nil {
+			return nil, nil, err
+		}
+		path[i] = &input.Path[i].Int
+	}
+	leaf, err := MemberCommitment(curve, &input.Secret.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := MerkleRoot(curve, leaf, input.Index, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	nullifier, err := MemberNullifier(curve, &input.Secret.Int, &input.Action.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assignment := NewMember(len(path))
+	assignment.Root = root
+	assignment.Nullifier = nullifier
+	assignment.Action = &input.Action.Int
+	assignment.Message = &input.Message.Int
+	assignment.Secret = &input.Secret.Int
+	assignment.Index = input.Index
+	for i := range path {
+		assignment.Path[i] = path[i]
+	}
+	return NewMember(len(path)), assignment, nil
+}
@@ -0,0 +1,35 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemberCircuit(t *testing.T) {
+	curve := ecc.BN254
+	example, err := exampleMember(curve)
+	require.NoError(t, err)
+	shape, assignment, err := decodeMember(curve, example)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	member := assignment.(*Member)
+	nullifier, err := MemberNullifier(curve, big.NewInt(2002), big.NewInt(11))
+	require.NoError(t, err)
+	require.Equal(t, nullifier, member.Nullifier)
+
+	// 换一个操作的 nullifier 或不在树中的成员不能满足约束
+	wrong := *member
+	wrong.Action = 12
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	wrong = *member
+	wrong.Secret = 2009
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+	_, _, err = decodeMember(curve, []byte(`{"secret": 1, "action": 2, "message": 3, "index": 0, "path": []}`))
+	require.ErrorContains(t, err, "must not be empty")
+}
@@ -0,0 +1,175 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// MaxMerkleDepth 支持的最大树深度
+const MaxMerkleDepth = 32
+
+// MerkleMembership 证明私密叶子 Leaf 位于根为 Root 的 MiMC Merkle 树中.
+// Index 的第 i 位为 0 时第 i 层当前节点在左侧, 内部节点为 MiMC(left, right)
+type MerkleMembership struct {
+	Root  frontend.Variable `gnark:",public"`
+	Leaf  frontend.Variable
+	Index frontend.Variable
+	Path  []frontend.Variable
+}
+
+// NewMerkleMembership 返回深度为 depth 的电路结构
+func NewMerkleMembership(depth int) *MerkleMembership {
+	return &MerkleMembership{Path: make([]frontend.Variable, depth)}
+}
+
+func (c *MerkleMembership) Define(api frontend.API) error {
+	root, err := merkleRootGadget(api, c.Leaf, c.Index, c.Path)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Root, root)
+	return nil
+}
+
+// merkleRootGadget 在电路中由叶子、下标和兄弟节点计算根, 与 MerkleRoot 一致
+func merkleRootGadget(api frontend.API, leaf, index frontend.Variable, path []frontend.Variable) (frontend.Variable, error) {
+	bits := api.ToBinary(index, len(path))
+	node := leaf
+	for i, sibling := range path {
+		h, err := mimc.NewMiMC(api)
+		if err != nil {
+			return nil, err
+		}
+		left := api.Select(bits[i], sibling, node)
+		right := api.Select(bits[i], node, sibling)
+		h.Write(left, right)
+		node = h.Sum()
+	}
+	return node, nil
+}
+
+// MerkleRoot 在电路外由叶子、下标和兄弟节点计算根
+func MerkleRoot(curve ecc.ID, leaf *big.Int, index uint64, path []*big.Int) (*big.Int, error) {
+	if len(path) > MaxMerkleDepth {
+		return nil, fmt.Errorf("merkle path longer than %d", MaxMerkleDepth)
+	}
+	if index>>len(path) != 0 {
+		return nil, fmt.Errorf("index %d does not fit a tree of depth %d", index, len(path))
+	}
+	node := leaf
+	for i, sibling := range path {
+		var err error
+		if index>>i&1 == 0 {
+			node, err = MiMC(curve, node, sibling)
+		} else {
+			node, err = MiMC(curve, sibling, node)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// MerkleProof 为深度 depth、空位补 0 的树计算第 index 个叶子的路径和根
+func MerkleProof(curve ecc.ID, leaves []*big.Int, depth int, index int) ([]*big.Int, *big.Int, error) {
+	if depth > MaxMerkleDepth || len(leaves) > 1<<depth {
+		return nil, nil, fmt.Errorf("%d leaves do not fit a tree of depth %d", len(leaves), depth)
+	}
+	if index < 0 || index >= len(leaves) {
+		return nil, nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+	level := append([]*big.Int{}, leaves...)
+	zero := new(big.Int)
+	path := make([]*big.Int, depth)
+	for d := 0; d < depth; d++ {
+		if len(level)%2 == 1 {
+			level = append(level, zero)
+		}
+		path[d] = level[index^1]
+		next := make([]*big.Int, len(level)/2)
+		for i := range next {
+			var err error
+			if next[i], err = MiMC(curve, level[2*i], level[2*i+1]); err != nil {
+				return nil, nil, err
+			}
+		}
+		// 空子树的哈希作为下一层的补位
+		var err error
+		if zero, err = MiMC(curve, zero, zero); err != nil {
+			return nil, nil, err
+		}
+		level, index = next, index/2
+	}
+	return path, level[0], nil
+}
+
+type merkleAssignment struct {
+	Leaf  Element   `json:"leaf"`
+	Index uint64    `json:"index"`
+	Path  []Element `json:"path"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "merkle",
+		Description: "membership of a private leaf in a mimc merkle tree with a public root; depth is the path length",
+		Decode:      decodeMerkle,
+		Example:     exampleMerkle,
+	})
+}
+
+func exampleMerkle(curve ecc.ID) ([]byte, error) {
+	leaves := make([]*big.Int, 10)
+	for i := range leaves {
+		leaves[i] = big.NewInt(int64(100 + i))
+	}
+	const index = 5
+	path, _, err := MerkleProof(curve, leaves, 8, index)
+	if err != nil {
+		return nil, err
+	}
+	input := merkleAssignment{Leaf: NewElement(leaves[index]), Index: index}
+	for _, sibling := range path {
+		input.Path = append(input.Path, NewElement(sibling))
+	}
+	return json.Marshal(input)
+}
+
+func decodeMerkle(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input merkleAssignment
+	if err := decodeJSON("merkle", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if len(input.Path) == 0 {
+		return nil, nil, fmt.Errorf("merkle path must not be empty")
+	}
+	if err := checkElement(curve, "leaf", &input.Leaf); err != nil {
+		return nil, nil, err
+	}
+	path := make([]*big.Int, len(input.Path))
+	for i := range input.Path {
+		if err := checkElement(curve, fmt.Sprintf("path[%d]", i), &input.Path[i]); err != nil {
+			return nil, nil, err
+		}
+		path[i] = &input.Path[i].Int
+	}
+	root, err := MerkleRoot(curve, &input.Leaf.Int, input.Index, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assignment := NewMerkleMembership(len(path))
+	assignment.Root = root
+	assignment.Leaf = &input.Leaf.Int
+	assignment.Index = input.Index
+	for i := range path {
+		assignment.Path[i] = path[i]
+	}
+	return NewMerkleMembership(len(path)), assignment, nil
+}
@@ -0,0 +1,57 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleProof(t *testing.T) {
+	curve := ecc.BN254
+	leaves := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+
+	var roots []*big.Int
+	for index, leaf := range leaves {
+		path, root, err := MerkleProof(curve, leaves, 4, index)
+		require.NoError(t, err)
+		require.Len(t, path, 4)
+		computed, err := MerkleRoot(curve, leaf, uint64(index), path)
+		require.NoError(t, err)
+		require.Equal(t, root, computed)
+		roots = append(roots, root)
+	}
+	require.Equal(t, roots[0], roots[1])
+	require.Equal(t, roots[0], roots[2])
+
+	_, _, err := MerkleProof(curve, leaves, 1, 0)
+	require.ErrorContains(t, err, "do not fit")
+	_, err = MerkleRoot(curve, leaves[0], 4, make([]*big.Int, 2))
+	require.ErrorContains(t, err, "does not fit")
+}
+
+func TestMerkleCircuit(t *testing.T) {
+	curve := ecc.BLS12_377
+	example, err := exampleMerkle(curve)
+	require.NoError(t, err)
+	shape, assignment, err := decodeMerkle(curve, example)
+	require.NoError(t, err)
+	require.Len(t, shape.(*MerkleMembership).Path, 8)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	// 叶子在错误的位置上不能满足约束
+	wrong := *assignment.(*MerkleMembership)
+	wrong.Index = 4
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+	var input merkleAssignment
+	require.NoError(t, json.Unmarshal(example, &input))
+	input.Path = nil
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	_, _, err = decodeMerkle(curve, data)
+	require.ErrorContains(t, err, "must not be empty")
+}
@@ -0,0 +1,199 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// MaxSparseMerkleDepth 稀疏 Merkle 树支持的最大深度, 叶子下标为 MiMC(id) 的低 depth 位
+const MaxSparseMerkleDepth = 64
+
+// 稀疏 Merkle 树的约定: 深度为 depth, 标识 id 对应的叶子下标为 MiMC(id) 的低 depth 位,
+// 已吊销的叶子为 1, 其余为 0; 内部节点为 MiMC(left, right), 与 MerkleRoot 一致.
+// 下标相同的两个标识共用一个叶子, 发行方登记持有者时须拒绝下标冲突, 见 gnarkverify.RevocationContract
+
+// NonMembership 证明 Commitment = MiMC(ID, Blinding) 承诺的私密标识不在根为 Root 的稀疏 Merkle 树中,
+// 即标识对应的叶子为 0. Path 为该叶子的兄弟节点
+type NonMembership struct {
+	Root       frontend.Variable `gnark:",public"`
+	Commitment frontend.Variable `gnark:",public"`
+	ID         frontend.Variable
+	Blinding   frontend.Variable
+	Path       []frontend.Variable
+}
+
+// NewNonMembership 返回深度为 depth 的电路结构
+func NewNonMembership(depth int) *NonMembership {
+	return &NonMembership{Path: make([]frontend.Variable, depth)}
+}
+
+func (c *NonMembership) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.ID)
+	// 按域的位数分解并检查小于模数, 否则可以用 hash + r 的分解指向另一个叶子
+	bits := api.ToBinary(h.Sum())
+	index := api.FromBinary(bits[:len(c.Path)]...)
+	root, err := merkleRootGadget(api, 0, index, c.Path)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Root, root)
+
+	h.Reset()
+	h.Write(c.ID, c.Blinding)
+	api.AssertIsEqual(c.Commitment, h.Sum())
+	return nil
+}
+
+// IdentifierCommitment 持有者公开的标识承诺 MiMC(id, blinding)
+func IdentifierCommitment(curve ecc.ID, id, blinding *big.Int) (*big.Int, error) {
+	return MiMC(curve, id, blinding)
+}
+
+// SparseIndex 标识在深度为 depth 的稀疏 Merkle 树中的叶子下标, 为 MiMC(id) 的低 depth 位
+func SparseIndex(curve ecc.ID, id *big.Int, depth int) (uint64, error) {
+	hash, err := MiMC(curve, id)
+	if err != nil {
+		return 0, err
+	}
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(depth)), big.NewInt(1))
+	return new(big.Int).And(hash, mask).Uint64(), nil
+}
+
+// SparseMerkleProof 由已吊销的叶子下标计算深度为 depth 的稀疏 Merkle 树中第 index 个叶子的路径和根
+func SparseMerkleProof(curve ecc.ID, depth int, revoked []uint64, index uint64) ([]*big.Int, *big.Int, error) {
+	if depth < 1 || depth > MaxSparseMerkleDepth {
+		return nil, nil, fmt.Errorf("sparse merkle tree depth %d out of range [1, %d]", depth, MaxSparseMerkleDepth)
+	}
+	if index>>depth != 0 {
+		return nil, nil, fmt.Errorf("index %d does not fit a tree of depth %d", index, depth)
+	}
+	// level 只保存非空的节点, 空子树的根为 zero
+	level := map[uint64]*big.Int{}
+	for _, i := range revoked {
+		if i>>depth != 0 {
+			return nil, nil, fmt.Errorf("index %d does not fit a tree of depth %d", i, depth)
+		}
+		level[i] = big.NewInt(1)
+	}
+	zero := new(big.Int)
+	path := make([]*big.Int, depth)
+	for d := 0; d < depth; d++ {
+		path[d] = zero
+		if sibling, ok := level[index^1]; ok {
+			path[d] = sibling
+		}
+		next := map[uint64]*big.Int{}
+		for i := range level {
+			parent := i >> 1
+			if _, ok := next[parent]; ok {
+				continue
+			}
+			left, right := zero, zero
+			if node, ok := level[parent<<1]; ok {
+				left = node
+			}
+			if node, ok := level[parent<<1|1]; ok {
+				right = node
+			}
+			node, err := MiMC(curve, left, right)
+			if err != nil {
+				return nil, nil, err
+			}
+			next[parent] = node
+		}
+		var err error
+		if zero, err = MiMC(curve, zero, zero); err != nil {
+			return nil, nil, err
+		}
+		level, index = next, index>>1
+	}
+	if root, ok := level[0]; ok {
+		return path, root, nil
+	}
+	return path, zero, nil
+}
+
+type nonMembershipAssignment struct {
+	ID       Element   `json:"id"`
+	Blinding Element   `json:"blinding"`
+	Depth    int       `json:"depth"`
+	Revoked  []Element `json:"revoked,omitempty"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "nonmember",
+		Description: "the identifier committed by the public mimc(id, blinding) is not revoked in a sparse mimc merkle tree with a public root",
+		Decode:      decodeNonMembership,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(nonMembershipAssignment{
+				ID:       NewElement(big.NewInt(424242)),
+				Blinding: NewElement(big.NewInt(99)),
+				Depth:    16,
+				Revoked:  []Element{NewElement(big.NewInt(1001)), NewElement(big.NewInt(1002))},
+			})
+		},
+	})
+}
+
+// decodeNonMembership 赋值给出已吊销的标识列表, 由其计算树的根和路径
+func decodeNonMembership(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input nonMembershipAssignment
+	if err := decodeJSON("nonmember", data, &input); err != nil {
+		return nil, nil, err
+	}
+	for name, e := range map[string]*Element{"id": &input.ID, "blinding": &input.Blinding} {
+		if err := checkElement(curve, name, e); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.Depth < 1 || input.Depth > MaxSparseMerkleDepth {
+		return nil, nil, fmt.Errorf("sparse merkle tree depth %d out of range [1, %d]", input.Depth, MaxSparseMerkleDepth)
+	}
+	index, err := SparseIndex(curve, &input.ID.Int, input.Depth)
+	if err != nil {
+		return nil, nil, err
+	}
+	revoked := make([]uint64, len(input.Revoked))
+	for i := range input.Revoked {
+		if err := checkElement(curve, fmt.Sprintf("revoked[%d]", i), &input.Revoked[i]); err != nil {
+			return nil, nil, err
+		}
+		if revoked[i], err = SparseIndex(curve, &input.Revoked[i].Int, input.Depth); err != nil {
+			return nil, nil, err
+		}
+		if input.Revoked[i].Cmp(&input.ID.Int) == 0 {
+			return nil, nil, fmt.Errorf("id is revoked")
+		}
+		if revoked[i] == index {
+			return nil, nil, fmt.Errorf("id shares leaf %d with revoked[%d]", index, i)
+		}
+	}
+	path, root, err := SparseMerkleProof(curve, input.Depth, revoked, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	commitment, err := IdentifierCommitment(curve, &input.ID.Int, &input.Blinding.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assignment := NewNonMembership(input.Depth)
+	assignment.Root = root
+	assignment.Commitment = commitment
+	assignment.ID = &input.ID.Int
+	assignment.Blinding = &input.Blinding.Int
+	for i := range path {
+		assignment.Path[i] = path[i]
+	}
+	return NewNonMembership(input.Depth), assignment, nil
+}
@@ -0,0 +1,88 @@
+package circuits
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparseMerkleProof(t *testing.T) {
+	curve := ecc.BN254
+	// 与空位补 0 的稠密树一致
+	leaves := make([]*big.Int, 16)
+	for i := range leaves {
+		leaves[i] = new(big.Int)
+	}
+	leaves[3], leaves[9] = big.NewInt(1), big.NewInt(1)
+	for _, index := range []uint64{0, 3, 8, 15} {
+		path, root, err := SparseMerkleProof(curve, 4, []uint64{9, 3}, index)
+		require.NoError(t, err)
+		densePath, denseRoot, err := MerkleProof(curve, leaves, 4, int(index))
+		require.NoError(t, err)
+		require.Equal(t, denseRoot, root)
+		require.Equal(t, densePath, path)
+	}
+
+	_, empty, err := SparseMerkleProof(curve, 64, nil, 1<<63)
+	require.NoError(t, err)
+	_, root, err := SparseMerkleProof(curve, 64, []uint64{1 << 63}, 0)
+	require.NoError(t, err)
+	require.NotEqual(t, empty, root)
+	_, _, err = SparseMerkleProof(curve, 4, []uint64{16}, 0)
+	require.ErrorContains(t, err, "does not fit")
+	_, _, err = SparseMerkleProof(curve, 65, nil, 0)
+	require.ErrorContains(t, err, "out of range")
+}
+
+// collidingID 返回与标识 id 共用深度为 depth 的叶子的最小的其他标识
+func collidingID(t *testing.T, curve ecc.ID, id int64, depth int) int64 {
+	index, err := SparseIndex(curve, big.NewInt(id), depth)
+	require.NoError(t, err)
+	for other := int64(0); ; other++ {
+		i, err := SparseIndex(curve, big.NewInt(other), depth)
+		require.NoError(t, err)
+		if i == index && other != id {
+			return other
+		}
+	}
+}
+
+func TestNonMembership(t *testing.T) {
+	curve := ecc.BN254
+	shape, assignment, err := decodeNonMembership(curve, []byte(`{"id": 4660, "blinding": 7, "depth": 8, "revoked": [4661, 17]}`))
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	_, _, err = decodeNonMembership(curve, []byte(`{"id": 4660, "blinding": 7, "depth": 8, "revoked": [4660]}`))
+	require.ErrorContains(t, err, "id is revoked")
+	// 下标由 MiMC(id) 决定, 低位相同的标识不再共用叶子; 哈希的低位相同时共用
+	_, _, err = decodeNonMembership(curve, []byte(`{"id": 4660, "blinding": 7, "depth": 8, "revoked": [52]}`))
+	require.NoError(t, err)
+	colliding := collidingID(t, curve, 4660, 8)
+	_, _, err = decodeNonMembership(curve, []byte(fmt.Sprintf(`{"id": 4660, "blinding": 7, "depth": 8, "revoked": [%d]}`, colliding)))
+	require.ErrorContains(t, err, "shares leaf")
+
+	// 绕过解析, 用已吊销标识的路径和根不能满足约束
+	revoked := big.NewInt(4661)
+	revokedIndex, err := SparseIndex(curve, revoked, 8)
+	require.NoError(t, err)
+	path, root, err := SparseMerkleProof(curve, 8, []uint64{revokedIndex, 17}, revokedIndex)
+	require.NoError(t, err)
+	commitment, err := IdentifierCommitment(curve, revoked, big.NewInt(7))
+	require.NoError(t, err)
+	forged := NewNonMembership(8)
+	forged.Root, forged.Commitment, forged.ID, forged.Blinding = root, commitment, revoked, 7
+	for i := range path {
+		forged.Path[i] = path[i]
+	}
+	require.Error(t, test.IsSolved(shape, forged, curve.ScalarField()))
+
+	// 承诺与标识不一致
+	wrong := *assignment.(*NonMembership)
+	wrong.Blinding = 8
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+}
@@ -0,0 +1,56 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// Preimage 证明知道公开哈希 Hash = MiMC(Preimage) 的原像
+type Preimage struct {
+	Preimage frontend.Variable
+	Hash     frontend.Variable `gnark:",public"`
+}
+
+func (c *Preimage) Define(api frontend.API) error {
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Preimage)
+	api.AssertIsEqual(c.Hash, h.Sum())
+	return nil
+}
+
+type preimageAssignment struct {
+	Preimage Element `json:"preimage"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "preimage",
+		Description: "knowledge of the mimc preimage of a public hash",
+		Decode:      decodePreimage,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(preimageAssignment{Preimage: NewElement(big.NewInt(42))})
+		},
+	})
+}
+
+func decodePreimage(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input preimageAssignment
+	if err := decodeJSON("preimage", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if err := checkElement(curve, "preimage", &input.Preimage); err != nil {
+		return nil, nil, err
+	}
+	digest, err := MiMC(curve, &input.Preimage.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Preimage{}, &Preimage{Preimage: &input.Preimage.Int, Hash: digest}, nil
+}
@@ -0,0 +1,43 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/oliverustc/gnarkabc/circuits"
+)
+
+// productAssignment product 电路的 json 赋值, 公开输入 N = P * Q 由解析时计算
+type productAssignment struct {
+	P Element `json:"p"`
+	Q Element `json:"q"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "product",
+		Description: "knowledge of factors p, q of the public n = p * q",
+		Decode:      decodeProduct,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(productAssignment{P: NewElement(big.NewInt(3)), Q: NewElement(big.NewInt(5))})
+		},
+	})
+}
+
+func decodeProduct(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input productAssignment
+	if err := decodeJSON("product", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if err := checkElement(curve, "p", &input.P); err != nil {
+		return nil, nil, err
+	}
+	if err := checkElement(curve, "q", &input.Q); err != nil {
+		return nil, nil, err
+	}
+	n := new(big.Int).Mul(&input.P.Int, &input.Q.Int)
+	n.Mod(n, curve.ScalarField())
+	return &circuits.Product{}, &circuits.Product{P: &input.P.Int, Q: &input.Q.Int, N: n}, nil
+}
@@ -0,0 +1,72 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// RangeBits 区间宽度上限, Upper - Lower 须小于 2^RangeBits
+const RangeBits = 64
+
+// RangeCheck 证明私密值 X 位于公开区间 [Lower, Upper] 内
+type RangeCheck struct {
+	X     frontend.Variable
+	Lower frontend.Variable `gnark:",public"`
+	Upper frontend.Variable `gnark:",public"`
+}
+
+func (c *RangeCheck) Define(api frontend.API) error {
+	checker := rangecheck.New(api)
+	checker.Check(api.Sub(c.X, c.Lower), RangeBits)
+	checker.Check(api.Sub(c.Upper, c.X), RangeBits)
+	return nil
+}
+
+type rangeAssignment struct {
+	X     Element `json:"x"`
+	Lower Element `json:"lower"`
+	Upper Element `json:"upper"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "range",
+		Description: fmt.Sprintf("private x lies in the public range [lower, upper], upper - lower < 2^%d", RangeBits),
+		Decode:      decodeRange,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(rangeAssignment{
+				X:     NewElement(big.NewInt(30)),
+				Lower: NewElement(big.NewInt(18)),
+				Upper: NewElement(big.NewInt(65)),
+			})
+		},
+	})
+}
+
+func decodeRange(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input rangeAssignment
+	if err := decodeJSON("range", data, &input); err != nil {
+		return nil, nil, err
+	}
+	for _, e := range []struct {
+		name  string
+		value *Element
+	}{{"x", &input.X}, {"lower", &input.Lower}, {"upper", &input.Upper}} {
+		if err := checkElement(curve, e.name, e.value); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.X.Cmp(&input.Lower.Int) < 0 || input.X.Cmp(&input.Upper.Int) > 0 {
+		return nil, nil, fmt.Errorf("x is not in [lower, upper]")
+	}
+	if new(big.Int).Sub(&input.Upper.Int, &input.Lower.Int).BitLen() > RangeBits {
+		return nil, nil, fmt.Errorf("upper - lower must be less than 2^%d", RangeBits)
+	}
+	assignment := &RangeCheck{X: &input.X.Int, Lower: &input.Lower.Int, Upper: &input.Upper.Int}
+	return &RangeCheck{}, assignment, nil
+}
@@ -0,0 +1,25 @@
+package circuits
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeCheck(t *testing.T) {
+	curve := ecc.BN254
+	shape, assignment, err := decodeRange(curve, []byte(`{"x": 18, "lower": 18, "upper": 65}`))
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	_, _, err = decodeRange(curve, []byte(`{"x": 66, "lower": 18, "upper": 65}`))
+	require.ErrorContains(t, err, "not in [lower, upper]")
+	_, _, err = decodeRange(curve, []byte(`{"x": 1, "lower": 0, "upper": "0x10000000000000000"}`))
+	require.ErrorContains(t, err, "less than 2^64")
+
+	// 绕过解析直接给出区间外的赋值, 约束不满足
+	outside := &RangeCheck{X: 17, Lower: 18, Upper: 65}
+	require.Error(t, test.IsSolved(shape, outside, curve.ScalarField()))
+}
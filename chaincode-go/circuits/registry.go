@@ -0,0 +1,118 @@
+// Package circuits 按名称注册可用于证明生成和测试的电路, 每个电路提供从 json 解析赋值的方法
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Definition 已注册的电路
+type Definition struct {
+	Name        string
+	Description string
+	// Decode 解析 json 赋值, 返回编译用的电路结构和对应的赋值.
+	// 电路结构的大小 (如 Merkle 路径长度) 可以由赋值决定
+	Decode func(curve ecc.ID, data []byte) (shape frontend.Circuit, assignment frontend.Circuit, err error)
+	// Example 生成一组有效赋值的 json, 用于测试和演示
+	Example func(curve ecc.ID) ([]byte, error)
+}
+
+var (
+	registryMu  sync.RWMutex
+	definitions = map[string]Definition{}
+)
+
+// Register 注册电路, 名称重复或定义不完整时 panic, 通常在 init 中调用
+func Register(definition Definition) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if definition.Name == "" || definition.Decode == nil || definition.Example == nil {
+		panic("circuits: incomplete definition " + definition.Name)
+	}
+	if _, ok := definitions[definition.Name]; ok {
+		panic("circuits: Register called twice for " + definition.Name)
+	}
+	definitions[definition.Name] = definition
+}
+
+// Lookup 按名称查找电路
+func Lookup(name string) (Definition, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	definition, ok := definitions[name]
+	if !ok {
+		return Definition{}, fmt.Errorf("unknown circuit %q, available: %s", name, strings.Join(namesLocked(), ", "))
+	}
+	return definition, nil
+}
+
+// Names 返回已注册的电路名称, 按字母排序
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Element json 中的域元素, 可以写作数字或十进制、0x 十六进制字符串
+type Element struct {
+	big.Int
+}
+
+// NewElement 由 big.Int 构造 Element
+func NewElement(v *big.Int) Element {
+	var e Element
+	e.Set(v)
+	return e
+}
+
+// UnmarshalJSON 只接受十进制和 0x 前缀的十六进制, 与链码解析公开输入的规则一致.
+// 不使用 SetString 的 base 0, 否则 "010" 会按八进制解析, "0b1"、"1_000" 也会被接受
+func (e *Element) UnmarshalJSON(data []byte) error {
+	str := strings.Trim(string(data), `"`)
+	var ok bool
+	if hexStr, isHex := strings.CutPrefix(strings.ToLower(str), "0x"); isHex {
+		_, ok = e.SetString(hexStr, 16)
+	} else {
+		_, ok = e.SetString(str, 10)
+	}
+	if !ok {
+		return fmt.Errorf("invalid field element %s", data)
+	}
+	return nil
+}
+
+// MarshalJSON 输出十进制字符串, 避免 json 数字的精度问题
+func (e Element) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// checkElement 检查取值在曲线标量域内
+func checkElement(curve ecc.ID, name string, e *Element) error {
+	if e.Sign() < 0 || e.Cmp(curve.ScalarField()) >= 0 {
+		return fmt.Errorf("%s is out of the %s scalar field", name, curve)
+	}
+	return nil
+}
+
+func decodeJSON(name string, data []byte, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s assignment: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/oliverustc/gnarkabc/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamplesSolveOnAllCurves(t *testing.T) {
+	require.Equal(t, []string{"credential", "eddsa", "joinsplit", "member", "merkle", "nonmember", "preimage", "product", "range", "threshold", "vote"}, Names())
+	for _, name := range Names() {
+		definition, err := Lookup(name)
+		require.NoError(t, err)
+		for _, curveName := range utils.CurveNameList {
+			curve := utils.CurveMap[curveName]
+			example, err := definition.Example(curve)
+			require.NoError(t, err)
+			shape, assignment, err := definition.Decode(curve, example)
+			require.NoError(t, err, "%s %s", name, curveName)
+			require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()), "%s %s", name, curveName)
+		}
+	}
+}
+
+func TestLookupAndRegister(t *testing.T) {
+	_, err := Lookup("missing")
+	require.ErrorContains(t, err, "unknown circuit")
+
+	definition, err := Lookup("product")
+	require.NoError(t, err)
+	require.Panics(t, func() { Register(definition) })
+	require.Panics(t, func() { Register(Definition{Name: "incomplete"}) })
+}
+
+func TestElementJSON(t *testing.T) {
+	var values []Element
+	require.NoError(t, json.Unmarshal([]byte(`[15, "15", "0xf"]`), &values))
+	for _, v := range values {
+		require.Equal(t, int64(15), v.Int64())
+	}
+	require.Error(t, json.Unmarshal([]byte(`["fifteen"]`), &values))
+	// 前导零仍按十进制解析, 不接受八进制、二进制和下划线分隔
+	require.NoError(t, json.Unmarshal([]byte(`["010", "0XF"]`), &values))
+	require.Equal(t, int64(10), values[0].Int64())
+	require.Equal(t, int64(15), values[1].Int64())
+	for _, invalid := range []string{`["0b1"]`, `["0o17"]`, `["1_000"]`, `["0x"]`} {
+		require.Error(t, json.Unmarshal([]byte(invalid), &values), invalid)
+	}
+
+	data, err := json.Marshal(NewElement(big.NewInt(15)))
+	require.NoError(t, err)
+	require.Equal(t, `"15"`, string(data))
+
+	_, _, err = decodeProduct(ecc.BN254, []byte(`{"p": "-1", "q": 2}`))
+	require.ErrorContains(t, err, "out of the")
+}
+
+func TestMiMC(t *testing.T) {
+	a, err := MiMC(ecc.BN254, big.NewInt(1), big.NewInt(2))
+	require.NoError(t, err)
+	b, err := MiMC(ecc.BN254, big.NewInt(2), big.NewInt(1))
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+
+	_, err = MiMC(ecc.BN254, ecc.BN254.ScalarField())
+	require.ErrorContains(t, err, "out of the")
+
+	// 原像电路使用相同的 MiMC, 错误的哈希不能满足约束
+	shape, assignment, err := decodePreimage(ecc.BLS12_381, []byte(`{"preimage": 7}`))
+	require.NoError(t, err)
+	assignment.(*Preimage).Hash = 1
+	require.Error(t, test.IsSolved(shape, assignment, ecc.BLS12_381.ScalarField()))
+}
@@ -0,0 +1,96 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+	"github.com/consensys/gnark/std/rangecheck"
+)
+
+// Threshold 证明 Commitment = MiMC(Value, Blinding) 承诺的私密值不小于公开阈值 Threshold.
+// Value 和 Threshold 都限制在 [0, 2^RangeBits) 内, 否则 Value - Threshold 可能在域上回绕.
+// Challenge 为验证方给出的挑战, 绑定到证明中, 使证明不能被他人或在其他交易中重放
+type Threshold struct {
+	Commitment frontend.Variable `gnark:",public"`
+	Threshold  frontend.Variable `gnark:",public"`
+	Challenge  frontend.Variable `gnark:",public"`
+	Value      frontend.Variable
+	Blinding   frontend.Variable
+}
+
+func (c *Threshold) Define(api frontend.API) error {
+	bindGadget(api, c.Challenge)
+
+	checker := rangecheck.New(api)
+	checker.Check(c.Value, RangeBits)
+	checker.Check(c.Threshold, RangeBits)
+	checker.Check(api.Sub(c.Value, c.Threshold), RangeBits)
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Value, c.Blinding)
+	api.AssertIsEqual(c.Commitment, h.Sum())
+	return nil
+}
+
+// bindGadget 把不参与其他约束的公开输入 (挑战、参数摘要) 平方一次, 使其出现在约束中.
+// 否则 Groth16 验证时其取值不影响结果, 证明可以换一个取值重放
+func bindGadget(api frontend.API, v frontend.Variable) {
+	api.Mul(v, v)
+}
+
+// ValueCommitment 发行方登记的私密值承诺 MiMC(value, blinding)
+func ValueCommitment(curve ecc.ID, value uint64, blinding *big.Int) (*big.Int, error) {
+	return MiMC(curve, new(big.Int).SetUint64(value), blinding)
+}
+
+type thresholdAssignment struct {
+	Value     uint64  `json:"value"`
+	Blinding  Element `json:"blinding"`
+	Threshold uint64  `json:"threshold"`
+	Challenge Element `json:"challenge"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "threshold",
+		Description: "the value committed by the public mimc(value, blinding) is at least the public threshold",
+		Decode:      decodeThreshold,
+		Example: func(curve ecc.ID) ([]byte, error) {
+			return json.Marshal(thresholdAssignment{Value: 30, Blinding: NewElement(big.NewInt(987654321)), Threshold: 18, Challenge: NewElement(big.NewInt(20250808))})
+		},
+	})
+}
+
+func decodeThreshold(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input thresholdAssignment
+	if err := decodeJSON("threshold", data, &input); err != nil {
+		return nil, nil, err
+	}
+	for name, e := range map[string]*Element{"blinding": &input.Blinding, "challenge": &input.Challenge} {
+		if err := checkElement(curve, name, e); err != nil {
+			return nil, nil, err
+		}
+	}
+	if input.Value < input.Threshold {
+		return nil, nil, fmt.Errorf("value is less than the threshold")
+	}
+	commitment, err := ValueCommitment(curve, input.Value, &input.Blinding.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment := &Threshold{
+		Commitment: commitment,
+		Threshold:  input.Threshold,
+		Challenge:  &input.Challenge.Int,
+		Value:      input.Value,
+		Blinding:   &input.Blinding.Int,
+	}
+	return &Threshold{}, assignment, nil
+}
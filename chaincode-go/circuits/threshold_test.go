@@ -0,0 +1,33 @@
+package circuits
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreshold(t *testing.T) {
+	curve := ecc.BN254
+	shape, assignment, err := decodeThreshold(curve, []byte(`{"value": 18, "blinding": 7, "threshold": 18, "challenge": 42}`))
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	_, _, err = decodeThreshold(curve, []byte(`{"value": 17, "blinding": 7, "threshold": 18}`))
+	require.ErrorContains(t, err, "less than the threshold")
+
+	// 绕过解析直接给出低于阈值的值, 约束不满足
+	commitment, err := ValueCommitment(curve, 17, big.NewInt(7))
+	require.NoError(t, err)
+	below := &Threshold{Commitment: commitment, Threshold: 18, Challenge: 42, Value: 17, Blinding: 7}
+	require.Error(t, test.IsSolved(shape, below, curve.ScalarField()))
+	// 阈值接近模数时 Value - Threshold 回绕为小数, 须由阈值的范围检查拒绝
+	wrapped := &Threshold{Commitment: commitment, Threshold: new(big.Int).Sub(curve.ScalarField(), big.NewInt(1)), Challenge: 42, Value: 17, Blinding: 7}
+	require.Error(t, test.IsSolved(shape, wrapped, curve.ScalarField()))
+	// 承诺与值不一致
+	wrong := *assignment.(*Threshold)
+	wrong.Value = 19
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+}
@@ -0,0 +1,153 @@
+package circuits
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/hash/mimc"
+)
+
+// MaxVoteOptions 投票电路支持的选项个数上限, Choice 按 8 位分解
+const MaxVoteOptions = 256
+
+// Vote 匿名投票: 证明 MiMC(Secret) 是根为 Root 的选民树中的叶子, 且 Nullifier = MiMC(Secret, Election).
+// 同一选民在同一选举中只能得到一个 nullifier, 不同选举之间的 nullifier 不可关联.
+// Choice 作为公开输入绑定到证明中, 他人转发证明时不能修改选项
+type Vote struct {
+	Root      frontend.Variable `gnark:",public"`
+	Nullifier frontend.Variable `gnark:",public"`
+	Election  frontend.Variable `gnark:",public"`
+	Choice    frontend.Variable `gnark:",public"`
+	Secret    frontend.Variable
+	Index     frontend.Variable
+	Path      []frontend.Variable
+}
+
+// NewVote 返回选民树深度为 depth 的电路结构
+func NewVote(depth int) *Vote {
+	return &Vote{Path: make([]frontend.Variable, depth)}
+}
+
+func (c *Vote) Define(api frontend.API) error {
+	api.ToBinary(c.Choice, 8)
+
+	h, err := mimc.NewMiMC(api)
+	if err != nil {
+		return err
+	}
+	h.Write(c.Secret)
+	root, err := merkleRootGadget(api, h.Sum(), c.Index, c.Path)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(c.Root, root)
+
+	h.Reset()
+	h.Write(c.Secret, c.Election)
+	api.AssertIsEqual(c.Nullifier, h.Sum())
+	return nil
+}
+
+// VoterCommitment 选民登记到选民树中的叶子 MiMC(secret)
+func VoterCommitment(curve ecc.ID, secret *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret)
+}
+
+// VoteNullifier 选民在选举 election 中的 nullifier MiMC(secret, election)
+func VoteNullifier(curve ecc.ID, secret, election *big.Int) (*big.Int, error) {
+	return MiMC(curve, secret, election)
+}
+
+type voteAssignment struct {
+	Secret   Element   `json:"secret"`
+	Election Element   `json:"election"`
+	Choice   uint64    `json:"choice"`
+	Index    uint64    `json:"index"`
+	Path     []Element `json:"path"`
+}
+
+func init() {
+	Register(Definition{
+		Name:        "vote",
+		Description: "anonymous vote: membership of mimc(secret) in a voter tree with nullifier mimc(secret, election) and a public choice",
+		Decode:      decodeVote,
+		Example:     exampleVote,
+	})
+}
+
+func exampleVote(curve ecc.ID) ([]byte, error) {
+	voters := make([]*big.Int, 10)
+	for i := range voters {
+		var err error
+		if voters[i], err = VoterCommitment(curve, big.NewInt(int64(1000+i))); err != nil {
+			return nil, err
+		}
+	}
+	const index = 4
+	path, _, err := MerkleProof(curve, voters, 8, index)
+	if err != nil {
+		return nil, err
+	}
+	input := voteAssignment{
+		Secret:   NewElement(big.NewInt(1000 + index)),
+		Election: NewElement(big.NewInt(7)),
+		Choice:   1,
+		Index:    index,
+	}
+	for _, sibling := range path {
+		input.Path = append(input.Path, NewElement(sibling))
+	}
+	return json.Marshal(input)
+}
+
+func decodeVote(curve ecc.ID, data []byte) (frontend.Circuit, frontend.Circuit, error) {
+	var input voteAssignment
+	if err := decodeJSON("vote", data, &input); err != nil {
+		return nil, nil, err
+	}
+	if len(input.Path) == 0 {
+		return nil, nil, fmt.Errorf("voter tree path must not be empty")
+	}
+	if input.Choice >= MaxVoteOptions {
+		return nil, nil, fmt.Errorf("choice %d out of range [0, %d)", input.Choice, MaxVoteOptions)
+	}
+	for name, e := range map[string]*Element{"secret": &input.Secret, "election": &input.Election} {
+		if err := checkElement(curve, name, e); err != nil {
+			return nil, nil, err
+		}
+	}
+	path := make([]*big.Int, len(input.Path))
+	for i := range input.Path {
+		if err := checkElement(curve, fmt.Sprintf("path[%d]", i), &input.Path[i]); err != nil {
+			return nil, nil, err
+		}
+		path[i] = &input.Path[i].Int
+	}
+	leaf, err := VoterCommitment(curve, &input.Secret.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := MerkleRoot(curve, leaf, input.Index, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	nullifier, err := VoteNullifier(curve, &input.Secret.Int, &input.Election.Int)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assignment := NewVote(len(path))
+	assignment.Root = root
+	assignment.Nullifier = nullifier
+	assignment.Election = &input.Election.Int
+	assignment.Choice = input.Choice
+	assignment.Secret = &input.Secret.Int
+	assignment.Index = input.Index
+	for i := range path {
+		assignment.Path[i] = path[i]
+	}
+	return NewVote(len(path)), assignment, nil
+}
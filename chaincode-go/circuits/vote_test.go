@@ -0,0 +1,41 @@
+package circuits
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVoteCircuit(t *testing.T) {
+	curve := ecc.BN254
+	example, err := exampleVote(curve)
+	require.NoError(t, err)
+	shape, assignment, err := decodeVote(curve, example)
+	require.NoError(t, err)
+	require.NoError(t, test.IsSolved(shape, assignment, curve.ScalarField()))
+
+	vote := assignment.(*Vote)
+	nullifier, err := VoteNullifier(curve, big.NewInt(1004), big.NewInt(7))
+	require.NoError(t, err)
+	require.Equal(t, nullifier, vote.Nullifier)
+
+	// 换一个选举的 nullifier 或超出 8 位的选项不能满足约束
+	wrong := *vote
+	wrong.Election = 8
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+	wrong = *vote
+	wrong.Choice = MaxVoteOptions
+	require.Error(t, test.IsSolved(shape, &wrong, curve.ScalarField()))
+
+	var input voteAssignment
+	require.NoError(t, json.Unmarshal(example, &input))
+	input.Choice = MaxVoteOptions
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	_, _, err = decodeVote(curve, data)
+	require.ErrorContains(t, err, "out of range")
+}
@@ -0,0 +1,77 @@
+// prove 编译已注册的电路并生成证明, 产物写入 <out>/<protocol>_<curve>_<circuit>.json
+//
+//	go run ./cmd/prove --protocol groth16 --curve BN254 --circuit merkle --assignment merkle.json
+//
+// 不指定 --assignment 时使用电路自带的示例赋值, --example 只输出示例赋值
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/oliverustc/gnarkabc/utils"
+)
+
+func main() {
+	protocol := flag.String("protocol", prover.ProtocolGroth16, "proof system: groth16 or plonk")
+	curveName := flag.String("curve", "BN254", "curve name")
+	circuitName := flag.String("circuit", "product", "circuit name, one of "+strings.Join(circuits.Names(), ", "))
+	assignmentPath := flag.String("assignment", "", "json file with the circuit assignment, defaults to the circuit example")
+	example := flag.Bool("example", false, "print the example assignment of the circuit and exit")
+	keysDir := flag.String("keys", "", "load keys from this directory, or setup and save them there if absent; setup without saving when empty")
+	outDir := flag.String("out", "output", "output directory for the artifact")
+	flag.Parse()
+
+	definition, err := circuits.Lookup(*circuitName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	curve, ok := utils.CurveMap[*curveName]
+	if !ok {
+		log.Fatalf("unknown curve %q", *curveName)
+	}
+
+	var assignmentJSON []byte
+	if *assignmentPath == "" || *example {
+		if assignmentJSON, err = definition.Example(curve); err != nil {
+			log.Fatal(err)
+		}
+		if *example {
+			fmt.Println(string(assignmentJSON))
+			return
+		}
+	} else if assignmentJSON, err = os.ReadFile(*assignmentPath); err != nil {
+		log.Fatalf("failed to read assignment: %v", err)
+	}
+	shape, assignment, err := definition.Decode(curve, assignmentJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	p, err := prover.Compile(*protocol, *curveName, *circuitName, shape)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *keysDir == "" {
+		err = p.Setup()
+	} else {
+		err = p.SetupOrLoadKeys(*keysDir)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	artifact, err := p.ProveAssignment(assignment)
+	if err != nil {
+		log.Fatal(err)
+	}
+	path, err := artifact.Write(*outDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %s", path)
+}
@@ -0,0 +1,259 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+)
+
+const (
+	poolObjectType = "pool"
+
+	// joinSplitNbPublic circuits.JoinSplit 的公开输入: Root, 两个 Nullifier, 两个 Commitment, ValueIn, ValueOut
+	joinSplitNbPublic = 1 + 2*gvcircuits.JoinSplitNotes + 2
+)
+
+// joinSplitSchema circuits.JoinSplit 公开输入的 schema, 按 JoinSplitNotes 为 2 列出
+var joinSplitSchema = []InputSchema{
+	{Name: "root", Type: InputField},
+	{Name: "nullifier0", Type: InputField},
+	{Name: "nullifier1", Type: InputField},
+	{Name: "commitment0", Type: InputField},
+	{Name: "commitment1", Type: InputField},
+	{Name: "valueIn", Type: InputUint64},
+	{Name: "valueOut", Type: InputUint64},
+}
+
+// AssetContract 基于 circuits.JoinSplit 电路的保密资产 (zk-UTXO). 票据承诺保存在与资产池同名的 Merkle 树中,
+// 转账花费两个票据并生成两个新票据, 链上只出现 nullifier 和新的承诺, 不出现金额和所有者
+type AssetContract struct {
+	contractapi.Contract
+}
+
+// AssetPool 资产池. Supply 为铸造减去销毁的总量, 等于池中所有未花费票据的金额之和
+type AssetPool struct {
+	ID     string `json:"id"`
+	VKID   string `json:"vkId"`
+	Curve  string `json:"curve"`
+	Issuer string `json:"issuer"`
+	Supply uint64 `json:"supply"`
+}
+
+func poolKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(poolObjectType, []string{id})
+}
+
+func readAssetPool(ctx contractapi.TransactionContextInterface, id string) (*AssetPool, error) {
+	key, err := poolKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset pool %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("asset pool %s does not exist", id)
+	}
+	var pool AssetPool
+	if err := json.Unmarshal(data, &pool); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal asset pool %s: %v", id, err)
+	}
+	return &pool, nil
+}
+
+func putAssetPool(ctx contractapi.TransactionContextInterface, pool *AssetPool) error {
+	key, err := poolKey(ctx, pool.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(pool)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// poolNullifierScope 资产池 nullifier 的作用域, 与验证密钥和选举的 nullifier 分开
+func poolNullifierScope(id string) string {
+	return poolObjectType + "~" + id
+}
+
+// CreatePool 创建资产池和同名的票据树. vkID 为已登记的 circuits.JoinSplit 的 Groth16 验证密钥,
+// 登记时须声明 joinSplitSchema, 电路的路径长度须等于 depth; issuer 为可以铸造和销毁的组织 MSP ID
+func (c *AssetContract) CreatePool(ctx contractapi.TransactionContextInterface, id string, vkID string, issuer string, depth int, historySize int) error {
+	if issuer == "" {
+		return fmt.Errorf("issuer must not be empty")
+	}
+	key, err := poolKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read asset pool %s: %v", id, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("asset pool %s already exists", id)
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.Protocol != protocolGroth16 || vkRecord.NbPublic != joinSplitNbPublic {
+		return fmt.Errorf("verifying key %s is not a groth16 key with %d public inputs", vkID, joinSplitNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "joinsplit", joinSplitSchema); err != nil {
+		return err
+	}
+	// 票据树只能由本合约追加
+	if err := createMerkleTree(ctx, id, vkRecord.Curve, depth, historySize, ""); err != nil {
+		return err
+	}
+	return putAssetPool(ctx, &AssetPool{ID: id, VKID: vkID, Curve: vkRecord.Curve, Issuer: issuer})
+}
+
+// GetPool 查询资产池
+func (c *AssetContract) GetPool(ctx contractapi.TransactionContextInterface, id string) (*AssetPool, error) {
+	return readAssetPool(ctx, id)
+}
+
+// parseNotes 解析两个域元素组成的 json 数组
+func parseNotes(name string, str string) ([]string, error) {
+	var notes []string
+	if err := json.Unmarshal([]byte(str), &notes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", name, err)
+	}
+	if len(notes) != gvcircuits.JoinSplitNotes {
+		return nil, fmt.Errorf("%s must have %d elements", name, gvcircuits.JoinSplitNotes)
+	}
+	for i := range notes {
+		var err error
+		if notes[i], err = normalizeFieldElement(notes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+// joinSplit 验证 join-split 证明, 消费 nullifier 并把新承诺追加到票据树, 返回第一个新票据的下标
+func joinSplit(ctx contractapi.TransactionContextInterface, pool *AssetPool, proofStr, nullifiersJSON, commitmentsJSON, root string, valueIn, valueOut uint64) (uint64, error) {
+	nullifiers, err := parseNotes("nullifiers", nullifiersJSON)
+	if err != nil {
+		return 0, err
+	}
+	// 同一交易读不到自己的写入, 两个 nullifier 相同时 spendNullifier 无法发现
+	if nullifiers[0] == nullifiers[1] {
+		return 0, newError(ErrNullifierSpent, "nullifier %s is spent twice", nullifiers[0])
+	}
+	commitments, err := parseNotes("commitments", commitmentsJSON)
+	if err != nil {
+		return 0, err
+	}
+	root, err = normalizeFieldElement(root)
+	if err != nil {
+		return 0, err
+	}
+	known, err := isKnownRoot(ctx, pool.ID, root)
+	if err != nil {
+		return 0, err
+	}
+	if !known {
+		return 0, newError(ErrBindingMismatch, "root is not among the recent roots of asset pool %s", pool.ID)
+	}
+
+	vkRecord, err := readVerifyingKeyRecord(ctx, pool.VKID)
+	if err != nil {
+		return 0, err
+	}
+	inputs := append([]string{root}, nullifiers...)
+	inputs = append(inputs, commitments...)
+	inputs = append(inputs, strconv.FormatUint(valueIn, 10), strconv.FormatUint(valueOut, 10))
+	witness, err := json.Marshal(inputs)
+	if err != nil {
+		return 0, err
+	}
+	if _, _, err := verifyAndRecord(ctx, vkRecord, proofStr, string(witness), nil); err != nil {
+		return 0, err
+	}
+	for _, nullifier := range nullifiers {
+		if err := spendNullifier(ctx, poolNullifierScope(pool.ID), nullifier); err != nil {
+			return 0, err
+		}
+	}
+	return appendLeaves(ctx, pool.ID, commitments)
+}
+
+// Transfer 保密转账: 花费 nullifiers 对应的两个票据, 生成 commitments 两个新票据, root 为证明引用的票据树的根,
+// 可以是最近 historySize 个根中的任意一个. 返回第一个新票据在票据树中的下标
+func (c *AssetContract) Transfer(ctx contractapi.TransactionContextInterface, poolID string, proofStr string, nullifiersJSON string, commitmentsJSON string, root string) (uint64, error) {
+	pool, err := readAssetPool(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+	return joinSplit(ctx, pool, proofStr, nullifiersJSON, commitmentsJSON, root, 0, 0)
+}
+
+// checkIssuer 只有资产池的发行方组织可以铸造和销毁
+func checkIssuer(ctx contractapi.TransactionContextInterface, pool *AssetPool) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != pool.Issuer {
+		return fmt.Errorf("only %s can mint or burn in asset pool %s", pool.Issuer, pool.ID)
+	}
+	return nil
+}
+
+// Mint 发行方铸造 amount, 证明中的 ValueIn 为 amount, 新票据的金额之和等于 amount 加上花费的票据金额
+func (c *AssetContract) Mint(ctx contractapi.TransactionContextInterface, poolID string, proofStr string, nullifiersJSON string, commitmentsJSON string, root string, amount uint64) (uint64, error) {
+	pool, err := readAssetPool(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkIssuer(ctx, pool); err != nil {
+		return 0, err
+	}
+	if pool.Supply+amount < pool.Supply {
+		return 0, fmt.Errorf("supply of asset pool %s would overflow", poolID)
+	}
+	index, err := joinSplit(ctx, pool, proofStr, nullifiersJSON, commitmentsJSON, root, amount, 0)
+	if err != nil {
+		return 0, err
+	}
+	pool.Supply += amount
+	return index, putAssetPool(ctx, pool)
+}
+
+// Burn 发行方销毁 amount, 证明中的 ValueOut 为 amount, 通常用于赎回链下资产
+func (c *AssetContract) Burn(ctx contractapi.TransactionContextInterface, poolID string, proofStr string, nullifiersJSON string, commitmentsJSON string, root string, amount uint64) (uint64, error) {
+	pool, err := readAssetPool(ctx, poolID)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkIssuer(ctx, pool); err != nil {
+		return 0, err
+	}
+	if amount > pool.Supply {
+		return 0, fmt.Errorf("burn amount %d exceeds supply %d of asset pool %s", amount, pool.Supply, poolID)
+	}
+	index, err := joinSplit(ctx, pool, proofStr, nullifiersJSON, commitmentsJSON, root, 0, amount)
+	if err != nil {
+		return 0, err
+	}
+	pool.Supply -= amount
+	return index, putAssetPool(ctx, pool)
+}
+
+// IsNoteSpent 查询 nullifier 对应的票据是否已被花费
+func (c *AssetContract) IsNoteSpent(ctx contractapi.TransactionContextInterface, poolID string, nullifier string) (bool, error) {
+	normalized, err := normalizeFieldElement(nullifier)
+	if err != nil {
+		return false, err
+	}
+	return isNullifierSpent(ctx, poolNullifierScope(poolID), normalized)
+}
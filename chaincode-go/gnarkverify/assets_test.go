@@ -0,0 +1,228 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+const noteTreeDepth = 4
+
+// note 测试用的票据, index 为其在票据树中的下标
+type note struct {
+	amount uint64
+	secret int64
+	rho    *big.Int
+	index  int
+}
+
+// placeholder 金额为 0 的占位输入票据
+func placeholder(secret, rho int64) note {
+	return note{secret: secret, rho: big.NewInt(rho)}
+}
+
+// noteWallet 在链下跟踪票据树的叶子, 为 join-split 生成证明
+type noteWallet struct {
+	t      *testing.T
+	curve  ecc.ID
+	prover *prover.Prover
+	leaves []*big.Int
+}
+
+// joinSplitTx join-split 的证明产物、Transfer/Mint/Burn 的参数和接收方得到的新票据
+type joinSplitTx struct {
+	artifact    *prover.Artifact
+	nullifiers  string
+	commitments string
+	root        string
+	outputs     [2]note
+}
+
+func (w *noteWallet) pk(secret int64) *big.Int {
+	pk, err := gvcircuits.NotePublicKey(w.curve, big.NewInt(secret))
+	require.NoError(w.t, err)
+	return pk
+}
+
+// add 登记上链后的新票据, 返回带下标的票据
+func (w *noteWallet) add(n note) note {
+	commitment, err := gvcircuits.NoteCommitment(w.curve, n.amount, w.pk(n.secret), n.rho)
+	require.NoError(w.t, err)
+	w.leaves = append(w.leaves, commitment)
+	n.index = len(w.leaves) - 1
+	return n
+}
+
+// root 链下计算的票据树的根
+func (w *noteWallet) root() *big.Int {
+	leaves := w.leaves
+	if len(leaves) == 0 {
+		leaves = []*big.Int{new(big.Int)}
+	}
+	_, root, err := gvcircuits.MerkleProof(w.curve, leaves, noteTreeDepth, 0)
+	require.NoError(w.t, err)
+	return root
+}
+
+// joinSplit 生成证明, outputs 只需给出金额和所有者, rho 由输入的 nullifier 导出
+func (w *noteWallet) joinSplit(inputs [2]note, outputs [2]note, valueIn, valueOut uint64) *joinSplitTx {
+	w.t.Helper()
+	type inputJSON struct {
+		Amount uint64     `json:"amount"`
+		Secret int64      `json:"secret"`
+		Rho    *big.Int   `json:"rho"`
+		Index  int        `json:"index"`
+		Path   []*big.Int `json:"path"`
+	}
+	type outputJSON struct {
+		Amount uint64   `json:"amount"`
+		PK     *big.Int `json:"pk"`
+	}
+	leaves := w.leaves
+	if len(leaves) == 0 {
+		leaves = []*big.Int{new(big.Int)}
+	}
+	root := w.root()
+	assignment := struct {
+		Root     *big.Int     `json:"root"`
+		Inputs   []inputJSON  `json:"inputs"`
+		Outputs  []outputJSON `json:"outputs"`
+		ValueIn  uint64       `json:"valueIn"`
+		ValueOut uint64       `json:"valueOut"`
+	}{Root: root, ValueIn: valueIn, ValueOut: valueOut}
+	tx := &joinSplitTx{root: root.String()}
+	var nullifiers [2]*big.Int
+	var nullifierStrs, commitments []string
+	for i, in := range inputs {
+		path, _, err := gvcircuits.MerkleProof(w.curve, leaves, noteTreeDepth, min(in.index, len(leaves)-1))
+		require.NoError(w.t, err)
+		assignment.Inputs = append(assignment.Inputs, inputJSON{in.amount, in.secret, in.rho, in.index, path})
+		nullifiers[i], err = gvcircuits.NoteNullifier(w.curve, big.NewInt(in.secret), in.rho)
+		require.NoError(w.t, err)
+		nullifierStrs = append(nullifierStrs, nullifiers[i].String())
+	}
+	for i, out := range outputs {
+		assignment.Outputs = append(assignment.Outputs, outputJSON{out.amount, w.pk(out.secret)})
+		rho, err := gvcircuits.NoteRho(w.curve, nullifiers, i)
+		require.NoError(w.t, err)
+		commitment, err := gvcircuits.NoteCommitment(w.curve, out.amount, w.pk(out.secret), rho)
+		require.NoError(w.t, err)
+		commitments = append(commitments, commitment.String())
+		tx.outputs[i] = note{amount: out.amount, secret: out.secret, rho: rho}
+	}
+	data, err := json.Marshal(assignment)
+	require.NoError(w.t, err)
+	definition, err := gvcircuits.Lookup("joinsplit")
+	require.NoError(w.t, err)
+	_, circuit, err := definition.Decode(w.curve, data)
+	require.NoError(w.t, err)
+	tx.artifact, err = w.prover.ProveAssignment(circuit)
+	require.NoError(w.t, err)
+	nullifiersJSON, err := json.Marshal(nullifierStrs)
+	require.NoError(w.t, err)
+	commitmentsJSON, err := json.Marshal(commitments)
+	require.NoError(w.t, err)
+	tx.nullifiers, tx.commitments = string(nullifiersJSON), string(commitmentsJSON)
+	return tx
+}
+
+func TestAssetPool(t *testing.T) {
+	for _, curveName := range []string{"BN254", "BLS12-381"} {
+		t.Run(curveName, func(t *testing.T) {
+			testAssetPool(t, curveName)
+		})
+	}
+}
+
+func testAssetPool(t *testing.T, curveName string) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	issuerContext, _, _ := newMockContext("Org1MSP")
+	issuerContext.GetStubReturns(chaincodeStub)
+	userContext, _, _ := newMockContext("Org2MSP")
+	userContext.GetStubReturns(chaincodeStub)
+	gnarkVerify := &GnarkVerifyContract{}
+	assets := &AssetContract{}
+
+	_, curve, err := parseCurve(curveName)
+	require.NoError(t, err)
+	p, err := prover.Compile("groth16", curveName, "joinsplit", gvcircuits.NewJoinSplit(noteTreeDepth))
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	wallet := &noteWallet{t: t, curve: curve, prover: p}
+	const alice, bob = 11, 21
+
+	// 铸造: 两个金额为 0 的占位输入, 引用空树的根
+	mint := wallet.joinSplit(
+		[2]note{placeholder(alice, 901), placeholder(alice, 902)},
+		[2]note{{amount: 30, secret: alice}, {amount: 20, secret: alice}}, 50, 0)
+	// 未声明 joinsplit schema 的密钥不能用于资产池
+	require.NoError(t, registerVerifyingKey(transactionContext, "unnamed", "groth16", curveName, mint.artifact.VK, ""))
+	require.ErrorContains(t, assets.CreatePool(transactionContext, "usd", "unnamed", "Org1MSP", noteTreeDepth, 8), "joinsplit schema")
+	require.NoError(t, registerVerifyingKey(transactionContext, "joinsplit", "groth16", curveName, mint.artifact.VK, schemaConfig(t, joinSplitSchema)))
+	require.ErrorContains(t, assets.CreatePool(transactionContext, "usd", "joinsplit", "", noteTreeDepth, 8), "issuer")
+	require.NoError(t, assets.CreatePool(transactionContext, "usd", "joinsplit", "Org1MSP", noteTreeDepth, 8))
+	require.ErrorContains(t, assets.CreatePool(transactionContext, "usd", "joinsplit", "Org1MSP", noteTreeDepth, 8), "already exists")
+	// 票据树不能由组织直接追加
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "usd", "1")
+	require.ErrorContains(t, err, "only")
+
+	_, err = assets.Mint(userContext, "usd", mint.artifact.Proof, mint.nullifiers, mint.commitments, mint.root, 50)
+	require.ErrorContains(t, err, "only Org1MSP")
+	_, err = assets.Mint(issuerContext, "usd", mint.artifact.Proof, mint.nullifiers, mint.commitments, mint.root, 60)
+	require.ErrorIs(t, err, ErrProofInvalid)
+	chaincodeStub.GetTxIDReturns("mint")
+	index, err := assets.Mint(issuerContext, "usd", mint.artifact.Proof, mint.nullifiers, mint.commitments, mint.root, 50)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), index)
+	aliceNotes := [2]note{wallet.add(mint.outputs[0]), wallet.add(mint.outputs[1])}
+	root, err := gnarkVerify.GetRoot(transactionContext, "usd")
+	require.NoError(t, err)
+	require.Equal(t, wallet.root().String(), root)
+
+	// alice 转给 bob 45, 找零 5, 链上不出现金额
+	transfer := wallet.joinSplit(aliceNotes,
+		[2]note{{amount: 45, secret: bob}, {amount: 5, secret: alice}}, 0, 0)
+	_, err = assets.Transfer(userContext, "usd", transfer.artifact.Proof, transfer.nullifiers, transfer.commitments, "12345")
+	require.ErrorIs(t, err, ErrBindingMismatch)
+	var twice []string
+	require.NoError(t, json.Unmarshal([]byte(transfer.nullifiers), &twice))
+	_, err = assets.Transfer(userContext, "usd", transfer.artifact.Proof, fmt.Sprintf(`[%q,%q]`, twice[0], twice[0]), transfer.commitments, transfer.root)
+	require.ErrorIs(t, err, ErrNullifierSpent)
+	chaincodeStub.GetTxIDReturns("transfer")
+	index, err = assets.Transfer(userContext, "usd", transfer.artifact.Proof, transfer.nullifiers, transfer.commitments, transfer.root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), index)
+	bobNote := wallet.add(transfer.outputs[0])
+	wallet.add(transfer.outputs[1])
+	spent, err := assets.IsNoteSpent(transactionContext, "usd", twice[1])
+	require.NoError(t, err)
+	require.True(t, spent)
+
+	// 同一票据不能花费两次
+	_, err = assets.Transfer(userContext, "usd", transfer.artifact.Proof, transfer.nullifiers, transfer.commitments, transfer.root)
+	require.ErrorIs(t, err, ErrNullifierSpent)
+
+	// bob 向发行方赎回 45, 超过供应量或非发行方时拒绝
+	burn := wallet.joinSplit(
+		[2]note{bobNote, placeholder(bob, 903)},
+		[2]note{{secret: bob}, {secret: bob}}, 0, 45)
+	_, err = assets.Burn(userContext, "usd", burn.artifact.Proof, burn.nullifiers, burn.commitments, burn.root, 45)
+	require.ErrorContains(t, err, "only Org1MSP")
+	_, err = assets.Burn(issuerContext, "usd", burn.artifact.Proof, burn.nullifiers, burn.commitments, burn.root, 51)
+	require.ErrorContains(t, err, "exceeds supply")
+	chaincodeStub.GetTxIDReturns("burn")
+	_, err = assets.Burn(issuerContext, "usd", burn.artifact.Proof, burn.nullifiers, burn.commitments, burn.root, 45)
+	require.NoError(t, err)
+	pool, err := assets.GetPool(transactionContext, "usd")
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), pool.Supply)
+	tree, err := gnarkVerify.GetMerkleTree(transactionContext, "usd")
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), tree.Size)
+}
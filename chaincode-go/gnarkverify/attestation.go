@@ -0,0 +1,278 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	valueCommitmentObjectType = "commitment"
+	attestationObjectType     = "attestation"
+
+	// PredicateGTE 承诺的值不小于阈值
+	PredicateGTE = "gte"
+
+	// thresholdNbPublic circuits.Threshold 的公开输入: Commitment, Threshold, Challenge
+	thresholdNbPublic = 3
+)
+
+// thresholdSchema circuits.Threshold 公开输入的 schema
+var thresholdSchema = []InputSchema{
+	{Name: "commitment", Type: InputField},
+	{Name: "threshold", Type: InputUint64},
+	{Name: "challenge", Type: InputField},
+}
+
+// AttestationContract 阈值证明: 发行方为主体的某个属性 (余额、年龄等) 登记私密值的承诺,
+// 持有者用 circuits.Threshold 电路证明该值不小于公开阈值, 合约记录带时间戳的证明结果供其他链码查询.
+// 证明绑定由调用者身份和 nonce 确定的挑战, 每个挑战只能使用一次, 重放旧证明不能刷新证明结果的时间戳
+type AttestationContract struct {
+	contractapi.Contract
+}
+
+// ValueCommitment 发行方登记的承诺 MiMC(value, blinding), 证明须使用发行方指定的验证密钥
+type ValueCommitment struct {
+	Issuer     string `json:"issuer"`
+	Subject    string `json:"subject"`
+	Attribute  string `json:"attribute"`
+	Commitment string `json:"commitment"`
+	VKID       string `json:"vkId"`
+	TxID       string `json:"txId"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Attestation 一次验证通过的阈值证明, Commitment 为当时登记的承诺, 承诺更新后不再有效
+type Attestation struct {
+	TxID       string `json:"txId"`
+	Issuer     string `json:"issuer"`
+	Subject    string `json:"subject"`
+	Attribute  string `json:"attribute"`
+	Predicate  string `json:"predicate"`
+	Threshold  uint64 `json:"threshold"`
+	Commitment string `json:"commitment"`
+	Challenge  string `json:"challenge"`
+	VKHash     string `json:"vkHash"`
+	Timestamp  string `json:"timestamp"`
+}
+
+func valueCommitmentKey(ctx contractapi.TransactionContextInterface, issuer, subject, attribute string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(valueCommitmentObjectType, []string{issuer, subject, attribute})
+}
+
+func readValueCommitment(ctx contractapi.TransactionContextInterface, issuer, subject, attribute string) (*ValueCommitment, error) {
+	key, err := valueCommitmentKey(ctx, issuer, subject, attribute)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commitment: %v", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s has no commitment to %s of %s", issuer, attribute, subject)
+	}
+	var commitment ValueCommitment
+	if err := json.Unmarshal(data, &commitment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal commitment: %v", err)
+	}
+	return &commitment, nil
+}
+
+// attestationNullifierScope 主体属性的证明挑战的作用域
+func attestationNullifierScope(commitment *ValueCommitment) string {
+	return attestationObjectType + "~" + commitment.Issuer + "~" + commitment.Subject + "~" + commitment.Attribute
+}
+
+// attestationChallenge 由承诺、调用者身份和 nonce 确定的挑战, 承诺更新后挑战随之改变
+func attestationChallenge(ctx contractapi.TransactionContextInterface, commitment *ValueCommitment, nonce string) (string, error) {
+	vkRecord, err := readVerifyingKeyRecord(ctx, commitment.VKID)
+	if err != nil {
+		return "", err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return "", err
+	}
+	return callerTag(ctx, curve, attestationObjectType, commitment.Issuer, commitment.Subject, commitment.Attribute, commitment.Commitment, nonce)
+}
+
+// RegisterCommitment 发行方登记或更新主体属性值的承诺, vkID 为登记时声明了 thresholdSchema 的 circuits.Threshold 验证密钥.
+// 更新后基于旧承诺的证明结果不再被 HasAttestation 认可
+func (c *AttestationContract) RegisterCommitment(ctx contractapi.TransactionContextInterface, subject string, attribute string, commitment string, vkID string) error {
+	if subject == "" || attribute == "" {
+		return fmt.Errorf("subject and attribute must not be empty")
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.NbPublic != thresholdNbPublic {
+		return fmt.Errorf("verifying key %s does not have %d public inputs", vkID, thresholdNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "threshold", thresholdSchema); err != nil {
+		return err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return err
+	}
+	commitment, err = normalizeFieldElement(commitment)
+	if err != nil {
+		return err
+	}
+	if value, _ := new(big.Int).SetString(commitment, 10); value.Cmp(curve.ScalarField()) >= 0 {
+		return fmt.Errorf("commitment is not in the scalar field of %s", vkRecord.Curve)
+	}
+	issuer, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := valueCommitmentKey(ctx, issuer, subject, attribute)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ValueCommitment{
+		Issuer:     issuer,
+		Subject:    subject,
+		Attribute:  attribute,
+		Commitment: commitment,
+		VKID:       vkID,
+		TxID:       ctx.GetStub().GetTxID(),
+		Timestamp:  ts.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetCommitment 查询发行方为主体属性登记的承诺
+func (c *AttestationContract) GetCommitment(ctx contractapi.TransactionContextInterface, issuer string, subject string, attribute string) (*ValueCommitment, error) {
+	return readValueCommitment(ctx, issuer, subject, attribute)
+}
+
+// GetAttestationChallenge 查询调用者以 nonce 证明主体属性时所需的挑战, 须以 Attest 时的同一身份查询
+func (c *AttestationContract) GetAttestationChallenge(ctx contractapi.TransactionContextInterface, issuer string, subject string, attribute string, nonce string) (string, error) {
+	commitment, err := readValueCommitment(ctx, issuer, subject, attribute)
+	if err != nil {
+		return "", err
+	}
+	return attestationChallenge(ctx, commitment, nonce)
+}
+
+// Attest 验证承诺的值不小于 threshold 的证明, 记录并返回证明结果. nonce 为调用者选择的字符串,
+// 证明中的挑战由 GetAttestationChallenge 给出; 同一挑战再次使用时返回 NULLIFIER_SPENT, 他人转发证明时挑战不同, 证明无效
+func (c *AttestationContract) Attest(ctx contractapi.TransactionContextInterface, issuer string, subject string, attribute string, threshold uint64, proofStr string, nonce string) (*Attestation, error) {
+	commitment, err := readValueCommitment(ctx, issuer, subject, attribute)
+	if err != nil {
+		return nil, err
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, commitment.VKID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := attestationChallenge(ctx, commitment, nonce)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := json.Marshal([]string{commitment.Commitment, strconv.FormatUint(threshold, 10), challenge})
+	if err != nil {
+		return nil, err
+	}
+	_, record, err := verifyAndRecord(ctx, vkRecord, proofStr, string(inputs), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := spendNullifier(ctx, attestationNullifierScope(commitment), challenge); err != nil {
+		return nil, err
+	}
+
+	attestation := &Attestation{
+		TxID:       record.TxID,
+		Issuer:     issuer,
+		Subject:    subject,
+		Attribute:  attribute,
+		Predicate:  PredicateGTE,
+		Threshold:  threshold,
+		Commitment: commitment.Commitment,
+		Challenge:  challenge,
+		VKHash:     vkRecord.VKHash,
+		Timestamp:  record.Timestamp,
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(attestationObjectType, []string{issuer, subject, attribute, record.TxID})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(attestation)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, data); err != nil {
+		return nil, err
+	}
+	return attestation, nil
+}
+
+// GetAttestations 查询主体属性的全部证明结果, 包括基于旧承诺的
+func (c *AttestationContract) GetAttestations(ctx contractapi.TransactionContextInterface, issuer string, subject string, attribute string) ([]*Attestation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(attestationObjectType, []string{issuer, subject, attribute})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attestations: %v", err)
+	}
+	defer iterator.Close()
+	attestations := []*Attestation{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list attestations: %v", err)
+		}
+		var attestation Attestation
+		if err := json.Unmarshal(kv.Value, &attestation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attestation: %v", err)
+		}
+		attestations = append(attestations, &attestation)
+	}
+	return attestations, nil
+}
+
+// HasAttestation 供其他链码查询: 是否存在基于当前承诺、阈值不小于 threshold 的证明结果.
+// maxAgeSeconds 大于 0 时, 证明结果须在交易时间之前的 maxAgeSeconds 秒内
+func (c *AttestationContract) HasAttestation(ctx contractapi.TransactionContextInterface, issuer string, subject string, attribute string, threshold uint64, maxAgeSeconds int64) (bool, error) {
+	commitment, err := readValueCommitment(ctx, issuer, subject, attribute)
+	if err != nil {
+		return false, err
+	}
+	attestations, err := c.GetAttestations(ctx, issuer, subject, attribute)
+	if err != nil {
+		return false, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, attestation := range attestations {
+		if attestation.Commitment != commitment.Commitment || attestation.Threshold < threshold {
+			continue
+		}
+		if maxAgeSeconds > 0 {
+			ts, err := time.Parse(time.RFC3339Nano, attestation.Timestamp)
+			if err != nil {
+				return false, fmt.Errorf("invalid timestamp of attestation %s: %v", attestation.TxID, err)
+			}
+			if now.Sub(ts) > time.Duration(maxAgeSeconds)*time.Second {
+				continue
+			}
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,142 @@
+package gnarkverify
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestAttestation(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	holderContext := newClientContext(chaincodeStub, "Org2MSP", "x509::CN=holder")
+	// relayContext 同一组织的另一个成员, 拿到持有者的证明后尝试重放
+	relayContext := newClientContext(chaincodeStub, "Org2MSP", "x509::CN=relay")
+	gnarkVerify := &GnarkVerifyContract{}
+	attestations := &AttestationContract{}
+
+	definition, err := gvcircuits.Lookup("threshold")
+	require.NoError(t, err)
+	p, err := prover.Compile("groth16", "BN254", "threshold", &gvcircuits.Threshold{})
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	prove := func(value uint64, blinding int64, threshold uint64, challenge string) *prover.Artifact {
+		_, assignment, err := definition.Decode(ecc.BN254, []byte(fmt.Sprintf(`{"value":%d,"blinding":%d,"threshold":%d,"challenge":"%s"}`, value, blinding, threshold, challenge)))
+		require.NoError(t, err)
+		artifact, err := p.ProveAssignment(assignment)
+		require.NoError(t, err)
+		return artifact
+	}
+	commit := func(value uint64, blinding int64) string {
+		commitment, err := gvcircuits.ValueCommitment(ecc.BN254, value, big.NewInt(blinding))
+		require.NoError(t, err)
+		return commitment.String()
+	}
+
+	// 发行方 Org1 登记 alice 的年龄 30 的承诺
+	sample := prove(30, 77, 18, "0")
+	require.NoError(t, registerVerifyingKey(transactionContext, "threshold", "groth16", "BN254", sample.VK, schemaConfig(t, thresholdSchema)))
+	require.NoError(t, registerVerifyingKey(transactionContext, "unnamed", "groth16", "BN254", sample.VK, ""))
+	require.ErrorContains(t, attestations.RegisterCommitment(transactionContext, "alice", "age", commit(30, 77), "unnamed"), "threshold schema")
+	product := proveProduct(t, "groth16", "BN254", [2]int{3, 5})[0]
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", product.VK, ""))
+	require.ErrorContains(t, attestations.RegisterCommitment(transactionContext, "alice", "age", commit(30, 77), "product"), "3 public inputs")
+	require.ErrorContains(t, attestations.RegisterCommitment(transactionContext, "", "age", commit(30, 77), "threshold"), "must not be empty")
+	require.NoError(t, attestations.RegisterCommitment(transactionContext, "alice", "age", commit(30, 77), "threshold"))
+	commitment, err := attestations.GetCommitment(transactionContext, "Org1MSP", "alice", "age")
+	require.NoError(t, err)
+	require.Equal(t, commit(30, 77), commitment.Commitment)
+
+	// 持有者以 nonce n1 的挑战生成证明
+	challenge, err := attestations.GetAttestationChallenge(holderContext, "Org1MSP", "alice", "age", "n1")
+	require.NoError(t, err)
+	relayChallenge, err := attestations.GetAttestationChallenge(relayContext, "Org1MSP", "alice", "age", "n1")
+	require.NoError(t, err)
+	require.NotEqual(t, challenge, relayChallenge)
+	adult := prove(30, 77, 18, challenge)
+
+	// 其他组织登记的承诺不是 Org1 的承诺
+	_, err = attestations.Attest(holderContext, "Org2MSP", "alice", "age", 18, adult.Proof, "n1")
+	require.ErrorContains(t, err, "has no commitment")
+	// 证明的阈值与参数不一致时无效
+	_, err = attestations.Attest(holderContext, "Org1MSP", "alice", "age", 21, adult.Proof, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+
+	chaincodeStub.GetTxIDReturns("attest1")
+	attestation, err := attestations.Attest(holderContext, "Org1MSP", "alice", "age", 18, adult.Proof, "n1")
+	require.NoError(t, err)
+	require.Equal(t, &Attestation{
+		TxID:       "attest1",
+		Issuer:     "Org1MSP",
+		Subject:    "alice",
+		Attribute:  "age",
+		Predicate:  PredicateGTE,
+		Threshold:  18,
+		Commitment: commit(30, 77),
+		Challenge:  challenge,
+		VKHash:     attestation.VKHash,
+		Timestamp:  "2025-08-08T12:00:00Z",
+	}, attestation)
+	vk, err := gnarkVerify.GetVerifyingKey(transactionContext, "threshold")
+	require.NoError(t, err)
+	require.Equal(t, vk.VKHash, attestation.VKHash)
+	// 证明结果同时写入验证记录
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "attest1")
+	require.NoError(t, err)
+
+	// 重放: 持有者重复使用同一挑战, 换一个 nonce, 或他人转发证明, 都不产生新的证明结果
+	chaincodeStub.GetTxIDReturns("replay")
+	_, err = attestations.Attest(holderContext, "Org1MSP", "alice", "age", 18, adult.Proof, "n1")
+	require.ErrorIs(t, err, ErrNullifierSpent)
+	_, err = attestations.Attest(holderContext, "Org1MSP", "alice", "age", 18, adult.Proof, "n2")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = attestations.Attest(relayContext, "Org1MSP", "alice", "age", 18, adult.Proof, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	replayed, err := attestations.GetAttestations(transactionContext, "Org1MSP", "alice", "age")
+	require.NoError(t, err)
+	require.Len(t, replayed, 1)
+
+	for _, tc := range []struct {
+		threshold uint64
+		maxAge    int64
+		expected  bool
+	}{
+		{18, 0, true},
+		{16, 0, true},
+		{21, 0, false},
+		{18, 3600, true},
+	} {
+		ok, err := attestations.HasAttestation(transactionContext, "Org1MSP", "alice", "age", tc.threshold, tc.maxAge)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, ok, tc)
+	}
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2025, 8, 8, 14, 0, 0, 0, time.UTC)), nil)
+	ok, err := attestations.HasAttestation(transactionContext, "Org1MSP", "alice", "age", 18, 3600)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// 承诺更新后, 旧证明结果不再被认可, 但仍可查询
+	require.NoError(t, attestations.RegisterCommitment(transactionContext, "alice", "age", commit(31, 78), "threshold"))
+	ok, err = attestations.HasAttestation(transactionContext, "Org1MSP", "alice", "age", 18, 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = attestations.Attest(holderContext, "Org1MSP", "alice", "age", 18, adult.Proof, "n3")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	challenge, err = attestations.GetAttestationChallenge(holderContext, "Org1MSP", "alice", "age", "n3")
+	require.NoError(t, err)
+	chaincodeStub.GetTxIDReturns("attest2")
+	_, err = attestations.Attest(holderContext, "Org1MSP", "alice", "age", 21, prove(31, 78, 21, challenge).Proof, "n3")
+	require.NoError(t, err)
+	ok, err = attestations.HasAttestation(transactionContext, "Org1MSP", "alice", "age", 18, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	all, err := attestations.GetAttestations(transactionContext, "Org1MSP", "alice", "age")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+}
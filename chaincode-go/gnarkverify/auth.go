@@ -0,0 +1,301 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	memberGroupObjectType   = "group"
+	authorizationObjectType = "authorization"
+	actionDomain            = "action"
+	messageDomain           = "message"
+
+	// memberNbPublic circuits.Member 的公开输入: Root, Nullifier, Action, Message
+	memberNbPublic = 4
+)
+
+// memberSchema circuits.Member 公开输入的 schema
+var memberSchema = []InputSchema{
+	{Name: "root", Type: InputField},
+	{Name: "nullifier", Type: InputField},
+	{Name: "action", Type: InputField},
+	{Name: "message", Type: InputField},
+}
+
+// AuthContract 基于 circuits.Member 电路的匿名授权. 组织管理员维护成员秘密承诺 MiMC(secret) 组成的成员树的根,
+// 成员调用受保护的方法时证明自己在树中, 并给出由秘密和操作确定的 nullifier, 不暴露是哪个成员
+type AuthContract struct {
+	contractapi.Contract
+}
+
+// MemberGroup 组织的成员组, Root 为当前的成员树的根, 更新后基于旧根的证明不再被接受
+type MemberGroup struct {
+	Org       string `json:"org"`
+	VKID      string `json:"vkId"`
+	Curve     string `json:"curve"`
+	Root      string `json:"root"`
+	TxID      string `json:"txId"`
+	Timestamp string `json:"timestamp"`
+}
+
+// AnonymousAuth 匿名授权凭据, 受保护的方法以 json 字符串接收
+type AnonymousAuth struct {
+	Root      string `json:"root"`
+	Nullifier string `json:"nullifier"`
+	Proof     string `json:"proof"`
+}
+
+// AuthInputs 生成证明所需的公开输入, Action 和 Message 由操作和调用参数确定
+type AuthInputs struct {
+	Root    string `json:"root"`
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// Authorization 一次通过的匿名授权
+type Authorization struct {
+	TxID      string   `json:"txId"`
+	Org       string   `json:"org"`
+	Action    string   `json:"action"`
+	Args      []string `json:"args"`
+	Nullifier string   `json:"nullifier"`
+	Timestamp string   `json:"timestamp"`
+}
+
+func memberGroupKey(ctx contractapi.TransactionContextInterface, org string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(memberGroupObjectType, []string{org})
+}
+
+func readMemberGroup(ctx contractapi.TransactionContextInterface, org string) (*MemberGroup, error) {
+	key, err := memberGroupKey(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read member group of %s: %v", org, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s has no member group", org)
+	}
+	var group MemberGroup
+	if err := json.Unmarshal(data, &group); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal member group of %s: %v", org, err)
+	}
+	return &group, nil
+}
+
+// memberNullifierScope 成员组 nullifier 的作用域, nullifier 本身已包含操作
+func memberNullifierScope(org string) string {
+	return memberGroupObjectType + "~" + org
+}
+
+// authInputs 由操作和调用参数计算 Action 和 Message 公开输入
+func authInputs(group *MemberGroup, action string, args []string) (*AuthInputs, error) {
+	_, curve, err := parseCurve(group.Curve)
+	if err != nil {
+		return nil, err
+	}
+	actionID, err := json.Marshal([]string{group.Org, action})
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = []string{}
+	}
+	message, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthInputs{
+		Root:    group.Root,
+		Action:  domainTag(curve, actionDomain, string(actionID)).String(),
+		Message: domainTag(curve, messageDomain, string(message)).String(),
+	}, nil
+}
+
+// requireMember 匿名授权守卫: 验证调用者是 org 成员组中的成员且未执行过 action, 返回消费的 nullifier.
+// args 为受保护方法的其他参数, 绑定到证明中; 证明须基于当前的成员树的根 (否则 BINDING_MISMATCH),
+// 同一成员重复执行同一操作时返回 NULLIFIER_SPENT. 受保护的方法在修改状态前调用, 例如
+// CredentialContract.RevokeIssuerAsMember; action 应带合约前缀, 避免与其他方法的操作共用 nullifier
+func requireMember(ctx contractapi.TransactionContextInterface, org string, action string, args []string, authJSON string) (string, error) {
+	group, err := readMemberGroup(ctx, org)
+	if err != nil {
+		return "", err
+	}
+	var auth AnonymousAuth
+	if err := json.Unmarshal([]byte(authJSON), &auth); err != nil {
+		return "", fmt.Errorf("failed to unmarshal anonymous auth: %v", err)
+	}
+	root, err := normalizeFieldElement(auth.Root)
+	if err != nil {
+		return "", err
+	}
+	if root != group.Root {
+		return "", newError(ErrBindingMismatch, "root is not the current member root of %s", org)
+	}
+	nullifier, err := normalizeFieldElement(auth.Nullifier)
+	if err != nil {
+		return "", err
+	}
+	inputs, err := authInputs(group, action, args)
+	if err != nil {
+		return "", err
+	}
+	witness, err := json.Marshal([]string{root, nullifier, inputs.Action, inputs.Message})
+	if err != nil {
+		return "", err
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, group.VKID)
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := verifyAndRecord(ctx, vkRecord, auth.Proof, string(witness), nil); err != nil {
+		return "", err
+	}
+	if err := spendNullifier(ctx, memberNullifierScope(org), nullifier); err != nil {
+		return "", err
+	}
+	return nullifier, nil
+}
+
+// SetMemberRoot 组织管理员创建或更新本组织的成员组. vkID 为登记时声明了 memberSchema 的 circuits.Member 验证密钥,
+// root 为链下维护的成员树的根, 移除成员时重新计算并更新
+func (c *AuthContract) SetMemberRoot(ctx contractapi.TransactionContextInterface, vkID string, root string) error {
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return fmt.Errorf("only admins can set the member root")
+	}
+	org, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.NbPublic != memberNbPublic {
+		return fmt.Errorf("verifying key %s does not have %d public inputs", vkID, memberNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "member", memberSchema); err != nil {
+		return err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return err
+	}
+	root, err = normalizeFieldElement(root)
+	if err != nil {
+		return err
+	}
+	if rootInt, _ := new(big.Int).SetString(root, 10); rootInt.Cmp(curve.ScalarField()) >= 0 {
+		return fmt.Errorf("root is not in the scalar field of %s", vkRecord.Curve)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := memberGroupKey(ctx, org)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(MemberGroup{
+		Org:       org,
+		VKID:      vkID,
+		Curve:     vkRecord.Curve,
+		Root:      root,
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: ts.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetMemberGroup 查询组织的成员组
+func (c *AuthContract) GetMemberGroup(ctx contractapi.TransactionContextInterface, org string) (*MemberGroup, error) {
+	return readMemberGroup(ctx, org)
+}
+
+// GetAuthInputs 查询成员执行操作时证明所需的公开输入, argsJSON 为受保护方法其他参数的 json 数组
+func (c *AuthContract) GetAuthInputs(ctx contractapi.TransactionContextInterface, org string, action string, argsJSON string) (*AuthInputs, error) {
+	group, err := readMemberGroup(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal args: %v", err)
+	}
+	return authInputs(group, action, args)
+}
+
+// Authorize 以匿名授权守卫保护的通用操作: 记录 org 的某个成员执行了 action, 供其他链码查询
+func (c *AuthContract) Authorize(ctx contractapi.TransactionContextInterface, org string, action string, argsJSON string, authJSON string) (*Authorization, error) {
+	var args []string
+	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal args: %v", err)
+	}
+	nullifier, err := requireMember(ctx, org, action, args, authJSON)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = []string{}
+	}
+	authorization := &Authorization{
+		TxID:      ctx.GetStub().GetTxID(),
+		Org:       org,
+		Action:    action,
+		Args:      args,
+		Nullifier: nullifier,
+		Timestamp: ts.Format(time.RFC3339Nano),
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(authorizationObjectType, []string{org, action, authorization.TxID})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, data); err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+// GetAuthorizations 查询组织某个操作的全部授权记录
+func (c *AuthContract) GetAuthorizations(ctx contractapi.TransactionContextInterface, org string, action string) ([]*Authorization, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(authorizationObjectType, []string{org, action})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authorizations: %v", err)
+	}
+	defer iterator.Close()
+	authorizations := []*Authorization{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list authorizations: %v", err)
+		}
+		var authorization Authorization
+		if err := json.Unmarshal(kv.Value, &authorization); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal authorization: %v", err)
+		}
+		authorizations = append(authorizations, &authorization)
+	}
+	return authorizations, nil
+}
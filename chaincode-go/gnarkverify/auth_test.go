@@ -0,0 +1,142 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+const memberTreeDepth = 4
+
+func TestAnonymousAuth(t *testing.T) {
+	adminContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	withIdentity(adminContext, "Org1MSP", "admin")
+	clientContext, _, _ := newMockContext("Org1MSP")
+	clientContext.GetStubReturns(chaincodeStub)
+	withIdentity(clientContext, "Org1MSP", "client")
+	gnarkVerify := &GnarkVerifyContract{}
+	auth := &AuthContract{}
+
+	curve := ecc.BN254
+	p, err := prover.Compile("groth16", "BN254", "member", gvcircuits.NewMember(memberTreeDepth))
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	definition, err := gvcircuits.Lookup("member")
+	require.NoError(t, err)
+	members := make([]*big.Int, 3)
+	for i := range members {
+		members[i], err = gvcircuits.MemberCommitment(curve, big.NewInt(int64(3000+i)))
+		require.NoError(t, err)
+	}
+	memberRoot := func() string {
+		_, root, err := gvcircuits.MerkleProof(curve, members, memberTreeDepth, 0)
+		require.NoError(t, err)
+		return root.String()
+	}
+	// proveMember 成员 index 对给定的 Action 和 Message 生成证明, 返回证明产物和 nullifier
+	proveMember := func(index int, action, message string) (*prover.Artifact, string) {
+		path, _, err := gvcircuits.MerkleProof(curve, members, memberTreeDepth, index)
+		require.NoError(t, err)
+		data, err := json.Marshal(map[string]any{
+			"secret":  3000 + index,
+			"action":  action,
+			"message": message,
+			"index":   index,
+			"path":    path,
+		})
+		require.NoError(t, err)
+		_, assignment, err := definition.Decode(curve, data)
+		require.NoError(t, err)
+		artifact, err := p.ProveAssignment(assignment)
+		require.NoError(t, err)
+		return artifact, assignment.(*gvcircuits.Member).Nullifier.(*big.Int).String()
+	}
+	// prove 成员 index 对 action 和 args 生成匿名授权凭据
+	prove := func(index int, action string, args string) string {
+		inputs, err := auth.GetAuthInputs(clientContext, "Org1MSP", action, args)
+		require.NoError(t, err)
+		artifact, nullifier := proveMember(index, inputs.Action, inputs.Message)
+		authJSON, err := json.Marshal(AnonymousAuth{Root: inputs.Root, Nullifier: nullifier, Proof: artifact.Proof})
+		require.NoError(t, err)
+		return string(authJSON)
+	}
+
+	sample, _ := proveMember(0, "1", "1")
+	require.NoError(t, gnarkVerify.RegisterVerifyingKey(adminContext, "unnamed", "groth16", "BN254", sample.VK, ""))
+	require.ErrorContains(t, auth.SetMemberRoot(adminContext, "unnamed", memberRoot()), "member schema")
+	require.NoError(t, gnarkVerify.RegisterVerifyingKey(adminContext, "member", "groth16", "BN254", sample.VK, schemaConfig(t, memberSchema)))
+
+	require.ErrorContains(t, auth.SetMemberRoot(clientContext, "member", memberRoot()), "only admins")
+	require.NoError(t, auth.SetMemberRoot(adminContext, "member", memberRoot()))
+	group, err := auth.GetMemberGroup(clientContext, "Org1MSP")
+	require.NoError(t, err)
+	require.Equal(t, memberRoot(), group.Root)
+	_, err = auth.GetMemberGroup(clientContext, "Org2MSP")
+	require.ErrorContains(t, err, "no member group")
+
+	// 成员 1 匿名执行操作, 参数绑定到证明中
+	args := `["vk-old"]`
+	authJSON := prove(1, "revoke", args)
+	_, err = auth.Authorize(clientContext, "Org1MSP", "revoke", `["vk-new"]`, authJSON)
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = auth.Authorize(clientContext, "Org1MSP", "rotate", args, authJSON)
+	require.ErrorIs(t, err, ErrProofInvalid)
+	chaincodeStub.GetTxIDReturns("auth1")
+	authorization, err := auth.Authorize(clientContext, "Org1MSP", "revoke", args, authJSON)
+	require.NoError(t, err)
+	require.Equal(t, []string{"vk-old"}, authorization.Args)
+
+	// 重放或同一成员重新证明同一操作都被拒绝, 其他成员和其他操作不受影响
+	_, err = auth.Authorize(clientContext, "Org1MSP", "revoke", args, authJSON)
+	require.ErrorIs(t, err, ErrNullifierSpent)
+	_, err = auth.Authorize(clientContext, "Org1MSP", "revoke", args, prove(1, "revoke", args))
+	require.ErrorIs(t, err, ErrNullifierSpent)
+	chaincodeStub.GetTxIDReturns("auth2")
+	_, err = auth.Authorize(clientContext, "Org1MSP", "revoke", args, prove(2, "revoke", args))
+	require.NoError(t, err)
+	chaincodeStub.GetTxIDReturns("auth3")
+	_, err = auth.Authorize(clientContext, "Org1MSP", "rotate", args, prove(1, "rotate", args))
+	require.NoError(t, err)
+	authorizations, err := auth.GetAuthorizations(clientContext, "Org1MSP", "revoke")
+	require.NoError(t, err)
+	require.Len(t, authorizations, 2)
+	require.NotEqual(t, authorizations[0].Nullifier, authorizations[1].Nullifier)
+
+	// 管理员移除成员 0 后, 基于旧根的证明被拒绝
+	stale := prove(0, "pause", `[]`)
+	members[0] = new(big.Int)
+	require.NoError(t, auth.SetMemberRoot(adminContext, "member", memberRoot()))
+	_, err = auth.Authorize(clientContext, "Org1MSP", "pause", `[]`, stale)
+	require.ErrorIs(t, err, ErrBindingMismatch)
+	var forged AnonymousAuth
+	require.NoError(t, json.Unmarshal([]byte(stale), &forged))
+	forged.Root = memberRoot()
+	forgedJSON, err := json.Marshal(forged)
+	require.NoError(t, err)
+	_, err = auth.Authorize(clientContext, "Org1MSP", "pause", `[]`, string(forgedJSON))
+	require.ErrorIs(t, err, ErrProofInvalid)
+	chaincodeStub.GetTxIDReturns("auth4")
+	_, err = auth.Authorize(clientContext, "Org1MSP", "pause", `[]`, prove(2, "pause", `[]`))
+	require.NoError(t, err)
+
+	// 受守卫保护的方法: 发行方所有者组织的成员匿名吊销发行方, 证明绑定发行方 id
+	credentials := &CredentialContract{}
+	for _, issuer := range []*CredentialIssuer{{ID: "gov", Owner: "Org1MSP"}, {ID: "tax", Owner: "Org1MSP"}, {ID: "bank", Owner: "Org2MSP"}} {
+		require.NoError(t, putCredentialIssuer(adminContext, issuer))
+	}
+	revokeAuth := prove(2, revokeIssuerAction, `["gov"]`)
+	require.ErrorIs(t, credentials.RevokeIssuerAsMember(clientContext, "tax", revokeAuth), ErrProofInvalid)
+	require.ErrorContains(t, credentials.RevokeIssuerAsMember(clientContext, "bank", prove(2, revokeIssuerAction, `["bank"]`)), "Org2MSP has no member group")
+	require.ErrorIs(t, credentials.RevokeIssuerAsMember(clientContext, "gov", stale), ErrBindingMismatch)
+	chaincodeStub.GetTxIDReturns("auth5")
+	require.NoError(t, credentials.RevokeIssuerAsMember(clientContext, "gov", revokeAuth))
+	issuer, err := credentials.GetIssuer(clientContext, "gov")
+	require.NoError(t, err)
+	require.True(t, issuer.Revoked)
+	require.ErrorContains(t, credentials.RevokeIssuerAsMember(clientContext, "gov", prove(1, revokeIssuerAction, `["gov"]`)), "already revoked")
+}
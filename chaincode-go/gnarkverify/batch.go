@@ -0,0 +1,432 @@
+package gnarkverify
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxBatchSize 单笔交易允许批量验证的证明数量上限
+const maxBatchSize = 256
+
+// BatchItem 批量验证中的一组证明和公开见证, 字段名与证明生成产物一致
+type BatchItem struct {
+	Proof         string `json:"proof"`
+	WitnessPublic string `json:"witnessPublic"`
+}
+
+// BatchItemResult 批量验证中单个证明的结果
+type BatchItemResult struct {
+	Index     int    `json:"index"`
+	Valid     bool   `json:"valid"`
+	Reason    string `json:"reason,omitempty"`
+	ErrorCode string `json:"errorCode,omitempty"`
+	// RecordID 有效的证明的验证记录 ID, 可用 GetVerificationRecord 查询
+	RecordID string `json:"recordId,omitempty"`
+}
+
+func readBatchItems(itemsJSON string) ([]BatchItem, error) {
+	var items []BatchItem
+	if err := json.Unmarshal([]byte(itemsJSON), &items); err != nil {
+		return nil, newError(ErrBadEncoding, "failed to unmarshal batch items (%s): %v", describeInput([]byte(itemsJSON)), err)
+	}
+	if len(items) == 0 {
+		return nil, newError(ErrBadRequest, "batch must contain at least one item")
+	}
+	if len(items) > maxBatchSize {
+		return nil, newError(ErrInputTooLarge, "batch contains %d items, at most %d allowed", len(items), maxBatchSize)
+	}
+	return items, nil
+}
+
+// checkBatchSizes 在解码前检查验证密钥和每个证明的大小, 任一超限时整批拒绝
+func checkBatchSizes(ctx contractapi.TransactionContextInterface, protocol, curveName, vkStr string, items []BatchItem) error {
+	limits, err := readLimits(ctx)
+	if err != nil {
+		return err
+	}
+	if err := limits.checkInputs(protocol, curveName, vkStr, "", ""); err != nil {
+		return err
+	}
+	for i, item := range items {
+		if err := limits.checkInputs(protocol, curveName, "", item.Proof, item.WitnessPublic); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyParallel 并发验证每个证明, 结果按下标写回, 与调度顺序无关
+func verifyParallel(n int, verifyOne func(i int) error) []*BatchItemResult {
+	results := make([]*BatchItemResult, n)
+	workers := min(runtime.NumCPU(), n)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := &BatchItemResult{Index: i, Valid: true}
+				if err := verifyOne(i); err != nil {
+					result.Valid = false
+					result.Reason = err.Error()
+					result.ErrorCode = ErrorCode(err)
+				}
+				results[i] = result
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// batchSeed 由验证密钥和全部证明计算随机系数种子, 各背书节点结果一致
+func batchSeed(vkStr string, items []BatchItem) []byte {
+	h := sha256.New()
+	h.Write([]byte(vkStr))
+	for _, item := range items {
+		h.Write([]byte(item.Proof))
+		h.Write([]byte(item.WitnessPublic))
+	}
+	return h.Sum(nil)
+}
+
+func batchCoefficient(seed []byte, i int) fr_bn254.Element {
+	var index [4]byte
+	binary.BigEndian.PutUint32(index[:], uint32(i))
+	h := sha256.Sum256(append(append([]byte{}, seed...), index[:]...))
+	var r fr_bn254.Element
+	r.SetBytes(h[:16])
+	if r.IsZero() {
+		r.SetOne()
+	}
+	return r
+}
+
+// batchVerifyGroth16BN254 用随机线性组合将 n 个 BN254 Groth16 验证合并为一次配对检查:
+// Π e(rⱼAⱼ, Bⱼ) · e(ΣrⱼCⱼ, -δ) · e(ΣrⱼLⱼ, -γ) · e(-(Σrⱼ)α, β) = 1
+// supported 为 false 时 (其他曲线或带承诺的电路) 由调用方逐个验证
+func batchVerifyGroth16BN254(vk groth16.VerifyingKey, proofs []groth16.Proof, witnesses []witness.Witness, seed []byte) (valid bool, supported bool) {
+	bnVK, ok := vk.(*groth16_bn254.VerifyingKey)
+	if !ok || len(bnVK.CommitmentKeys) > 0 {
+		return false, false
+	}
+	nbPublic := len(bnVK.G1.K) - 1
+	ps := make([]bn254.G1Affine, 0, len(proofs)+3)
+	qs := make([]bn254.G2Affine, 0, len(proofs)+3)
+	coefficients := make(fr_bn254.Vector, nbPublic)
+	var rSum fr_bn254.Element
+	var cSum bn254.G1Jac
+	for j := range proofs {
+		proof, ok := proofs[j].(*groth16_bn254.Proof)
+		if !ok || len(proof.Commitments) > 0 {
+			return false, false
+		}
+		publicWitness, ok := witnesses[j].Vector().(fr_bn254.Vector)
+		if !ok || len(publicWitness) != nbPublic {
+			return false, true
+		}
+		if !proof.Ar.IsInSubGroup() || !proof.Krs.IsInSubGroup() || !proof.Bs.IsInSubGroup() {
+			return false, true
+		}
+
+		r := batchCoefficient(seed, j)
+		rBig := r.BigInt(new(big.Int))
+		var rA bn254.G1Affine
+		rA.ScalarMultiplication(&proof.Ar, rBig)
+		ps = append(ps, rA)
+		qs = append(qs, proof.Bs)
+
+		var rC bn254.G1Jac
+		rC.FromAffine(&proof.Krs)
+		rC.ScalarMultiplication(&rC, rBig)
+		cSum.AddAssign(&rC)
+
+		for i := range publicWitness {
+			var term fr_bn254.Element
+			term.Mul(&r, &publicWitness[i])
+			coefficients[i].Add(&coefficients[i], &term)
+		}
+		rSum.Add(&rSum, &r)
+	}
+
+	// ΣrⱼLⱼ = (Σrⱼ)K₀ + Σᵢ(Σⱼ rⱼxⱼᵢ)Kᵢ₊₁, 只需一次多标量乘法
+	rSumBig := rSum.BigInt(new(big.Int))
+	var lSum bn254.G1Jac
+	lSum.FromAffine(&bnVK.G1.K[0])
+	lSum.ScalarMultiplication(&lSum, rSumBig)
+	if nbPublic > 0 {
+		var kSum bn254.G1Jac
+		if _, err := kSum.MultiExp(bnVK.G1.K[1:], coefficients, ecc.MultiExpConfig{}); err != nil {
+			return false, false
+		}
+		lSum.AddAssign(&kSum)
+	}
+
+	var cSumAff, lSumAff, alpha bn254.G1Affine
+	cSumAff.FromJacobian(&cSum)
+	lSumAff.FromJacobian(&lSum)
+	alpha.ScalarMultiplication(&bnVK.G1.Alpha, rSumBig)
+	alpha.Neg(&alpha)
+	var deltaNeg, gammaNeg bn254.G2Affine
+	deltaNeg.Neg(&bnVK.G2.Delta)
+	gammaNeg.Neg(&bnVK.G2.Gamma)
+	ps = append(ps, cSumAff, lSumAff, alpha)
+	qs = append(qs, deltaNeg, gammaNeg, bnVK.G2.Beta)
+
+	valid, err := bn254.PairingCheck(ps, qs)
+	return err == nil && valid, true
+}
+
+// verifyGroth16Items 验证每个 Groth16 证明, 返回结果和有效证明的公开见证.
+// BN254 上先做随机线性组合的批量配对检查, 失败或不适用时并发逐个验证
+func verifyGroth16Items(vkStr string, curve ecc.ID, items []BatchItem) ([]*BatchItemResult, []witness.Witness, error) {
+	vk, err := readGroth16VK(vkStr, curve)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proofs := make([]groth16.Proof, len(items))
+	witnesses := make([]witness.Witness, len(items))
+	decodeErrs := make([]error, len(items))
+	decoded := verifyParallel(len(items), func(i int) error {
+		proofs[i], decodeErrs[i] = readGroth16Proof(items[i].Proof, curve)
+		if decodeErrs[i] != nil {
+			return decodeErrs[i]
+		}
+		witnesses[i], decodeErrs[i] = readPublicWitness(items[i].WitnessPublic, curve)
+		if decodeErrs[i] != nil {
+			return decodeErrs[i]
+		}
+		decodeErrs[i] = checkWitnessSize(vk, witnesses[i])
+		return decodeErrs[i]
+	})
+	allDecoded := true
+	for _, result := range decoded {
+		allDecoded = allDecoded && result.Valid
+	}
+	if allDecoded {
+		if valid, supported := batchVerifyGroth16BN254(vk, proofs, witnesses, batchSeed(vkStr, items)); supported && valid {
+			return decoded, witnesses, nil
+		}
+	}
+
+	return verifyParallel(len(items), func(i int) error {
+		if decodeErrs[i] != nil {
+			return decodeErrs[i]
+		}
+		if err := groth16.Verify(proofs[i], vk, witnesses[i]); err != nil {
+			return newError(ErrProofInvalid, "failed to verify proof: %v", err)
+		}
+		return nil
+	}), witnesses, nil
+}
+
+// verifyPlonkItems 并发逐个验证每个 PLONK 证明, 返回结果和有效证明的公开见证
+func verifyPlonkItems(vkStr string, curve ecc.ID, items []BatchItem) ([]*BatchItemResult, []witness.Witness, error) {
+	vk, err := readPlonkVK(vkStr, curve)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	witnesses := make([]witness.Witness, len(items))
+	return verifyParallel(len(items), func(i int) error {
+		proof, err := readPlonkProof(items[i].Proof, curve)
+		if err != nil {
+			return err
+		}
+		publicWitness, err := readPublicWitness(items[i].WitnessPublic, curve)
+		if err != nil {
+			return err
+		}
+		if err := checkWitnessSize(vk, publicWitness); err != nil {
+			return err
+		}
+		if err := plonk.Verify(proof, vk, publicWitness); err != nil {
+			return newError(ErrProofInvalid, "failed to verify proof: %v", err)
+		}
+		witnesses[i] = publicWitness
+		return nil
+	}), witnesses, nil
+}
+
+// verifyBatch 使用同一验证密钥批量验证证明, 为有效的证明写入验证记录并发出 BatchVerified 事件
+func verifyBatch(ctx contractapi.TransactionContextInterface, vkRecord *VerifyingKeyRecord, itemsJSON string) ([]*BatchItemResult, error) {
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return nil, err
+	}
+	items, err := readBatchItems(itemsJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBatchSizes(ctx, vkRecord.Protocol, vkRecord.Curve, vkRecord.VK, items); err != nil {
+		return nil, err
+	}
+
+	var (
+		results   []*BatchItemResult
+		witnesses []witness.Witness
+	)
+	switch vkRecord.Protocol {
+	case protocolGroth16:
+		results, witnesses, err = verifyGroth16Items(vkRecord.VK, curve, items)
+	case protocolPlonk:
+		results, witnesses, err = verifyPlonkItems(vkRecord.VK, curve, items)
+	default:
+		err = newError(ErrUnknownProtocol, "unknown protocol %.64q", vkRecord.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordBatch(ctx, vkRecord, items, witnesses, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// rejectBatchItem 将有效的证明改为无效, 用于验证通过但未通过密钥配置的检查的证明
+func rejectBatchItem(result *BatchItemResult, err error) {
+	result.Valid = false
+	result.Reason = err.Error()
+	result.ErrorCode = ErrorCode(err)
+}
+
+// recordBatch 按下标顺序为有效的证明写入验证记录, 记录 ID 为 <txID>#<index>.
+// 密钥配置的 schema、绑定和 nullifier 逐个检查, 未通过时该证明改为无效;
+// 同一批次中重复的 nullifier 只有第一个有效, 交易内读不到本交易的写入
+func recordBatch(ctx contractapi.TransactionContextInterface, vkRecord *VerifyingKeyRecord, items []BatchItem, witnesses []witness.Witness, results []*BatchItemResult) error {
+	spent := map[string]bool{}
+	indexed := map[string]bool{}
+	events := []ProofVerifiedEvent{}
+	for i, result := range results {
+		if !result.Valid {
+			continue
+		}
+		record, err := newVerificationRecord(ctx, &verification{
+			vkID:          vkRecord.ID,
+			protocol:      vkRecord.Protocol,
+			curveName:     vkRecord.Curve,
+			vkHash:        vkRecord.VKHash,
+			proofStr:      items[i].Proof,
+			publicWitness: witnesses[i],
+		})
+		if err != nil {
+			return err
+		}
+		record.RecordID = fmt.Sprintf("%s#%d", record.TxID, result.Index)
+		if vkRecord.Config.Schema != nil {
+			if record.Inputs, err = decodeInputs(vkRecord.Config.Schema, record.PublicInputs); err != nil {
+				rejectBatchItem(result, err)
+				continue
+			}
+		}
+		if err := checkBindings(ctx, vkRecord, record.PublicInputs); err != nil {
+			rejectBatchItem(result, err)
+			continue
+		}
+		if config := vkRecord.Config.Nullifier; config != nil && config.Index < len(record.PublicInputs) {
+			nullifier := record.PublicInputs[config.Index]
+			if spent[nullifier] {
+				rejectBatchItem(result, newError(ErrNullifierSpent, "nullifier %s already spent", nullifier))
+				continue
+			}
+			spent[nullifier] = true
+		}
+		if err := spendConfiguredNullifier(ctx, vkRecord, record.PublicInputs); err != nil {
+			rejectBatchItem(result, err)
+			continue
+		}
+
+		if err := putRecord(ctx, record); err != nil {
+			return err
+		}
+		if !indexed[record.ProofHash] {
+			if err := indexProofHash(ctx, record); err != nil {
+				return err
+			}
+			indexed[record.ProofHash] = true
+		}
+		result.RecordID = record.ID()
+		events = append(events, proofVerifiedEvent(record))
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return emitBatchVerified(ctx, events)
+}
+
+// VerifyGroth16Batch 使用同一验证密钥批量验证 Groth16 证明, 返回每个证明的结果, 有效的证明写入验证记录.
+// 只有 BN254 上不带承诺的电路做随机线性组合的批量配对检查, 其他曲线和检查失败时并发逐个验证
+func (c *GnarkVerifyContract) VerifyGroth16Batch(ctx contractapi.TransactionContextInterface, curveName string, vkStr string, itemsJSON string) ([]*BatchItemResult, error) {
+	vkRecord, err := inlineBatchKey(ctx, protocolGroth16, curveName, vkStr)
+	if err != nil {
+		return nil, err
+	}
+	return verifyBatch(ctx, vkRecord, itemsJSON)
+}
+
+// VerifyPlonkBatch 使用同一验证密钥并发逐个验证 PLONK 证明, 返回每个证明的结果, 有效的证明写入验证记录.
+// PLONK 证明不做批量检查
+func (c *GnarkVerifyContract) VerifyPlonkBatch(ctx contractapi.TransactionContextInterface, curveName string, vkStr string, itemsJSON string) ([]*BatchItemResult, error) {
+	vkRecord, err := inlineBatchKey(ctx, protocolPlonk, curveName, vkStr)
+	if err != nil {
+		return nil, err
+	}
+	return verifyBatch(ctx, vkRecord, itemsJSON)
+}
+
+// inlineBatchKey 直接携带的验证密钥视为未登记的临时密钥, 已登记并配置了检查的密钥须按 ID 批量验证
+func inlineBatchKey(ctx contractapi.TransactionContextInterface, protocol string, curveName string, vkStr string) (*VerifyingKeyRecord, error) {
+	curveName, _, err := parseCurve(curveName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInputSizes(ctx, protocol, curveName, vkStr, "", ""); err != nil {
+		return nil, err
+	}
+	vkHash, nbPublic, err := checkVerifyingKey(protocol, curveName, vkStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInlineVerifyingKey(ctx, vkHash, nbPublic); err != nil {
+		return nil, err
+	}
+	return &VerifyingKeyRecord{
+		Protocol: protocol,
+		Curve:    curveName,
+		VK:       vkStr,
+		VKHash:   vkHash,
+	}, nil
+}
+
+// VerifyBatchByKeyID 使用已登记的验证密钥批量验证证明, 每个有效的证明按密钥配置检查绑定并消费 nullifier.
+// 配置了私有数据集合的密钥不接受批量验证, 公开输入会随交易参数写入区块
+func (c *GnarkVerifyContract) VerifyBatchByKeyID(ctx contractapi.TransactionContextInterface, id string, itemsJSON string) ([]*BatchItemResult, error) {
+	vkRecord, err := readVerifyingKeyRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vkRecord.Config.PrivateCollection != "" {
+		return nil, newError(ErrInputsNotPrivate, "verifying key %s keeps public inputs in private collection %s, submit them through VerifyProofTransient", vkRecord.ID, vkRecord.Config.PrivateCollection)
+	}
+	return verifyBatch(ctx, vkRecord, itemsJSON)
+}
@@ -0,0 +1,177 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/oliverustc/gnarkabc/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// productBatch 在同一次 setup 下生成 n 个证明
+func productBatch(t *testing.T, protocol string, curveName string, n int) (string, []BatchItem) {
+	pqs := make([][2]int, n)
+	for i := range pqs {
+		pqs[i] = [2]int{i + 2, i + 3}
+	}
+	artifacts := proveProduct(t, protocol, curveName, pqs...)
+	items := make([]BatchItem, n)
+	for i, artifact := range artifacts {
+		items[i] = BatchItem{Proof: artifact.Proof, WitnessPublic: artifact.WitnessPublic}
+	}
+	return artifacts[0].VK, items
+}
+
+func groth16BatchParams(t *testing.T, curveName string, n int) (string, []BatchItem) {
+	return productBatch(t, prover.ProtocolGroth16, curveName, n)
+}
+
+func plonkBatchParams(t *testing.T, curveName string, n int) (string, []BatchItem) {
+	return productBatch(t, prover.ProtocolPlonk, curveName, n)
+}
+
+func marshalBatchItems(t *testing.T, items []BatchItem) string {
+	itemsJSON, err := json.Marshal(items)
+	require.NoError(t, err)
+	return string(itemsJSON)
+}
+
+func TestBatchVerifyGroth16BN254(t *testing.T) {
+	vkStr, items := groth16BatchParams(t, "BN254", 4)
+	curve := utils.CurveMap["BN254"]
+	vk, err := readGroth16VK(vkStr, curve)
+	require.NoError(t, err)
+	proofs := make([]groth16.Proof, len(items))
+	witnesses := make([]witness.Witness, len(items))
+	for i, item := range items {
+		proofs[i], err = readGroth16Proof(item.Proof, curve)
+		require.NoError(t, err)
+		witnesses[i], err = readPublicWitness(item.WitnessPublic, curve)
+		require.NoError(t, err)
+	}
+
+	valid, supported := batchVerifyGroth16BN254(vk, proofs, witnesses, batchSeed(vkStr, items))
+	require.True(t, supported)
+	require.True(t, valid)
+
+	// 交换两个公开见证后批量检查失败
+	witnesses[0], witnesses[1] = witnesses[1], witnesses[0]
+	valid, supported = batchVerifyGroth16BN254(vk, proofs, witnesses, batchSeed(vkStr, items))
+	require.True(t, supported)
+	require.False(t, valid)
+}
+
+func TestVerifyGroth16Batch(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	for _, curveName := range []string{"BN254", "BLS12-381"} {
+		vkStr, items := groth16BatchParams(t, curveName, 4)
+		results, err := gnarkVerify.VerifyGroth16Batch(transactionContext, curveName, vkStr, marshalBatchItems(t, items))
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+		for i, result := range results {
+			require.Equal(t, i, result.Index)
+			require.True(t, result.Valid, result.Reason)
+		}
+
+		// 单个证明无效或无法解析时只影响对应结果
+		items[1].WitnessPublic = items[2].WitnessPublic
+		items[3].Proof = "not base64"
+		chaincodeStub.GetTxIDReturns("batch-" + curveName)
+		results, err = gnarkVerify.VerifyGroth16Batch(transactionContext, curveName, vkStr, marshalBatchItems(t, items))
+		require.NoError(t, err)
+		require.True(t, results[0].Valid)
+		require.False(t, results[1].Valid)
+		require.True(t, results[2].Valid)
+		require.False(t, results[3].Valid)
+		require.NotEmpty(t, results[3].Reason)
+		require.Equal(t, "PROOF_INVALID", results[1].ErrorCode)
+		require.Equal(t, "BAD_ENCODING", results[3].ErrorCode)
+
+		// 有效的证明各自写入验证记录, 无效的不写入
+		require.Equal(t, "batch-"+curveName+"#2", results[2].RecordID)
+		require.Empty(t, results[1].RecordID)
+		record, err := gnarkVerify.GetVerificationRecord(transactionContext, results[2].RecordID)
+		require.NoError(t, err)
+		require.Equal(t, "batch-"+curveName, record.TxID)
+		require.Equal(t, curveName, record.Curve)
+		require.Equal(t, []string{"20"}, record.PublicInputs)
+		_, err = gnarkVerify.GetVerificationRecord(transactionContext, "batch-"+curveName+"#1")
+		require.ErrorContains(t, err, "does not exist")
+	}
+
+	_, err := gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", groth16Params(t, "BN254").VK, "[]")
+	require.ErrorContains(t, err, "at least one item")
+}
+
+func TestVerifyPlonkBatch(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	vkStr, items := plonkBatchParams(t, "BN254", 3)
+	items[2].WitnessPublic = items[0].WitnessPublic
+	results, err := gnarkVerify.VerifyPlonkBatch(transactionContext, "BN254", vkStr, marshalBatchItems(t, items))
+	require.NoError(t, err)
+	require.True(t, results[0].Valid)
+	require.True(t, results[1].Valid)
+	require.False(t, results[2].Valid)
+	require.Equal(t, "tx0#1", results[1].RecordID)
+}
+
+func TestVerifyBatchByKeyID(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	// 第 0 和第 1 个证明的公开输入 (nullifier) 相同
+	artifacts := proveProduct(t, prover.ProtocolGroth16, "BN254", [2]int{2, 3}, [2]int{3, 2}, [2]int{1, 7})
+	items := make([]BatchItem, len(artifacts))
+	for i, artifact := range artifacts {
+		items[i] = BatchItem{Proof: artifact.Proof, WitnessPublic: artifact.WitnessPublic}
+	}
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", artifacts[0].VK, `{"nullifier":{"index":0}}`))
+	_, err := gnarkVerify.VerifyBatchByKeyID(transactionContext, "missing", marshalBatchItems(t, items))
+	require.ErrorContains(t, err, "does not exist")
+
+	chaincodeStub.GetTxIDReturns("batch1")
+	results, err := gnarkVerify.VerifyBatchByKeyID(transactionContext, "product", marshalBatchItems(t, items))
+	require.NoError(t, err)
+	require.True(t, results[0].Valid)
+	require.False(t, results[1].Valid)
+	require.Equal(t, "NULLIFIER_SPENT", results[1].ErrorCode)
+	require.True(t, results[2].Valid)
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, results[2].RecordID)
+	require.NoError(t, err)
+	require.Equal(t, "product", record.VKID)
+	require.Equal(t, "batch1#2", record.RecordID)
+	spent, err := gnarkVerify.IsNullifierSpent(transactionContext, "product", "7")
+	require.NoError(t, err)
+	require.True(t, spent)
+
+	// 有效证明的记录合并在一个事件中
+	require.Equal(t, 1, chaincodeStub.SetEventCallCount())
+	name, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.Equal(t, BatchVerifiedEventName, name)
+	var event BatchVerifiedEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Equal(t, "batch1", event.TxID)
+	require.Len(t, event.Items, 2)
+	require.Equal(t, []string{"batch1#0", "batch1#2"}, []string{event.Items[0].RecordID, event.Items[1].RecordID})
+
+	// 之后的交易重放已消费 nullifier 的证明
+	chaincodeStub.GetTxIDReturns("batch2")
+	results, err = gnarkVerify.VerifyBatchByKeyID(transactionContext, "product", marshalBatchItems(t, items[2:]))
+	require.NoError(t, err)
+	require.False(t, results[0].Valid)
+	require.Equal(t, "NULLIFIER_SPENT", results[0].ErrorCode)
+	require.Equal(t, 1, chaincodeStub.SetEventCallCount())
+
+	// 配置了私有数据集合的密钥不接受批量验证
+	other := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "private", "groth16", "BN254", other.VK, `{"privateCollection":"gnarkverifyInputs"}`))
+	_, err = gnarkVerify.VerifyBatchByKeyID(transactionContext, "private", marshalBatchItems(t, []BatchItem{{Proof: other.Proof, WitnessPublic: other.WitnessPublic}}))
+	require.ErrorIs(t, err, ErrInputsNotPrivate)
+}
@@ -0,0 +1,148 @@
+package gnarkverify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// 公开输入绑定的类型
+const (
+	BindState      = "state"
+	BindEpoch      = "epoch"
+	BindMerkleRoot = "merkleRoot"
+)
+
+// InputBinding 要求公开输入等于验证时的账本值. Input 为 schema 中的名称或公开输入下标;
+// state 绑定比较世界状态中 Key 的值 (十进制或 0x 前缀十六进制文本), 指定 Attributes 时
+// Key 为对象类型, 状态键由 CreateCompositeKey 组合; epoch 绑定比较交易时间戳除以 Period 秒的商;
+// merkleRoot 绑定要求公开输入为 Key 指定的 Merkle 树最近 historySize 个根之一
+type InputBinding struct {
+	Input      string   `json:"input"`
+	Kind       string   `json:"kind"`
+	Key        string   `json:"key,omitempty"`
+	Attributes []string `json:"attributes,omitempty"`
+	Period     int64    `json:"period,omitempty"`
+}
+
+// schemaIndex 将名称或下标解析为公开输入下标
+func schemaIndex(record *VerifyingKeyRecord, schema []InputSchema, input string) (int, error) {
+	if index, err := strconv.Atoi(input); err == nil {
+		if index < 0 || index >= record.NbPublic {
+			return 0, fmt.Errorf("public input index %d out of range [0, %d)", index, record.NbPublic)
+		}
+		return index, nil
+	}
+	for i, s := range schema {
+		if s.Name == input {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("public input %q is not in the schema of verifying key %s", input, record.ID)
+}
+
+// checkBindingsConfig 检查绑定引用的公开输入存在且参数完整
+func checkBindingsConfig(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, config *VerifyingKeyConfig) error {
+	bound := map[int]bool{}
+	for _, binding := range config.Bindings {
+		index, err := schemaIndex(record, config.Schema, binding.Input)
+		if err != nil {
+			return err
+		}
+		if bound[index] {
+			return fmt.Errorf("public input %s is bound more than once", binding.Input)
+		}
+		bound[index] = true
+		switch binding.Kind {
+		case BindState:
+			if binding.Key == "" {
+				return fmt.Errorf("state binding of %s must set key", binding.Input)
+			}
+		case BindEpoch:
+			if binding.Period <= 0 {
+				return fmt.Errorf("epoch binding of %s must set a positive period", binding.Input)
+			}
+		case BindMerkleRoot:
+			tree, err := readMerkleTree(ctx, binding.Key)
+			if err != nil {
+				return err
+			}
+			if tree.Curve != record.Curve {
+				return fmt.Errorf("merkle tree %s is on curve %s, verifying key %s is on %s", tree.ID, tree.Curve, record.ID, record.Curve)
+			}
+		default:
+			return fmt.Errorf("unknown binding kind %q of public input %s", binding.Kind, binding.Input)
+		}
+	}
+	return nil
+}
+
+// matchBinding 判断十进制的公开输入是否满足绑定
+func matchBinding(ctx contractapi.TransactionContextInterface, binding InputBinding, input string) (bool, error) {
+	if binding.Kind == BindMerkleRoot {
+		return isKnownRoot(ctx, binding.Key, input)
+	}
+	expected, err := resolveBinding(ctx, binding)
+	if err != nil {
+		return false, err
+	}
+	return input == expected, nil
+}
+
+// resolveBinding 读取绑定在本次交易中的期望值, 十进制字符串
+func resolveBinding(ctx contractapi.TransactionContextInterface, binding InputBinding) (string, error) {
+	switch binding.Kind {
+	case BindState:
+		key := binding.Key
+		if len(binding.Attributes) > 0 {
+			var err error
+			if key, err = ctx.GetStub().CreateCompositeKey(binding.Key, binding.Attributes); err != nil {
+				return "", err
+			}
+		}
+		data, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read state %q: %v", key, err)
+		}
+		if data == nil {
+			return "", newError(ErrBindingMismatch, "public input %s is bound to state %q, which is not set", binding.Input, key)
+		}
+		value, err := normalizeFieldElement(strings.TrimSpace(string(data)))
+		if err != nil {
+			return "", fmt.Errorf("state %q bound to public input %s: %v", key, binding.Input, err)
+		}
+		return value, nil
+	case BindEpoch:
+		ts, err := txTime(ctx)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(ts.Unix()/binding.Period, 10), nil
+	default:
+		return "", fmt.Errorf("unknown binding kind %q", binding.Kind)
+	}
+}
+
+// checkBindings 逐个比较绑定的公开输入与账本值, 不相等时拒绝
+func checkBindings(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, inputs []string) error {
+	for _, binding := range record.Config.Bindings {
+		index, err := schemaIndex(record, record.Config.Schema, binding.Input)
+		if err != nil {
+			return err
+		}
+		if index >= len(inputs) {
+			return newError(ErrWitnessMismatch, "public witness has %d inputs, binding of %s needs index %d", len(inputs), binding.Input, index)
+		}
+		matched, err := matchBinding(ctx, binding, inputs[index])
+		if err != nil {
+			return err
+		}
+		// 错误信息中不输出公开输入, transient 提交时公开输入不应出现在返回结果中
+		if !matched {
+			return newError(ErrBindingMismatch, "public input %s does not match its %s binding", binding.Input, binding.Kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package gnarkverify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestVerifyingKeyBindings(t *testing.T) {
+	transactionContext, chaincodeStub, ledger := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	artifact := proveRegistered(t, "groth16", "range", "")
+	require.NoError(t, registerVerifyingKey(transactionContext, "range", "groth16", "BN254", artifact.VK, ""))
+
+	for _, invalid := range []string{
+		`{"bindings":[{"input":"lower","kind":"state","key":"policy"}]}`,
+		`{"bindings":[{"input":"2","kind":"state","key":"policy"}]}`,
+		`{"bindings":[{"input":"0","kind":"state"}]}`,
+		`{"bindings":[{"input":"0","kind":"epoch"}]}`,
+		`{"bindings":[{"input":"0","kind":"balance"}]}`,
+		`{"bindings":[{"input":"0","kind":"epoch","period":60},{"input":"0","kind":"state","key":"policy"}]}`,
+	} {
+		require.Error(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", invalid), invalid)
+	}
+	// lower 绑定到组合键 policy~range 的值, 下标 1 (upper) 绑定到以小时计的时间段
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", `{
+		"schema":[{"name":"lower","type":"uint64"},{"name":"upper","type":"uint64"}],
+		"bindings":[
+			{"input":"lower","kind":"state","key":"policy","attributes":["range"]},
+			{"input":"1","kind":"epoch","period":3600}
+		]}`))
+	policyKey, err := shim.CreateCompositeKey("policy", []string{"range"})
+	require.NoError(t, err)
+	inEpoch := timestamppb.New(time.Unix(65*3600+1800, 0))
+	chaincodeStub.GetTxTimestampReturns(inEpoch, nil)
+
+	verify := func(txID string) (*VerifyResponse, error) {
+		chaincodeStub.GetTxIDReturns(txID)
+		return gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+			VKID:          "range",
+			Proof:         artifact.Proof,
+			WitnessPublic: artifact.WitnessPublic,
+		}))
+	}
+
+	// 状态键不存在或值不相等时返回 valid=false
+	response, err := verify("tx1")
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "BINDING_MISMATCH", response.ErrorCode)
+	ledger.state[policyKey] = []byte("17")
+	response, err = verify("tx1")
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "BINDING_MISMATCH", response.ErrorCode)
+	require.NotContains(t, response.Reason, "17")
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.Error(t, err)
+
+	ledger.state[policyKey] = []byte("0x12\n")
+	response, err = verify("tx2")
+	require.NoError(t, err)
+	require.True(t, response.Valid, response.Reason)
+	require.Equal(t, map[string]string{"lower": "18", "upper": "65"}, response.Inputs)
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Unix(66*3600, 0)), nil)
+	response, err = verify("tx3")
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Contains(t, response.Reason, "epoch")
+
+	// 状态值不是整数时请求失败
+	chaincodeStub.GetTxTimestampReturns(inEpoch, nil)
+	ledger.state[policyKey] = []byte("eighteen")
+	_, err = verify("tx4")
+	require.ErrorContains(t, err, "invalid field element")
+}
@@ -0,0 +1,137 @@
+package gnarkverify
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// defaultVKCacheMB 验证密钥缓存默认上限, 可通过环境变量 GNARKVERIFY_VK_CACHE_MB 调整
+	defaultVKCacheMB = 64
+	// vkMemoryFactor 解析后的密钥 (非压缩点及预计算的配对值) 相对编码长度的估计倍数
+	vkMemoryFactor = 4
+)
+
+// vkCache 进程内共享的已解析验证密钥缓存, 同一链码进程的所有交易共用
+var vkCache = newLRUCache(vkCacheBytes())
+
+func vkCacheBytes() int {
+	mb := defaultVKCacheMB
+	if value, err := strconv.Atoi(os.Getenv("GNARKVERIFY_VK_CACHE_MB")); err == nil && value >= 0 {
+		mb = value
+	}
+	return mb << 20
+}
+
+type lruEntry struct {
+	key   string
+	value any
+	cost  int
+}
+
+// lruCache 按估计内存占用限制总量的 LRU 缓存, 并发安全
+type lruCache struct {
+	mu       sync.Mutex
+	maxCost  int
+	cost     int
+	entries  map[string]*list.Element
+	eviction *list.List
+}
+
+func newLRUCache(maxCost int) *lruCache {
+	return &lruCache{
+		maxCost:  maxCost,
+		entries:  map[string]*list.Element{},
+		eviction: list.New(),
+	}
+}
+
+func (c *lruCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.eviction.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+// add 插入缓存项, 单项超过上限时不缓存
+func (c *lruCache) add(key string, value any, cost int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cost > c.maxCost {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		c.eviction.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.eviction.PushFront(&lruEntry{key: key, value: value, cost: cost})
+	c.cost += cost
+	for c.cost > c.maxCost {
+		oldest := c.eviction.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.eviction.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.cost -= entry.cost
+	}
+}
+
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.eviction.Len()
+}
+
+// cachedVK 以协议、曲线和密钥内容哈希查找已解析的验证密钥, 未命中时调用 read 解析并缓存
+func cachedVK[T any](protocol string, curve ecc.ID, vkBytes []byte, read func() (T, error)) (T, error) {
+	key := protocol + "/" + curve.String() + "/" + hashHex(vkBytes)
+	if vk, ok := vkCache.get(key); ok {
+		return vk.(T), nil
+	}
+	vk, err := read()
+	if err != nil {
+		return vk, err
+	}
+	vkCache.add(key, vk, len(vkBytes)*vkMemoryFactor)
+	return vk, nil
+}
+
+// WarmVerifyingKeyCache 将已登记的验证密钥解析后放入本节点缓存, 返回加载的数量.
+// 预热需要手动触发: 链码进程启动时没有账本上下文, 而在第一笔交易中预热会把全部密钥的范围查询计入
+// 该交易的读集, 与并发的密钥登记冲突时交易失效, 解析开销也会落在该交易的客户端上.
+// 链码进程 (重新) 启动后应在每个背书节点上以查询方式调用一次 (./run.sh warmup), 不要作为交易提交;
+// 未预热时第一次使用某个密钥的交易负责解析并缓存它
+func (c *GnarkVerifyContract) WarmVerifyingKeyCache(ctx contractapi.TransactionContextInterface) (int, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(vkObjectType, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list verifying keys: %v", err)
+	}
+	defer iterator.Close()
+
+	loaded := 0
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return loaded, fmt.Errorf("failed to list verifying keys: %v", err)
+		}
+		var record VerifyingKeyRecord
+		if err := json.Unmarshal(kv.Value, &record); err != nil {
+			return loaded, fmt.Errorf("failed to unmarshal verifying key: %v", err)
+		}
+		if _, _, err := checkVerifyingKey(record.Protocol, record.Curve, record.VK); err != nil {
+			return loaded, fmt.Errorf("failed to load verifying key %s: %v", record.ID, err)
+		}
+		loaded++
+	}
+	return loaded, nil
+}
@@ -0,0 +1,64 @@
+package gnarkverify
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(10)
+	cache.add("a", 1, 4)
+	cache.add("b", 2, 4)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	// 超出上限时淘汰最久未使用的 b
+	cache.add("c", 3, 4)
+	_, ok = cache.get("b")
+	require.False(t, ok)
+	value, ok := cache.get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+	require.Equal(t, 2, cache.len())
+
+	// 单项超过上限时不缓存
+	cache.add("d", 4, 11)
+	_, ok = cache.get("d")
+	require.False(t, ok)
+	require.Equal(t, 2, cache.len())
+}
+
+func TestVerifyingKeyCache(t *testing.T) {
+	defer func(cache *lruCache) { vkCache = cache }(vkCache)
+	vkCache = newLRUCache(defaultVKCacheMB << 20)
+
+	params := groth16Params(t, "BW6-761")
+	vk, err := readGroth16VK(params.VK, ecc.BW6_761)
+	require.NoError(t, err)
+	cached, err := readGroth16VK(params.VK, ecc.BW6_761)
+	require.NoError(t, err)
+	require.Same(t, vk, cached)
+
+	// 同一内容按不同协议解析不会命中缓存
+	_, err = readPlonkVK(params.VK, ecc.BW6_761)
+	require.Error(t, err)
+	require.Equal(t, 1, vkCache.len())
+
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	plonkProof := plonkParams(t, "BLS24-317")
+	require.NoError(t, registerVerifyingKey(transactionContext, "groth16-product", "groth16", "BW6-761", params.VK, ""))
+	require.NoError(t, registerVerifyingKey(transactionContext, "plonk-product", "plonk", "BLS24-317", plonkProof.VK, ""))
+
+	vkCache = newLRUCache(defaultVKCacheMB << 20)
+	loaded, err := gnarkVerify.WarmVerifyingKeyCache(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, 2, loaded)
+	require.Equal(t, 2, vkCache.len())
+
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "plonk-product", plonkProof.Proof, plonkProof.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, 2, vkCache.len())
+}
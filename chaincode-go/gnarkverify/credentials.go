@@ -0,0 +1,380 @@
+package gnarkverify
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+)
+
+const (
+	issuerObjectType   = "issuer"
+	claimObjectType    = "claim"
+	presentationDomain = "presentation"
+	// revokeIssuerAction RevokeIssuerAsMember 的匿名授权操作, 参数为发行方 id
+	revokeIssuerAction = "credentials:RevokeIssuer"
+
+	// credentialNbPublic circuits.Credential 的公开输入: 公钥的两个坐标, 各属性的 Disclose 和 Disclosed,
+	// Predicate, Threshold, Challenge
+	credentialNbPublic = 2 + 2*gvcircuits.CredentialAttributes + 3
+)
+
+// credentialSchema circuits.Credential 公开输入的 schema, 属性下标接在名称之后
+var credentialSchema = func() []InputSchema {
+	schema := []InputSchema{{Name: "publicKeyX", Type: InputField}, {Name: "publicKeyY", Type: InputField}}
+	for i := range gvcircuits.CredentialAttributes {
+		schema = append(schema, InputSchema{Name: fmt.Sprintf("disclose%d", i), Type: InputBool})
+	}
+	for i := range gvcircuits.CredentialAttributes {
+		schema = append(schema, InputSchema{Name: fmt.Sprintf("disclosed%d", i), Type: InputField})
+	}
+	return append(schema,
+		InputSchema{Name: "predicate", Type: InputUint64},
+		InputSchema{Name: "threshold", Type: InputUint64},
+		InputSchema{Name: "challenge", Type: InputField},
+	)
+}()
+
+// CredentialContract 基于 circuits.Credential 电路的匿名凭证. 发行方登记 EdDSA 公钥后在链下对属性向量签名,
+// 持有者证明持有有效签名并选择性披露部分属性, 合约记录披露的属性和满足的阈值条件.
+// 证明绑定由调用者身份和 nonce 确定的挑战, 每个挑战只能使用一次, 他人转发或重复提交证明时被拒绝
+type CredentialContract struct {
+	contractapi.Contract
+}
+
+// CredentialIssuer 凭证发行方, PublicKey 为压缩编码的十六进制公钥, KeyX 和 KeyY 为电路中公钥的坐标.
+// Attributes 为属性名称, 依次对应电路中的属性下标
+type CredentialIssuer struct {
+	ID         string   `json:"id"`
+	Owner      string   `json:"owner"`
+	VKID       string   `json:"vkId"`
+	Curve      string   `json:"curve"`
+	PublicKey  string   `json:"publicKey"`
+	KeyX       string   `json:"keyX"`
+	KeyY       string   `json:"keyY"`
+	Attributes []string `json:"attributes"`
+	Revoked    bool     `json:"revoked"`
+	RevokedAt  string   `json:"revokedAt,omitempty"`
+	Timestamp  string   `json:"timestamp"`
+}
+
+// DisclosedAttribute 持有者公开的属性
+type DisclosedAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Claim 一次验证通过的凭证出示: 披露的属性, 以及属性 Predicate 不小于 Threshold
+type Claim struct {
+	TxID      string               `json:"txId"`
+	Issuer    string               `json:"issuer"`
+	Disclosed []DisclosedAttribute `json:"disclosed"`
+	Predicate string               `json:"predicate"`
+	Threshold uint64               `json:"threshold"`
+	Challenge string               `json:"challenge"`
+	Timestamp string               `json:"timestamp"`
+}
+
+func issuerKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(issuerObjectType, []string{id})
+}
+
+func readCredentialIssuer(ctx contractapi.TransactionContextInterface, id string) (*CredentialIssuer, error) {
+	key, err := issuerKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("issuer %s does not exist", id)
+	}
+	var issuer CredentialIssuer
+	if err := json.Unmarshal(data, &issuer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal issuer %s: %v", id, err)
+	}
+	return &issuer, nil
+}
+
+func putCredentialIssuer(ctx contractapi.TransactionContextInterface, issuer *CredentialIssuer) error {
+	key, err := issuerKey(ctx, issuer.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(issuer)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// attributeIndex 属性名称在发行方属性列表中的下标
+func (i *CredentialIssuer) attributeIndex(name string) (int, error) {
+	for index, attribute := range i.Attributes {
+		if attribute == name {
+			return index, nil
+		}
+	}
+	return 0, fmt.Errorf("issuer %s has no attribute %q", i.ID, name)
+}
+
+// claimNullifierScope 发行方凭证出示的挑战的作用域
+func claimNullifierScope(issuerID string) string {
+	return claimObjectType + "~" + issuerID
+}
+
+// presentationChallenge 由发行方、调用者身份和 nonce 确定的挑战, 其他调用者得到不同的挑战
+func presentationChallenge(ctx contractapi.TransactionContextInterface, issuer *CredentialIssuer, nonce string) (string, error) {
+	_, curve, err := parseCurve(issuer.Curve)
+	if err != nil {
+		return "", err
+	}
+	return callerTag(ctx, curve, presentationDomain, issuer.ID, nonce)
+}
+
+// RegisterIssuer 登记凭证发行方, 调用者的组织成为发行方的所有者. vkID 为登记时声明了 credentialSchema 的 circuits.Credential 验证密钥,
+// publicKey 为与验证密钥同一曲线上的压缩编码十六进制 EdDSA 公钥, attributesJSON 为属性名称的 json 数组
+func (c *CredentialContract) RegisterIssuer(ctx contractapi.TransactionContextInterface, id string, vkID string, publicKey string, attributesJSON string) error {
+	if id == "" {
+		return fmt.Errorf("issuer id must not be empty")
+	}
+	key, err := issuerKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read issuer %s: %v", id, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("issuer %s already exists", id)
+	}
+
+	var attributes []string
+	if err := json.Unmarshal([]byte(attributesJSON), &attributes); err != nil {
+		return fmt.Errorf("failed to unmarshal attributes: %v", err)
+	}
+	if len(attributes) == 0 || len(attributes) > gvcircuits.CredentialAttributes {
+		return fmt.Errorf("issuer must have 1 to %d attributes", gvcircuits.CredentialAttributes)
+	}
+	seen := map[string]bool{}
+	for _, attribute := range attributes {
+		if attribute == "" || seen[attribute] {
+			return fmt.Errorf("attribute names must be non-empty and unique")
+		}
+		seen[attribute] = true
+	}
+
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.NbPublic != credentialNbPublic {
+		return fmt.Errorf("verifying key %s does not have %d public inputs", vkID, credentialNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "credential", credentialSchema); err != nil {
+		return err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := hex.DecodeString(publicKey)
+	if err != nil {
+		return newError(ErrBadEncoding, "failed to decode public key from hex: %v", err)
+	}
+	x, y, err := gvcircuits.EdDSAPublicKey(curve, keyBytes)
+	if err != nil {
+		return newError(ErrBadEncoding, "%v", err)
+	}
+	owner, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	return putCredentialIssuer(ctx, &CredentialIssuer{
+		ID:         id,
+		Owner:      owner,
+		VKID:       vkID,
+		Curve:      vkRecord.Curve,
+		PublicKey:  hex.EncodeToString(keyBytes),
+		KeyX:       x.String(),
+		KeyY:       y.String(),
+		Attributes: attributes,
+		Timestamp:  ts.Format(time.RFC3339Nano),
+	})
+}
+
+// GetIssuer 查询凭证发行方
+func (c *CredentialContract) GetIssuer(ctx contractapi.TransactionContextInterface, id string) (*CredentialIssuer, error) {
+	return readCredentialIssuer(ctx, id)
+}
+
+// RevokeIssuer 发行方所有者吊销公钥, 之后不再接受该发行方的凭证, 已记录的出示结果保留
+func (c *CredentialContract) RevokeIssuer(ctx contractapi.TransactionContextInterface, id string) error {
+	issuer, err := readCredentialIssuer(ctx, id)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != issuer.Owner {
+		return fmt.Errorf("only %s can revoke issuer %s", issuer.Owner, id)
+	}
+	return revokeIssuer(ctx, issuer)
+}
+
+// RevokeIssuerAsMember 发行方所有者组织的成员匿名吊销公钥, 例如发现签名私钥泄露时, 不暴露是哪个成员.
+// 由 requireMember 守卫: authJSON 为对操作 "credentials:RevokeIssuer" 和参数 [id] 的匿名授权凭据,
+// 公开输入可用 AuthContract.GetAuthInputs 查询
+func (c *CredentialContract) RevokeIssuerAsMember(ctx contractapi.TransactionContextInterface, id string, authJSON string) error {
+	issuer, err := readCredentialIssuer(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := requireMember(ctx, issuer.Owner, revokeIssuerAction, []string{id}, authJSON); err != nil {
+		return err
+	}
+	return revokeIssuer(ctx, issuer)
+}
+
+func revokeIssuer(ctx contractapi.TransactionContextInterface, issuer *CredentialIssuer) error {
+	if issuer.Revoked {
+		return fmt.Errorf("issuer %s is already revoked", issuer.ID)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	issuer.Revoked = true
+	issuer.RevokedAt = ts.Format(time.RFC3339Nano)
+	return putCredentialIssuer(ctx, issuer)
+}
+
+// GetPresentationChallenge 查询调用者以 nonce 出示发行方凭证时证明所需的挑战, 须以出示时的同一身份查询
+func (c *CredentialContract) GetPresentationChallenge(ctx contractapi.TransactionContextInterface, issuerID string, nonce string) (string, error) {
+	issuer, err := readCredentialIssuer(ctx, issuerID)
+	if err != nil {
+		return "", err
+	}
+	return presentationChallenge(ctx, issuer, nonce)
+}
+
+// Present 验证凭证出示的证明并记录结果. disclosedJSON 为披露的属性名称到值的 json 对象,
+// predicate 为不小于 threshold 的属性名称, nonce 为调用者选择的字符串, 证明中的挑战由 GetPresentationChallenge 给出.
+// 同一挑战再次出示时返回 NULLIFIER_SPENT, 其他调用者转发证明时挑战不同, 证明无效
+func (c *CredentialContract) Present(ctx contractapi.TransactionContextInterface, issuerID string, proofStr string, disclosedJSON string, predicate string, threshold uint64, nonce string) (*Claim, error) {
+	issuer, err := readCredentialIssuer(ctx, issuerID)
+	if err != nil {
+		return nil, err
+	}
+	if issuer.Revoked {
+		return nil, fmt.Errorf("issuer %s is revoked", issuerID)
+	}
+	predicateIndex, err := issuer.attributeIndex(predicate)
+	if err != nil {
+		return nil, err
+	}
+	var disclosedValues map[string]string
+	if err := json.Unmarshal([]byte(disclosedJSON), &disclosedValues); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal disclosed attributes: %v", err)
+	}
+
+	disclose := make([]string, gvcircuits.CredentialAttributes)
+	disclosed := make([]string, gvcircuits.CredentialAttributes)
+	for i := range disclose {
+		disclose[i], disclosed[i] = "0", "0"
+	}
+	for name, value := range disclosedValues {
+		index, err := issuer.attributeIndex(name)
+		if err != nil {
+			return nil, err
+		}
+		if disclosed[index], err = normalizeFieldElement(value); err != nil {
+			return nil, err
+		}
+		disclose[index] = "1"
+	}
+	inputs := append([]string{issuer.KeyX, issuer.KeyY}, disclose...)
+	inputs = append(inputs, disclosed...)
+	challenge, err := presentationChallenge(ctx, issuer, nonce)
+	if err != nil {
+		return nil, err
+	}
+	inputs = append(inputs, strconv.Itoa(predicateIndex), strconv.FormatUint(threshold, 10), challenge)
+	witness, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, issuer.VKID)
+	if err != nil {
+		return nil, err
+	}
+	_, record, err := verifyAndRecord(ctx, vkRecord, proofStr, string(witness), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := spendNullifier(ctx, claimNullifierScope(issuerID), challenge); err != nil {
+		return nil, err
+	}
+
+	claim := &Claim{
+		TxID:      record.TxID,
+		Issuer:    issuerID,
+		Disclosed: []DisclosedAttribute{},
+		Predicate: predicate,
+		Threshold: threshold,
+		Challenge: challenge,
+		Timestamp: record.Timestamp,
+	}
+	for i, name := range issuer.Attributes {
+		if disclose[i] == "1" {
+			claim.Disclosed = append(claim.Disclosed, DisclosedAttribute{Name: name, Value: disclosed[i]})
+		}
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(claimObjectType, []string{issuerID, record.TxID})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(claim)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(key, data); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// GetClaims 查询发行方凭证的全部出示结果
+func (c *CredentialContract) GetClaims(ctx contractapi.TransactionContextInterface, issuerID string) ([]*Claim, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(claimObjectType, []string{issuerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list claims: %v", err)
+	}
+	defer iterator.Close()
+	claims := []*Claim{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list claims: %v", err)
+		}
+		var claim Claim
+		if err := json.Unmarshal(kv.Value, &claim); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal claim: %v", err)
+		}
+		claims = append(claims, &claim)
+	}
+	return claims, nil
+}
@@ -0,0 +1,123 @@
+package gnarkverify
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredential(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	holderContext := newClientContext(chaincodeStub, "Org2MSP", "x509::CN=holder")
+	// relayContext 同一组织的另一个成员, 拿到持有者的证明后尝试重放
+	relayContext := newClientContext(chaincodeStub, "Org2MSP", "x509::CN=relay")
+	credentials := &CredentialContract{}
+
+	curve := ecc.BN254
+	shape, err := gvcircuits.NewCredential(curve)
+	require.NoError(t, err)
+	p, err := prover.Compile("groth16", "BN254", "credential", shape)
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	issuerKey, err := gvcircuits.NewEdDSAKey(curve, "issuer")
+	require.NoError(t, err)
+	// 国家代码、年龄、证件号
+	attributes := []*big.Int{big.NewInt(86), big.NewInt(30), big.NewInt(123456789)}
+	sig, err := gvcircuits.SignCredential(curve, issuerKey, attributes)
+	require.NoError(t, err)
+	present := func(disclose []bool, predicate, threshold uint64, challenge string) *prover.Artifact {
+		data, err := json.Marshal(map[string]any{
+			"attributes": attributes,
+			"disclose":   disclose,
+			"predicate":  predicate,
+			"threshold":  threshold,
+			"challenge":  challenge,
+			"publicKey":  hex.EncodeToString(issuerKey.Public().Bytes()),
+			"signature":  hex.EncodeToString(sig),
+		})
+		require.NoError(t, err)
+		definition, err := gvcircuits.Lookup("credential")
+		require.NoError(t, err)
+		_, assignment, err := definition.Decode(curve, data)
+		require.NoError(t, err)
+		artifact, err := p.ProveAssignment(assignment)
+		require.NoError(t, err)
+		return artifact
+	}
+
+	sample := present([]bool{true}, 1, 18, "0")
+	require.NoError(t, registerVerifyingKey(transactionContext, "credential", "groth16", "BN254", sample.VK, schemaConfig(t, credentialSchema)))
+	require.NoError(t, registerVerifyingKey(transactionContext, "unnamed", "groth16", "BN254", sample.VK, ""))
+	publicKey := hex.EncodeToString(issuerKey.Public().Bytes())
+	attributesJSON := `["country","age","id"]`
+	require.ErrorContains(t, credentials.RegisterIssuer(transactionContext, "gov", "unnamed", publicKey, attributesJSON), "credential schema")
+	require.ErrorContains(t, credentials.RegisterIssuer(transactionContext, "gov", "credential", publicKey, `["age","age"]`), "unique")
+	require.ErrorContains(t, credentials.RegisterIssuer(transactionContext, "gov", "credential", publicKey, `["a","b","c","d","e"]`), "1 to 4 attributes")
+	require.ErrorIs(t, credentials.RegisterIssuer(transactionContext, "gov", "credential", "0102", attributesJSON), ErrBadEncoding)
+	require.NoError(t, credentials.RegisterIssuer(transactionContext, "gov", "credential", publicKey, attributesJSON))
+	require.ErrorContains(t, credentials.RegisterIssuer(transactionContext, "gov", "credential", publicKey, attributesJSON), "already exists")
+	issuer, err := credentials.GetIssuer(transactionContext, "gov")
+	require.NoError(t, err)
+	require.Equal(t, "Org1MSP", issuer.Owner)
+	require.False(t, issuer.Revoked)
+
+	// 持有者以 nonce n1 的挑战披露国家代码, 证明年龄不小于 18
+	challenge, err := credentials.GetPresentationChallenge(holderContext, "gov", "n1")
+	require.NoError(t, err)
+	relayChallenge, err := credentials.GetPresentationChallenge(relayContext, "gov", "n1")
+	require.NoError(t, err)
+	require.NotEqual(t, challenge, relayChallenge)
+	adult := present([]bool{true}, 1, 18, challenge)
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"87"}`, "age", 18, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86","id":"123456789"}`, "age", 18, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86"}`, "age", 21, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86"}`, "height", 18, "n1")
+	require.ErrorContains(t, err, "no attribute")
+	chaincodeStub.GetTxIDReturns("present1")
+	claim, err := credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86"}`, "age", 18, "n1")
+	require.NoError(t, err)
+	require.Equal(t, &Claim{
+		TxID:      "present1",
+		Issuer:    "gov",
+		Disclosed: []DisclosedAttribute{{Name: "country", Value: "86"}},
+		Predicate: "age",
+		Threshold: 18,
+		Challenge: challenge,
+		Timestamp: "2025-08-08T12:00:00Z",
+	}, claim)
+
+	// 重放: 持有者重复提交同一挑战, 换一个 nonce, 或他人转发证明
+	chaincodeStub.GetTxIDReturns("replay")
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86"}`, "age", 18, "n1")
+	require.ErrorIs(t, err, ErrNullifierSpent)
+	_, err = credentials.Present(holderContext, "gov", adult.Proof, `{"country":"86"}`, "age", 18, "n2")
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = credentials.Present(relayContext, "gov", adult.Proof, `{"country":"86"}`, "age", 18, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+
+	// 其他发行方的公钥签名的凭证无效
+	other, err := gvcircuits.NewEdDSAKey(curve, "other")
+	require.NoError(t, err)
+	require.NoError(t, credentials.RegisterIssuer(transactionContext, "other", "credential", hex.EncodeToString(other.Public().Bytes()), attributesJSON))
+	_, err = credentials.Present(holderContext, "other", adult.Proof, `{"country":"86"}`, "age", 18, "n1")
+	require.ErrorIs(t, err, ErrProofInvalid)
+
+	// 吊销后不再接受出示, 已记录的结果保留
+	require.ErrorContains(t, credentials.RevokeIssuer(holderContext, "gov"), "only Org1MSP")
+	require.NoError(t, credentials.RevokeIssuer(transactionContext, "gov"))
+	require.ErrorContains(t, credentials.RevokeIssuer(transactionContext, "gov"), "already revoked")
+	_, err = credentials.Present(holderContext, "gov", present(nil, 0, 0, challenge).Proof, `{}`, "country", 0, "n3")
+	require.ErrorContains(t, err, "revoked")
+	claims, err := credentials.GetClaims(transactionContext, "gov")
+	require.NoError(t, err)
+	require.Equal(t, []*Claim{claim}, claims)
+}
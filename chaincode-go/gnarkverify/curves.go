@@ -0,0 +1,132 @@
+package gnarkverify
+
+import (
+	"runtime/debug"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// supportedCurves 合约支持的曲线, name 为写入账本的规范名称
+var supportedCurves = []struct {
+	name    string
+	id      ecc.ID
+	aliases []string
+}{
+	{"BN254", ecc.BN254, []string{"bn128", "altbn128"}},
+	{"BLS12-377", ecc.BLS12_377, nil},
+	{"BLS12-381", ecc.BLS12_381, nil},
+	{"BW6-633", ecc.BW6_633, nil},
+	{"BW6-761", ecc.BW6_761, nil},
+	{"BLS24-315", ecc.BLS24_315, nil},
+	{"BLS24-317", ecc.BLS24_317, nil},
+}
+
+// curveNames 归一化名称 (小写, 去掉 '-' '_' 空格) 到 supportedCurves 下标
+var curveNames = func() map[string]int {
+	names := map[string]int{}
+	for i, curve := range supportedCurves {
+		names[normalizeCurveName(curve.name)] = i
+		for _, alias := range curve.aliases {
+			names[normalizeCurveName(alias)] = i
+		}
+	}
+	return names
+}()
+
+func normalizeCurveName(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(name))
+}
+
+// parseCurve 解析曲线名称或别名, 返回规范名称和曲线 ID, 未知曲线直接报错
+func parseCurve(name string) (string, ecc.ID, error) {
+	i, ok := curveNames[normalizeCurveName(name)]
+	if !ok {
+		return "", ecc.UNKNOWN, newError(ErrUnknownCurve, "unknown curve %.64q", name)
+	}
+	return supportedCurves[i].name, supportedCurves[i].id, nil
+}
+
+// Scheme 某个证明协议支持的曲线
+type Scheme struct {
+	Protocol string   `json:"protocol"`
+	Curves   []string `json:"curves"`
+}
+
+// SupportedSchemes 当前部署的构建支持的协议与曲线组合
+type SupportedSchemes struct {
+	GnarkVersion       string   `json:"gnarkVersion"`
+	GnarkCryptoVersion string   `json:"gnarkCryptoVersion"`
+	Schemes            []Scheme `json:"schemes"`
+}
+
+// ContractInfo 合约信息
+type ContractInfo struct {
+	Name         string            `json:"name"`
+	Capabilities *SupportedSchemes `json:"capabilities"`
+}
+
+// protocolSupportsCurve 通过构造验证密钥探测 gnark 是否实现了该组合
+func protocolSupportsCurve(protocol string, curve ecc.ID) (supported bool) {
+	defer func() {
+		if recover() != nil {
+			supported = false
+		}
+	}()
+	switch protocol {
+	case protocolGroth16:
+		return groth16.NewVerifyingKey(curve) != nil
+	case protocolPlonk:
+		return plonk.NewVerifyingKey(curve) != nil
+	}
+	return false
+}
+
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+func supportedSchemes() *SupportedSchemes {
+	schemes := &SupportedSchemes{
+		GnarkVersion:       moduleVersion("github.com/consensys/gnark"),
+		GnarkCryptoVersion: moduleVersion("github.com/consensys/gnark-crypto"),
+	}
+	for _, protocol := range []string{protocolGroth16, protocolPlonk} {
+		scheme := Scheme{Protocol: protocol, Curves: []string{}}
+		for _, curve := range supportedCurves {
+			if protocolSupportsCurve(protocol, curve.id) {
+				scheme.Curves = append(scheme.Curves, curve.name)
+			}
+		}
+		schemes.Schemes = append(schemes.Schemes, scheme)
+	}
+	return schemes
+}
+
+// GetSupportedSchemes 查询支持的协议与曲线组合及 gnark 版本
+func (c *GnarkVerifyContract) GetSupportedSchemes(ctx contractapi.TransactionContextInterface) (*SupportedSchemes, error) {
+	return supportedSchemes(), nil
+}
+
+// GetContractInfo 获取合约信息
+func (c *GnarkVerifyContract) GetContractInfo(ctx contractapi.TransactionContextInterface) (*ContractInfo, error) {
+	return &ContractInfo{
+		Name:         "Gnark Verification Contract",
+		Capabilities: supportedSchemes(),
+	}, nil
+}
@@ -0,0 +1,63 @@
+package gnarkverify
+
+import (
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/oliverustc/gnarkabc/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurve(t *testing.T) {
+	for _, name := range []string{"BN254", "bn254", "BN-254", "bn128", "alt_bn128"} {
+		curveName, curve, err := parseCurve(name)
+		require.NoError(t, err, name)
+		require.Equal(t, "BN254", curveName)
+		require.Equal(t, ecc.BN254, curve)
+	}
+	curveName, curve, err := parseCurve("bls12_381")
+	require.NoError(t, err)
+	require.Equal(t, "BLS12-381", curveName)
+	require.Equal(t, ecc.BLS12_381, curve)
+
+	for _, curveName := range utils.CurveNameList {
+		canonical, curve, err := parseCurve(curveName)
+		require.NoError(t, err)
+		require.Equal(t, curveName, canonical)
+		require.Equal(t, utils.CurveMap[curveName], curve)
+	}
+
+	for _, name := range []string{"", "bn256", "secp256k1", "BLS12"} {
+		_, _, err := parseCurve(name)
+		require.ErrorContains(t, err, "unknown curve", name)
+	}
+}
+
+func TestGetSupportedSchemes(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	schemes, err := gnarkVerify.GetSupportedSchemes(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, "v0.13.0", schemes.GnarkVersion)
+	require.Equal(t, "v0.18.0", schemes.GnarkCryptoVersion)
+	require.Len(t, schemes.Schemes, 2)
+	for _, scheme := range schemes.Schemes {
+		require.ElementsMatch(t, utils.CurveNameList, scheme.Curves, scheme.Protocol)
+	}
+}
+
+func TestVerifyWithCurveAlias(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "bn128", params.VK, ""))
+	record, err := gnarkVerify.GetVerifyingKey(transactionContext, "product")
+	require.NoError(t, err)
+	require.Equal(t, "BN254", record.Curve)
+
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "bn254", params.Proof, params.VK, params.WitnessPublic)
+	require.NoError(t, err)
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "bn256", params.Proof, params.VK, params.WitnessPublic)
+	require.ErrorContains(t, err, "unknown curve")
+}
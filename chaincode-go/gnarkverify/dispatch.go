@@ -0,0 +1,147 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// verifyResponseVersion VerifyResponse 的格式版本, 字段语义变化时递增
+const verifyResponseVersion = 1
+
+// rejections 证明被拒绝 (而非输入格式错误) 的错误分类, 以 valid=false 返回而不是交易失败
+var rejections = []error{ErrProofInvalid, ErrNullifierSpent, ErrBindingMismatch}
+
+// VerifyRequest VerifyProof 的请求, vk 与 vkId 二选一, witnessPublic 与 publicInputs 二选一
+type VerifyRequest struct {
+	Protocol      string `json:"protocol"`
+	Curve         string `json:"curve"`
+	VK            string `json:"vk,omitempty"`
+	VKID          string `json:"vkId,omitempty"`
+	Proof         string `json:"proof"`
+	WitnessPublic string `json:"witnessPublic,omitempty"`
+	// PublicInputs 十进制或 0x 前缀十六进制的域元素数组, 由链码构造公开见证
+	PublicInputs json.RawMessage `json:"publicInputs,omitempty"`
+}
+
+// VerifyResponse VerifyProof 的结果, 证明有效和无效时都会返回
+type VerifyResponse struct {
+	Version      int               `json:"version"`
+	Valid        bool              `json:"valid"`
+	Protocol     string            `json:"protocol"`
+	Curve        string            `json:"curve"`
+	VKHash       string            `json:"vkHash"`
+	PublicInputs []string          `json:"publicInputs,omitempty"`
+	Inputs       map[string]string `json:"inputs,omitempty"`
+	// PublicInputsHash 经 transient 验证时代替 PublicInputs 返回, 为以 salt 为密钥的 HMAC
+	PublicInputsHash string `json:"publicInputsHash,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+	ErrorCode        string `json:"errorCode,omitempty"`
+}
+
+func rejectionCode(err error) string {
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return ErrorCode(err)
+		}
+	}
+	return ""
+}
+
+// resolveVerifyingKey 取得请求使用的验证密钥; 直接携带的 vk 视为未登记的临时密钥
+func resolveVerifyingKey(ctx contractapi.TransactionContextInterface, request *VerifyRequest) (*VerifyingKeyRecord, error) {
+	if request.VKID != "" {
+		if request.VK != "" {
+			return nil, newError(ErrBadRequest, "vk and vkId must not both be set")
+		}
+		record, err := readVerifyingKeyRecord(ctx, request.VKID)
+		if err != nil {
+			return nil, err
+		}
+		if request.Protocol != "" && request.Protocol != record.Protocol {
+			return nil, newError(ErrBadRequest, "verifying key %s is registered for protocol %s, not %.64q", record.ID, record.Protocol, request.Protocol)
+		}
+		if request.Curve != "" {
+			curveName, _, err := parseCurve(request.Curve)
+			if err != nil {
+				return nil, err
+			}
+			if curveName != record.Curve {
+				return nil, newError(ErrBadRequest, "verifying key %s is registered for curve %s, not %s", record.ID, record.Curve, curveName)
+			}
+		}
+		return record, nil
+	}
+
+	if request.VK == "" {
+		return nil, newError(ErrBadRequest, "either vk or vkId must be set")
+	}
+	curveName, _, err := parseCurve(request.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInputSizes(ctx, request.Protocol, curveName, request.VK, "", ""); err != nil {
+		return nil, err
+	}
+	vkHash, nbPublic, err := checkVerifyingKey(request.Protocol, curveName, request.VK)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkInlineVerifyingKey(ctx, vkHash, nbPublic); err != nil {
+		return nil, err
+	}
+	return &VerifyingKeyRecord{
+		Protocol: request.Protocol,
+		Curve:    curveName,
+		VK:       request.VK,
+		VKHash:   vkHash,
+		NbPublic: nbPublic,
+	}, nil
+}
+
+// VerifyProof 按请求中的 protocol 分发验证, 证明无效时返回 valid=false 而非错误,
+// 只有请求格式错误时才返回错误
+func (c *GnarkVerifyContract) VerifyProof(ctx contractapi.TransactionContextInterface, requestJSON string) (*VerifyResponse, error) {
+	var request VerifyRequest
+	if err := json.Unmarshal([]byte(requestJSON), &request); err != nil {
+		return nil, newError(ErrBadEncoding, "failed to unmarshal verify request (%s): %v", describeInput([]byte(requestJSON)), err)
+	}
+	return verifyRequest(ctx, &request, nil)
+}
+
+// verifyRequest 执行 VerifyProof 的请求, salt 非空时 (经 transient 调用) 结果、记录和事件中只包含加盐的公开见证哈希
+func verifyRequest(ctx contractapi.TransactionContextInterface, request *VerifyRequest, salt []byte) (*VerifyResponse, error) {
+	if len(request.PublicInputs) > 0 {
+		if request.WitnessPublic != "" {
+			return nil, newError(ErrBadRequest, "witnessPublic and publicInputs must not both be set")
+		}
+		request.WitnessPublic = string(request.PublicInputs)
+	}
+	vkRecord, err := resolveVerifyingKey(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &VerifyResponse{
+		Version:  verifyResponseVersion,
+		Protocol: vkRecord.Protocol,
+		Curve:    vkRecord.Curve,
+		VKHash:   vkRecord.VKHash,
+	}
+	_, record, err := verifyAndRecord(ctx, vkRecord, request.Proof, request.WitnessPublic, salt)
+	if err != nil {
+		code := rejectionCode(err)
+		if code == "" {
+			return nil, err
+		}
+		response.Reason = err.Error()
+		response.ErrorCode = code
+		return response, nil
+	}
+	response.Valid = true
+	response.PublicInputs = record.PublicInputs
+	response.Inputs = record.Inputs
+	response.PublicInputsHash = record.PublicInputsHash
+	return response, nil
+}
@@ -0,0 +1,86 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func verifyRequestJSON(t *testing.T, request VerifyRequest) string {
+	requestJSON, err := json.Marshal(request)
+	require.NoError(t, err)
+	return string(requestJSON)
+}
+
+func TestVerifyProof(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	groth16Proof := groth16Params(t, "BLS12-377")
+	chaincodeStub.GetTxIDReturns("tx1")
+	response, err := gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		Protocol:      "groth16",
+		Curve:         "BLS12-377",
+		VK:            groth16Proof.VK,
+		Proof:         groth16Proof.Proof,
+		WitnessPublic: groth16Proof.WitnessPublic,
+	}))
+	require.NoError(t, err)
+	require.Equal(t, verifyResponseVersion, response.Version)
+	require.True(t, response.Valid)
+	require.Equal(t, "groth16", response.Protocol)
+	require.Equal(t, "BLS12-377", response.Curve)
+	require.Len(t, response.PublicInputs, 1)
+	require.Empty(t, response.ErrorCode)
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, record.VKHash, response.VKHash)
+
+	plonkProof := plonkParams(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "plonk-product", "plonk", "BN254", plonkProof.VK, ""))
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "plonk-product", `{"nullifier":{"index":0}}`))
+	byKeyID := VerifyRequest{
+		Protocol:      "plonk",
+		VKID:          "plonk-product",
+		Proof:         plonkProof.Proof,
+		WitnessPublic: plonkProof.WitnessPublic,
+	}
+	response, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, byKeyID))
+	require.NoError(t, err)
+	require.True(t, response.Valid)
+	require.Equal(t, "BN254", response.Curve)
+
+	// 重放被拒绝, 以结构化结果返回
+	response, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, byKeyID))
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "NULLIFIER_SPENT", response.ErrorCode)
+
+	// 证明无效
+	invalid := VerifyRequest{
+		Protocol:      "groth16",
+		Curve:         "BLS12-377",
+		VK:            groth16Proof.VK,
+		Proof:         groth16Proof.Proof,
+		WitnessPublic: groth16Params(t, "BLS12-377").WitnessPublic,
+	}
+	response, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, invalid))
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "PROOF_INVALID", response.ErrorCode)
+	require.NotEmpty(t, response.Reason)
+	require.NotEmpty(t, response.VKHash)
+
+	// 格式错误的请求返回错误
+	_, err = gnarkVerify.VerifyProof(transactionContext, "{")
+	require.Error(t, err)
+	invalid.Protocol = "marlin"
+	_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, invalid))
+	require.ErrorContains(t, err, "unknown protocol")
+	byKeyID.Protocol = "groth16"
+	_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, byKeyID))
+	require.ErrorContains(t, err, "registered for protocol plonk")
+	_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{Protocol: "groth16", Curve: "BN254"}))
+	require.ErrorContains(t, err, "either vk or vkId")
+}
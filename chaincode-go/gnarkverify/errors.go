@@ -0,0 +1,90 @@
+package gnarkverify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 验证失败的错误分类, 可用 errors.Is 判断; 返回给客户端的错误信息以对应的错误码开头
+var (
+	ErrUnknownCurve     = errors.New("unknown curve")
+	ErrUnknownProtocol  = errors.New("unknown protocol")
+	ErrBadRequest       = errors.New("bad request")
+	ErrKeyNotFound      = errors.New("verifying key not found")
+	ErrBadEncoding      = errors.New("bad encoding")
+	ErrMalformedVK      = errors.New("malformed verifying key")
+	ErrMalformedProof   = errors.New("malformed proof")
+	ErrWitnessMismatch  = errors.New("public witness mismatch")
+	ErrProofInvalid     = errors.New("proof invalid")
+	ErrNullifierSpent   = errors.New("nullifier already spent")
+	ErrInputTooLarge    = errors.New("input too large")
+	ErrBindingMismatch  = errors.New("public input does not match its binding")
+	ErrInputsNotPrivate = errors.New("public inputs must not be passed as arguments")
+	ErrKeyRegistered    = errors.New("verifying key is registered with a config")
+	ErrKeyExists        = errors.New("verifying key already exists")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// errorCodes 错误分类对应的稳定错误码, 客户端依赖这些字符串, 不要修改
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrUnknownCurve, "UNKNOWN_CURVE"},
+	{ErrUnknownProtocol, "UNKNOWN_PROTOCOL"},
+	{ErrBadRequest, "BAD_REQUEST"},
+	{ErrKeyNotFound, "VK_NOT_FOUND"},
+	{ErrBadEncoding, "BAD_ENCODING"},
+	{ErrMalformedVK, "MALFORMED_VK"},
+	{ErrMalformedProof, "MALFORMED_PROOF"},
+	{ErrWitnessMismatch, "WITNESS_MISMATCH"},
+	{ErrProofInvalid, "PROOF_INVALID"},
+	{ErrNullifierSpent, "NULLIFIER_SPENT"},
+	{ErrInputTooLarge, "INPUT_TOO_LARGE"},
+	{ErrBindingMismatch, "BINDING_MISMATCH"},
+	{ErrInputsNotPrivate, "INPUTS_NOT_PRIVATE"},
+	{ErrKeyRegistered, "VK_REGISTERED"},
+	{ErrKeyExists, "VK_EXISTS"},
+	{ErrPermissionDenied, "PERMISSION_DENIED"},
+}
+
+// Error 带错误码的错误, Error() 形如 "PROOF_INVALID: <message>"
+type Error struct {
+	Code    string
+	Message string
+	kind    error
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap 返回错误分类, 使 errors.Is(err, ErrProofInvalid) 成立
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// newError 构造某一分类的错误, message 中不要放入原始输入, 用 describeInput 代替
+func newError(kind error, format string, args ...any) error {
+	code := "INTERNAL"
+	for _, c := range errorCodes {
+		if c.err == kind {
+			code = c.code
+		}
+	}
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), kind: kind}
+}
+
+// ErrorCode 返回错误链中第一个带错误码的错误的错误码, 没有时返回空字符串
+func ErrorCode(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
+// describeInput 只用哈希和长度描述输入, 避免在错误信息中输出整段字节
+func describeInput(data []byte) string {
+	return fmt.Sprintf("sha256 %s, %d bytes", hashHex(data), len(data))
+}
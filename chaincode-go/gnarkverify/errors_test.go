@@ -0,0 +1,124 @@
+package gnarkverify
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/stretchr/testify/require"
+)
+
+// twoInputWitness 含两个公开输入的见证, 与 product 电路的验证密钥不匹配
+func twoInputWitness(t *testing.T) string {
+	publicWitness, err := witness.New(ecc.BN254.ScalarField())
+	require.NoError(t, err)
+	values := make(chan any, 2)
+	values <- 1
+	values <- 2
+	close(values)
+	require.NoError(t, publicWitness.Fill(2, 0, values))
+	data, err := publicWitness.MarshalBinary()
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func truncateBase64(t *testing.T, str string) string {
+	data, err := base64.StdEncoding.DecodeString(str)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data[:len(data)/2])
+}
+
+func TestErrorCodes(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+	plonkProof := plonkParams(t, "BN254")
+	longCurve := strings.Repeat("x", 1000)
+	groth16 := func(curve, proof, vk, witness string) (string, error) {
+		return gnarkVerify.VerifyGroth16Proof(transactionContext, curve, proof, vk, witness)
+	}
+	plonk := func(curve, proof, vk, witness string) (string, error) {
+		return gnarkVerify.VerifyPlonkProof(transactionContext, curve, proof, vk, witness)
+	}
+
+	testCases := []struct {
+		name     string
+		verify   func(curve, proof, vk, witness string) (string, error)
+		curve    string
+		proof    string
+		vk       string
+		witness  string
+		expected error
+		code     string
+	}{
+		{"unknown curve", groth16, longCurve, params.Proof, params.VK, params.WitnessPublic, ErrUnknownCurve, "UNKNOWN_CURVE"},
+		{"bad proof encoding", groth16, "BN254", "not base64!" + params.Proof, params.VK, params.WitnessPublic, ErrBadEncoding, "BAD_ENCODING"},
+		{"bad witness encoding", plonk, "BN254", plonkProof.Proof, plonkProof.VK, "%%%", ErrBadEncoding, "BAD_ENCODING"},
+		{"malformed vk", groth16, "BN254", params.Proof, truncateBase64(t, params.VK), params.WitnessPublic, ErrMalformedVK, "MALFORMED_VK"},
+		{"malformed proof", plonk, "BN254", truncateBase64(t, plonkProof.Proof), plonkProof.VK, plonkProof.WitnessPublic, ErrMalformedProof, "MALFORMED_PROOF"},
+		{"groth16 witness mismatch", groth16, "BN254", params.Proof, params.VK, twoInputWitness(t), ErrWitnessMismatch, "WITNESS_MISMATCH"},
+		{"plonk witness mismatch", plonk, "BN254", plonkProof.Proof, plonkProof.VK, twoInputWitness(t), ErrWitnessMismatch, "WITNESS_MISMATCH"},
+		{"proof invalid", groth16, "BN254", params.Proof, params.VK, groth16Params(t, "BN254").WitnessPublic, ErrProofInvalid, "PROOF_INVALID"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verify(tc.curve, tc.proof, tc.vk, tc.witness)
+			require.ErrorIs(t, err, tc.expected)
+			require.Equal(t, tc.code, ErrorCode(err))
+			require.True(t, strings.HasPrefix(err.Error(), tc.code+": "), err.Error())
+			// 错误信息中不包含原始输入
+			require.Less(t, len(err.Error()), 300, err.Error())
+			for _, input := range []string{tc.proof, tc.vk, tc.witness} {
+				require.NotContains(t, err.Error(), input)
+			}
+		})
+	}
+
+	var coded *Error
+	_, err := gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", "???", params.VK, params.WitnessPublic)
+	require.True(t, errors.As(err, &coded))
+	require.Equal(t, "BAD_ENCODING", coded.Code)
+	require.Contains(t, coded.Message, "sha256 "+hashHex([]byte("???")))
+	require.Contains(t, coded.Message, "3 bytes")
+
+	require.Empty(t, ErrorCode(errors.New("plain error")))
+	require.Empty(t, ErrorCode(nil))
+}
+
+func TestRequestErrorCodes(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, ""))
+	verifyProof := func(requestJSON string) error {
+		_, err := gnarkVerify.VerifyProof(transactionContext, requestJSON)
+		return err
+	}
+	_, byKeyIDErr := gnarkVerify.VerifyProofByKeyID(transactionContext, "missing", params.Proof, params.WitnessPublic)
+	_, batchErr := gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", params.VK, "[]")
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected error
+		code     string
+	}{
+		{"unknown protocol", verifyProof(verifyRequestJSON(t, VerifyRequest{Protocol: "marlin", Curve: "BN254", VK: params.VK, Proof: params.Proof, WitnessPublic: params.WitnessPublic})), ErrUnknownProtocol, "UNKNOWN_PROTOCOL"},
+		{"vk and vkId", verifyProof(verifyRequestJSON(t, VerifyRequest{VK: params.VK, VKID: "product", Proof: params.Proof, WitnessPublic: params.WitnessPublic})), ErrBadRequest, "BAD_REQUEST"},
+		{"no vk", verifyProof(verifyRequestJSON(t, VerifyRequest{Protocol: "groth16", Curve: "BN254", Proof: params.Proof, WitnessPublic: params.WitnessPublic})), ErrBadRequest, "BAD_REQUEST"},
+		{"protocol mismatch", verifyProof(verifyRequestJSON(t, VerifyRequest{Protocol: "plonk", VKID: "product", Proof: params.Proof, WitnessPublic: params.WitnessPublic})), ErrBadRequest, "BAD_REQUEST"},
+		{"unknown key", verifyProof(verifyRequestJSON(t, VerifyRequest{VKID: "missing", Proof: params.Proof, WitnessPublic: params.WitnessPublic})), ErrKeyNotFound, "VK_NOT_FOUND"},
+		{"bad request encoding", verifyProof("{"), ErrBadEncoding, "BAD_ENCODING"},
+		{"unknown key by id", byKeyIDErr, ErrKeyNotFound, "VK_NOT_FOUND"},
+		{"empty batch", batchErr, ErrBadRequest, "BAD_REQUEST"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorIs(t, tc.err, tc.expected)
+			require.Equal(t, tc.code, ErrorCode(tc.err))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// ProofVerifiedEventName 验证成功时发出的链码事件名
+	ProofVerifiedEventName = "ProofVerified"
+	// BatchVerifiedEventName 批量验证中有证明有效时发出的链码事件名
+	BatchVerifiedEventName = "BatchVerified"
+)
+
+// ProofVerifiedEvent ProofVerified 事件的负载
+type ProofVerifiedEvent struct {
+	TxID             string            `json:"txId"`
+	VKID             string            `json:"vkId,omitempty"`
+	Protocol         string            `json:"protocol"`
+	Curve            string            `json:"curve"`
+	VKHash           string            `json:"vkHash"`
+	ProofHash        string            `json:"proofHash"`
+	PublicInputs     []string          `json:"publicInputs,omitempty"`
+	Inputs           map[string]string `json:"inputs,omitempty"`
+	PublicInputsHash string            `json:"publicInputsHash,omitempty"`
+	RecordID         string            `json:"recordId,omitempty"`
+	Submitter        string            `json:"submitter"`
+}
+
+// BatchVerifiedEvent BatchVerified 事件的负载, 每笔交易只能设置一个事件, 批量验证中有效的证明合并在一起
+type BatchVerifiedEvent struct {
+	TxID  string               `json:"txId"`
+	Items []ProofVerifiedEvent `json:"items"`
+}
+
+func proofVerifiedEvent(record *VerificationRecord) ProofVerifiedEvent {
+	return ProofVerifiedEvent{
+		TxID:             record.TxID,
+		VKID:             record.VKID,
+		Protocol:         record.Protocol,
+		Curve:            record.Curve,
+		VKHash:           record.VKHash,
+		ProofHash:        record.ProofHash,
+		PublicInputs:     record.PublicInputs,
+		Inputs:           record.Inputs,
+		PublicInputsHash: record.PublicInputsHash,
+		RecordID:         record.RecordID,
+		Submitter:        record.Creator,
+	}
+}
+
+// emitProofVerified 根据验证记录设置链码事件, 每笔交易只保留最后一次设置的事件
+func emitProofVerified(ctx contractapi.TransactionContextInterface, record *VerificationRecord) error {
+	payload, err := json.Marshal(proofVerifiedEvent(record))
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().SetEvent(ProofVerifiedEventName, payload); err != nil {
+		return fmt.Errorf("failed to set %s event: %v", ProofVerifiedEventName, err)
+	}
+	return nil
+}
+
+// emitBatchVerified 以批量验证中全部有效证明的记录设置链码事件
+func emitBatchVerified(ctx contractapi.TransactionContextInterface, events []ProofVerifiedEvent) error {
+	payload, err := json.Marshal(BatchVerifiedEvent{
+		TxID:  ctx.GetStub().GetTxID(),
+		Items: events,
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().SetEvent(BatchVerifiedEventName, payload); err != nil {
+		return fmt.Errorf("failed to set %s event: %v", BatchVerifiedEventName, err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofVerifiedEvent(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := plonkParams(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "plonk", "BN254", params.VK, ""))
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	_, err := gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, 1, chaincodeStub.SetEventCallCount())
+
+	name, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.Equal(t, ProofVerifiedEventName, name)
+	var event ProofVerifiedEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, "tx1", event.TxID)
+	require.Equal(t, "product", event.VKID)
+	require.Equal(t, "plonk", event.Protocol)
+	require.Equal(t, "BN254", event.Curve)
+	require.Equal(t, record.VKHash, event.VKHash)
+	require.Equal(t, record.ProofHash, event.ProofHash)
+	require.Equal(t, record.PublicInputs, event.PublicInputs)
+	require.Equal(t, "Org1MSP", event.Submitter)
+
+	// 验证失败不发出事件
+	other := plonkParams(t, "BN254")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", other.Proof, params.WitnessPublic)
+	require.Error(t, err)
+	require.Equal(t, 1, chaincodeStub.SetEventCallCount())
+
+	_, err = gnarkVerify.VerifyPlonkProof(transactionContext, "BN254", other.Proof, other.VK, other.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, 2, chaincodeStub.SetEventCallCount())
+	_, payload = chaincodeStub.SetEventArgsForCall(1)
+	var rawEvent ProofVerifiedEvent
+	require.NoError(t, json.Unmarshal(payload, &rawEvent))
+	require.Empty(t, rawEvent.VKID)
+	require.Equal(t, "plonk", rawEvent.Protocol)
+}
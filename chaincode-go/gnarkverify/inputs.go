@@ -0,0 +1,89 @@
+package gnarkverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/witness"
+)
+
+// isPublicInputsJSON 公开见证参数以 '[' 开头时按 json 数组解析, base64 编码中不会出现该字符
+func isPublicInputsJSON(str string) bool {
+	return strings.HasPrefix(strings.TrimSpace(str), "[")
+}
+
+// jsonInputChars json 数组中每个公开输入允许的字符数: 十进制数字不超过 3 倍的字节数, 另留出引号、0x 前缀和分隔符
+func jsonInputChars(curve ecc.ID) int {
+	return 3*((curve.ScalarField().BitLen()+7)/8) + 8
+}
+
+// parsePublicInputs 解析十进制或 0x 前缀十六进制的域元素数组, 元素可以是 json 数字或字符串, 须小于曲线的标量域模数
+func parsePublicInputs(str string, curve ecc.ID) ([]*big.Int, error) {
+	decoder := json.NewDecoder(strings.NewReader(str))
+	decoder.UseNumber()
+	var elements []any
+	if err := decoder.Decode(&elements); err != nil {
+		return nil, newError(ErrBadEncoding, "failed to unmarshal public inputs (%s): %v", describeInput([]byte(str)), err)
+	}
+	modulus := curve.ScalarField()
+	inputs := make([]*big.Int, len(elements))
+	for i, element := range elements {
+		var text string
+		switch element := element.(type) {
+		case json.Number:
+			text = element.String()
+		case string:
+			text = element
+		default:
+			return nil, newError(ErrBadEncoding, "public input %d must be a number or a string", i)
+		}
+		decimal, err := normalizeFieldElement(text)
+		if err != nil {
+			return nil, newError(ErrBadEncoding, "public input %d is not a non-negative integer", i)
+		}
+		inputs[i], _ = new(big.Int).SetString(decimal, 10)
+		if inputs[i].Cmp(modulus) >= 0 {
+			return nil, newError(ErrBadEncoding, "public input %d is not in the scalar field of %s", i, curve)
+		}
+	}
+	return inputs, nil
+}
+
+// witnessFromInputs 由 json 数组构造只含公开部分的见证, 与 gnark 序列化后再读取的结果一致
+func witnessFromInputs(str string, curve ecc.ID) (witness.Witness, error) {
+	inputs, err := parsePublicInputs(str, curve)
+	if err != nil {
+		return nil, err
+	}
+	publicWitness, err := witness.New(curve.ScalarField())
+	if err != nil {
+		return nil, newError(ErrUnknownCurve, "failed to create public witness for %s: %v", curve, err)
+	}
+	values := make(chan any, len(inputs))
+	for _, input := range inputs {
+		values <- input
+	}
+	close(values)
+	if err := publicWitness.Fill(len(inputs), 0, values); err != nil {
+		return nil, newError(ErrBadEncoding, "failed to fill public witness: %v", err)
+	}
+	return publicWitness, nil
+}
+
+// witnessHash 以 salt 为密钥的公开见证 gnark 编码的 HMAC-SHA256, 以二进制或 json 数组提交同一组公开输入时结果相同.
+// 公开输入 (选项、金额、纪元等) 的取值通常很少, 不加盐的哈希可以由持有通道账本的任何人穷举还原
+func witnessHash(publicWitness witness.Witness, salt []byte) (string, error) {
+	var buf bytes.Buffer
+	if _, err := publicWitness.WriteTo(&buf); err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(buf.Bytes())
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
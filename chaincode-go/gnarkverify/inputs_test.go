@@ -0,0 +1,130 @@
+package gnarkverify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePublicInputs(t *testing.T) {
+	modulus := ecc.BN254.ScalarField()
+	maxElement := new(big.Int).Sub(modulus, big.NewInt(1)).String()
+	inputs, err := parsePublicInputs(`[1, "2", "0x10", "0X0a", 0, "`+maxElement+`"]`, ecc.BN254)
+	require.NoError(t, err)
+	var decimals []string
+	for _, input := range inputs {
+		decimals = append(decimals, input.String())
+	}
+	require.Equal(t, []string{"1", "2", "16", "10", "0", maxElement}, decimals)
+
+	inputs, err = parsePublicInputs(`[]`, ecc.BN254)
+	require.NoError(t, err)
+	require.Empty(t, inputs)
+
+	for _, invalid := range []string{
+		`[`,
+		`{"a":1}`,
+		`[-1]`,
+		`["-1"]`,
+		`[1.5]`,
+		`[true]`,
+		`[null]`,
+		`["abc"]`,
+		`[" 1"]`,
+		`["` + modulus.String() + `"]`,
+		`["0x` + modulus.Text(16) + `"]`,
+	} {
+		_, err := parsePublicInputs(invalid, ecc.BN254)
+		require.ErrorIs(t, err, ErrBadEncoding, invalid)
+	}
+	// BLS12-377 的标量域小于 BN254, BN254 上合法的元素可能越界
+	_, err = parsePublicInputs(`["`+maxElement+`"]`, ecc.BLS12_377)
+	require.ErrorIs(t, err, ErrBadEncoding)
+}
+
+func TestVerifyWithPublicInputsJSON(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	groth16Proof := proveProduct(t, "groth16", "BN254", [2]int{1000003, 1000033})[0]
+	inputs := `["1000036000099"]`
+
+	// 由 json 数组构造的见证与 gnark 编码的见证一致
+	fromJSON, err := witnessFromInputs(`["0xe8d6ca6163"]`, ecc.BN254)
+	require.NoError(t, err)
+	fromBinary, err := readPublicWitness(groth16Proof.WitnessPublic, ecc.BN254)
+	require.NoError(t, err)
+	jsonHash, err := witnessHash(fromJSON, testSalt)
+	require.NoError(t, err)
+	binaryHash, err := witnessHash(fromBinary, testSalt)
+	require.NoError(t, err)
+	require.Equal(t, binaryHash, jsonHash)
+	witnessBytes, err := base64.StdEncoding.DecodeString(groth16Proof.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, saltedHash(testSalt, witnessBytes), jsonHash)
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, inputs)
+	require.NoError(t, err)
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1000036000099"}, record.PublicInputs)
+
+	response, err := gnarkVerify.VerifyProof(transactionContext, fmt.Sprintf(
+		`{"protocol":"groth16","curve":"BN254","vk":%q,"proof":%q,"publicInputs":[1000036000099]}`, groth16Proof.VK, groth16Proof.Proof))
+	require.NoError(t, err)
+	require.True(t, response.Valid, response.Reason)
+	require.Equal(t, []string{"1000036000099"}, response.PublicInputs)
+
+	response, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		Protocol:      "groth16",
+		Curve:         "BN254",
+		VK:            groth16Proof.VK,
+		Proof:         groth16Proof.Proof,
+		WitnessPublic: groth16Proof.WitnessPublic,
+		PublicInputs:  json.RawMessage(inputs),
+	}))
+	require.ErrorContains(t, err, "must not both be set")
+	require.Nil(t, response)
+
+	// 公开输入不匹配时证明无效, 个数不一致时报告 WITNESS_MISMATCH
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, `["1000036000098"]`)
+	require.ErrorIs(t, err, ErrProofInvalid)
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, `["1", "2"]`)
+	require.ErrorIs(t, err, ErrWitnessMismatch)
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, `["`+ecc.BN254.ScalarField().String()+`"]`)
+	require.ErrorIs(t, err, ErrBadEncoding)
+	tooMany := "[" + strings.Repeat("1,", defaultMaxPublicInputs) + "1]"
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, tooMany)
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	tooLong := `["` + strings.Repeat("0", jsonInputChars(ecc.BN254)*defaultMaxPublicInputs) + `1"]`
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", groth16Proof.Proof, groth16Proof.VK, tooLong)
+	require.ErrorIs(t, err, ErrInputTooLarge)
+
+	// PLONK、批量验证和 transient 同样支持
+	plonkProofs := proveProduct(t, "plonk", "BN254", [2]int{3, 5}, [2]int{7, 11})
+	results, err := gnarkVerify.VerifyPlonkBatch(transactionContext, "BN254", plonkProofs[0].VK, marshalBatchItems(t, []BatchItem{
+		{Proof: plonkProofs[0].Proof, WitnessPublic: `[15]`},
+		{Proof: plonkProofs[1].Proof, WitnessPublic: `["0x4d"]`},
+		{Proof: plonkProofs[1].Proof, WitnessPublic: `[15]`},
+	}))
+	require.NoError(t, err)
+	require.True(t, results[0].Valid, results[0].Reason)
+	require.True(t, results[1].Valid, results[1].Reason)
+	require.False(t, results[2].Valid)
+
+	transient := transientMap(t, plonkProofs[0], "")
+	transient[transientWitness] = []byte(`["15"]`)
+	chaincodeStub.GetTransientReturns(transient, nil)
+	response, err = gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "BN254")
+	require.NoError(t, err)
+	require.True(t, response.Valid, response.Reason)
+	plonkWitness, err := base64.StdEncoding.DecodeString(plonkProofs[0].WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, saltedHash(testSalt, plonkWitness), response.PublicInputsHash)
+}
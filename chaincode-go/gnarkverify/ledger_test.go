@@ -0,0 +1,106 @@
+package gnarkverify
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockLedger 基于 map 的内存世界状态和私有数据, 供 mock stub 使用
+type mockLedger struct {
+	state   map[string][]byte
+	private map[string]map[string][]byte
+}
+
+func newMockLedger() *mockLedger {
+	return &mockLedger{state: map[string][]byte{}, private: map[string]map[string][]byte{}}
+}
+
+func (l *mockLedger) iterator(prefix string) *mocks.StateQueryIterator {
+	var keys []string
+	for key := range l.state {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	iterator := &mocks.StateQueryIterator{}
+	next := 0
+	iterator.HasNextCalls(func() bool {
+		return next < len(keys)
+	})
+	iterator.NextCalls(func() (*queryresult.KV, error) {
+		key := keys[next]
+		next++
+		return &queryresult.KV{Key: key, Value: l.state[key]}, nil
+	})
+	return iterator
+}
+
+func splitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimPrefix(compositeKey, "\x00"), "\x00")
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+// newMockContext 创建带有内存世界状态的交易上下文
+func newMockContext(mspID string) (*mocks.TransactionContext, *mocks.ChaincodeStub, *mockLedger) {
+	ledger := newMockLedger()
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		return ledger.state[key], nil
+	})
+	chaincodeStub.PutStateCalls(func(key string, value []byte) error {
+		ledger.state[key] = value
+		return nil
+	})
+	chaincodeStub.DelStateCalls(func(key string) error {
+		delete(ledger.state, key)
+		return nil
+	})
+	chaincodeStub.GetPrivateDataCalls(func(collection, key string) ([]byte, error) {
+		return ledger.private[collection][key], nil
+	})
+	chaincodeStub.PutPrivateDataCalls(func(collection, key string, value []byte) error {
+		if ledger.private[collection] == nil {
+			ledger.private[collection] = map[string][]byte{}
+		}
+		ledger.private[collection][key] = value
+		return nil
+	})
+	chaincodeStub.GetTxIDReturns("tx0")
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2025, 8, 8, 12, 0, 0, 0, time.UTC)), nil)
+	chaincodeStub.CreateCompositeKeyCalls(shim.CreateCompositeKey)
+	chaincodeStub.SplitCompositeKeyCalls(splitCompositeKey)
+	chaincodeStub.GetStateByPartialCompositeKeyCalls(func(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+		prefix, err := shim.CreateCompositeKey(objectType, attributes)
+		if err != nil {
+			return nil, err
+		}
+		return ledger.iterator(prefix), nil
+	})
+
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetMSPIDReturns(mspID, nil)
+
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+	return transactionContext, chaincodeStub, ledger
+}
+
+// newClientContext 共用 chaincodeStub 世界状态的另一个调用者, id 为 GetID 返回的身份
+func newClientContext(chaincodeStub *mocks.ChaincodeStub, mspID string, id string) *mocks.TransactionContext {
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetMSPIDReturns(mspID, nil)
+	clientIdentity.GetIDReturns(id, nil)
+
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+	return transactionContext
+}
@@ -0,0 +1,325 @@
+package gnarkverify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	configObjectType = "config"
+	limitsConfigKey  = "limits"
+
+	// adminOU 管理员证书的组织单元, 与 test-network 开启 NodeOUs 后签发的 Admin 证书一致
+	adminOU = "admin"
+
+	defaultMaxPublicInputs = 64
+	defaultMaxCommitments  = 4
+	// defaultGoverningMSP 未配置时可以修改限制的组织, 与 test-network 的第一个组织一致
+	defaultGoverningMSP = "Org1MSP"
+
+	// witnessHeaderBytes 公开见证编码的头部: 公开个数、私有个数和向量长度各 4 字节
+	witnessHeaderBytes = 12
+)
+
+// Limits 输入大小限制, 保存在账本上, 只有 GoverningMSPs 中组织的管理员可以修改.
+// 未在 Sizes 中单独配置的协议和曲线按 MaxPublicInputs 和 MaxCommitments 推算上限
+type Limits struct {
+	MaxPublicInputs int         `json:"maxPublicInputs"`
+	MaxCommitments  int         `json:"maxCommitments"`
+	Sizes           []SizeLimit `json:"sizes,omitempty"`
+	// GoverningMSPs 可以修改限制的组织, 修改时为空表示保持不变
+	GoverningMSPs []string `json:"governingMsps,omitempty"`
+}
+
+// SizeLimit 某个协议和曲线上验证密钥、证明和公开见证解码后的最大字节数
+type SizeLimit struct {
+	Protocol        string `json:"protocol"`
+	Curve           string `json:"curve"`
+	MaxVKBytes      int    `json:"maxVkBytes"`
+	MaxProofBytes   int    `json:"maxProofBytes"`
+	MaxWitnessBytes int    `json:"maxWitnessBytes"`
+}
+
+func defaultLimits() *Limits {
+	return &Limits{MaxPublicInputs: defaultMaxPublicInputs, MaxCommitments: defaultMaxCommitments, GoverningMSPs: []string{defaultGoverningMSP}}
+}
+
+// rawSize 空对象非压缩编码的字节数, 即不含公开输入和承诺时的编码长度
+func rawSize(w interface {
+	WriteRawTo(io.Writer) (int64, error)
+}) int {
+	var buf bytes.Buffer
+	n, err := w.WriteRawTo(&buf)
+	if err != nil {
+		panic(fmt.Sprintf("failed to measure %T: %v", w, err))
+	}
+	return int(n)
+}
+
+// baseSizes 各协议和曲线上空验证密钥和空证明的非压缩编码长度, 以 "<protocol>/<curve>" 为键
+var baseSizes = func() map[string][2]int {
+	sizes := map[string][2]int{}
+	for _, curve := range supportedCurves {
+		sizes[protocolGroth16+"/"+curve.name] = [2]int{rawSize(groth16.NewVerifyingKey(curve.id)), rawSize(groth16.NewProof(curve.id))}
+		sizes[protocolPlonk+"/"+curve.name] = [2]int{rawSize(plonk.NewVerifyingKey(curve.id)), rawSize(plonk.NewProof(curve.id))}
+	}
+	return sizes
+}()
+
+// deriveSizeLimit 按编码格式推算上限: 点均按非压缩计, groth16 的验证密钥每个公开输入和承诺多一个 G1 点,
+// 每个承诺另有两个 G2 点和被承诺的下标; 证明每个承诺多一个 G1 点, PLONK 另多一个域元素
+func deriveSizeLimit(protocol string, curveName string, curve ecc.ID, limits *Limits) *SizeLimit {
+	fp := (curve.BaseField().BitLen() + 7) / 8
+	fr := (curve.ScalarField().BitLen() + 7) / 8
+	g1, g2 := 2*fp, 4*fp
+	base := baseSizes[protocol+"/"+curveName]
+	size := &SizeLimit{
+		Protocol:        protocol,
+		Curve:           curveName,
+		MaxWitnessBytes: witnessHeaderBytes + limits.MaxPublicInputs*fr,
+	}
+	switch protocol {
+	case protocolGroth16:
+		size.MaxVKBytes = base[0] + (limits.MaxPublicInputs+1+limits.MaxCommitments)*g1 +
+			limits.MaxCommitments*(2*g2+8*(limits.MaxPublicInputs+2))
+		size.MaxProofBytes = base[1] + limits.MaxCommitments*g1
+	case protocolPlonk:
+		size.MaxVKBytes = base[0] + limits.MaxCommitments*(g1+8)
+		size.MaxProofBytes = base[1] + limits.MaxCommitments*(g1+fr)
+	}
+	return size
+}
+
+func limitsKey(ctx contractapi.TransactionContextInterface) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{limitsConfigKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to create limits key: %v", err)
+	}
+	return key, nil
+}
+
+// readLimits 读取账本上的限制, 未配置时返回默认值
+func readLimits(ctx contractapi.TransactionContextInterface) (*Limits, error) {
+	key, err := limitsKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read limits: %v", err)
+	}
+	if data == nil {
+		return defaultLimits(), nil
+	}
+	var limits Limits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal limits: %v", err)
+	}
+	return &limits, nil
+}
+
+// sizeLimit 返回协议和曲线上生效的上限, Sizes 中的配置优先
+func (l *Limits) sizeLimit(protocol, curveName string) (*SizeLimit, error) {
+	curveName, curve, err := parseCurve(curveName)
+	if err != nil {
+		return nil, err
+	}
+	if protocol != protocolGroth16 && protocol != protocolPlonk {
+		return nil, newError(ErrUnknownProtocol, "unknown protocol %.64q", protocol)
+	}
+	for i := range l.Sizes {
+		if l.Sizes[i].Protocol == protocol && l.Sizes[i].Curve == curveName {
+			return &l.Sizes[i], nil
+		}
+	}
+	return deriveSizeLimit(protocol, curveName, curve, l), nil
+}
+
+func (l *Limits) check() error {
+	if l.MaxPublicInputs <= 0 {
+		return newError(ErrBadRequest, "maxPublicInputs must be positive")
+	}
+	if l.MaxCommitments < 0 {
+		return newError(ErrBadRequest, "maxCommitments must not be negative")
+	}
+	if slices.Contains(l.GoverningMSPs, "") {
+		return newError(ErrBadRequest, "governing msp id must not be empty")
+	}
+	seen := map[string]bool{}
+	for i := range l.Sizes {
+		size := &l.Sizes[i]
+		if size.Protocol != protocolGroth16 && size.Protocol != protocolPlonk {
+			return newError(ErrUnknownProtocol, "unknown protocol %s", describeInput([]byte(size.Protocol)))
+		}
+		curveName, _, err := parseCurve(size.Curve)
+		if err != nil {
+			return err
+		}
+		size.Curve = curveName
+		if size.MaxVKBytes <= 0 || size.MaxProofBytes <= 0 || size.MaxWitnessBytes <= 0 {
+			return newError(ErrBadRequest, "size limits of %s on %s must be positive", size.Protocol, size.Curve)
+		}
+		if seen[size.Protocol+"/"+size.Curve] {
+			return newError(ErrBadRequest, "duplicate size limits of %s on %s", size.Protocol, size.Curve)
+		}
+		seen[size.Protocol+"/"+size.Curve] = true
+	}
+	return nil
+}
+
+// checkEncodedSize 在解码前由 base64 编码长度和填充字符算出解码后的字节数并检查, 空字符串不检查
+func checkEncodedSize(name, str string, maxBytes int) error {
+	size := base64.StdEncoding.DecodedLen(len(str))
+	if len(str)%4 == 0 {
+		size -= len(str) - len(strings.TrimSuffix(strings.TrimSuffix(str, "="), "="))
+	}
+	if size > maxBytes {
+		return newError(ErrInputTooLarge, "%s is %d bytes, at most %d allowed", name, size, maxBytes)
+	}
+	return nil
+}
+
+// checkPublicInputCount 只解码公开见证的头部, 检查公开输入个数
+func checkPublicInputCount(witnessStr string, maxPublicInputs int) error {
+	prefix := base64.StdEncoding.EncodedLen(witnessHeaderBytes)
+	if len(witnessStr) < prefix {
+		return nil
+	}
+	header, err := base64.StdEncoding.DecodeString(witnessStr[:prefix])
+	if err != nil {
+		return nil
+	}
+	if nbPublic := binary.BigEndian.Uint32(header); nbPublic > uint32(maxPublicInputs) {
+		return newError(ErrInputTooLarge, "public witness has %d inputs, at most %d allowed", nbPublic, maxPublicInputs)
+	}
+	return nil
+}
+
+// checkInputsJSONSize 在解析前按字符数和分隔符个数检查 json 数组形式的公开输入
+func checkInputsJSONSize(str, curveName string, maxPublicInputs int) error {
+	_, curve, err := parseCurve(curveName)
+	if err != nil {
+		return err
+	}
+	if maxChars := 2 + maxPublicInputs*jsonInputChars(curve); len(str) > maxChars {
+		return newError(ErrInputTooLarge, "public inputs are %d characters, at most %d allowed", len(str), maxChars)
+	}
+	if count := strings.Count(str, ",") + 1; count > maxPublicInputs {
+		return newError(ErrInputTooLarge, "public inputs have %d elements, at most %d allowed", count, maxPublicInputs)
+	}
+	return nil
+}
+
+// checkInputSizes 在解码前检查验证密钥、证明和公开见证的大小, 为空的输入跳过
+func checkInputSizes(ctx contractapi.TransactionContextInterface, protocol, curveName, vkStr, proofStr, witnessStr string) error {
+	limits, err := readLimits(ctx)
+	if err != nil {
+		return err
+	}
+	return limits.checkInputs(protocol, curveName, vkStr, proofStr, witnessStr)
+}
+
+func (l *Limits) checkInputs(protocol, curveName, vkStr, proofStr, witnessStr string) error {
+	size, err := l.sizeLimit(protocol, curveName)
+	if err != nil {
+		return err
+	}
+	if err := checkEncodedSize("vk", vkStr, size.MaxVKBytes); err != nil {
+		return err
+	}
+	if err := checkEncodedSize("proof", proofStr, size.MaxProofBytes); err != nil {
+		return err
+	}
+	if isPublicInputsJSON(witnessStr) {
+		return checkInputsJSONSize(witnessStr, curveName, l.MaxPublicInputs)
+	}
+	if err := checkEncodedSize("publicWitness", witnessStr, size.MaxWitnessBytes); err != nil {
+		return err
+	}
+	return checkPublicInputCount(witnessStr, l.MaxPublicInputs)
+}
+
+// checkVKPublicInputs 登记或使用验证密钥时检查其公开输入个数
+func checkVKPublicInputs(ctx contractapi.TransactionContextInterface, nbPublic int) error {
+	limits, err := readLimits(ctx)
+	if err != nil {
+		return err
+	}
+	if nbPublic > limits.MaxPublicInputs {
+		return newError(ErrInputTooLarge, "verifying key has %d public inputs, at most %d allowed", nbPublic, limits.MaxPublicInputs)
+	}
+	return nil
+}
+
+// isAdmin 调用者证书的组织单元中包含 admin 时视为管理员
+func isAdmin(ctx contractapi.TransactionContextInterface) (bool, error) {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return false, fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	return cert != nil && slices.Contains(cert.Subject.OrganizationalUnit, adminOU), nil
+}
+
+// GetLimits 查询当前的输入大小限制
+func (c *GnarkVerifyContract) GetLimits(ctx contractapi.TransactionContextInterface) (*Limits, error) {
+	return readLimits(ctx)
+}
+
+// GetSizeLimit 查询某个协议和曲线上生效的输入大小上限
+func (c *GnarkVerifyContract) GetSizeLimit(ctx contractapi.TransactionContextInterface, protocol string, curveName string) (*SizeLimit, error) {
+	limits, err := readLimits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return limits.sizeLimit(protocol, curveName)
+}
+
+// SetLimits 由当前治理组织的管理员修改输入大小限制, 已登记的验证密钥不受影响.
+// 其他组织的管理员只能管理本组织的验证密钥和成员组, 不能修改全通道共用的限制
+func (c *GnarkVerifyContract) SetLimits(ctx contractapi.TransactionContextInterface, limitsJSON string) error {
+	current, err := readLimits(ctx)
+	if err != nil {
+		return err
+	}
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if !admin || !slices.Contains(current.GoverningMSPs, mspID) {
+		return newError(ErrPermissionDenied, "only admins of %s can set limits", strings.Join(current.GoverningMSPs, ", "))
+	}
+	var limits Limits
+	if err := json.Unmarshal([]byte(limitsJSON), &limits); err != nil {
+		return newError(ErrBadEncoding, "failed to unmarshal limits (%s): %v", describeInput([]byte(limitsJSON)), err)
+	}
+	if len(limits.GoverningMSPs) == 0 {
+		limits.GoverningMSPs = current.GoverningMSPs
+	}
+	if err := limits.check(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(limits)
+	if err != nil {
+		return err
+	}
+	key, err := limitsKey(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
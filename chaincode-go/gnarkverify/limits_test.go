@@ -0,0 +1,219 @@
+package gnarkverify
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark/frontend"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+// withIdentity 替换交易上下文的调用者身份, ou 为证书的组织单元
+func withIdentity(transactionContext *mocks.TransactionContext, mspID string, ou ...string) {
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetMSPIDReturns(mspID, nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{OrganizationalUnit: ou}}, nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+}
+
+// twoInputs 含两个公开输入的电路, 用于检查验证密钥的公开输入个数限制
+type twoInputs struct {
+	A, B frontend.Variable `gnark:",public"`
+}
+
+func (c *twoInputs) Define(api frontend.API) error {
+	api.AssertIsEqual(c.A, c.B)
+	return nil
+}
+
+func decodedLen(t *testing.T, str string) int {
+	data, err := base64.StdEncoding.DecodeString(str)
+	require.NoError(t, err)
+	return len(data)
+}
+
+func TestDefaultSizeLimits(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	limits, err := gnarkVerify.GetLimits(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, defaultLimits(), limits)
+	for _, curve := range supportedCurves {
+		for _, protocol := range []string{protocolGroth16, protocolPlonk} {
+			size, err := gnarkVerify.GetSizeLimit(transactionContext, protocol, curve.name)
+			require.NoError(t, err)
+			require.Equal(t, curve.name, size.Curve)
+			require.Greater(t, size.MaxVKBytes, baseSizes[protocol+"/"+curve.name][0])
+			require.Greater(t, size.MaxProofBytes, baseSizes[protocol+"/"+curve.name][1])
+		}
+	}
+	_, err = gnarkVerify.GetSizeLimit(transactionContext, "marlin", "BN254")
+	require.ErrorContains(t, err, "unknown protocol")
+
+	// 默认上限能容纳带承诺的电路 (range 使用 std/rangecheck)
+	for _, protocol := range []string{protocolGroth16, protocolPlonk} {
+		definition, err := gvcircuits.Lookup("range")
+		require.NoError(t, err)
+		curve := supportedCurves[0]
+		example, err := definition.Example(curve.id)
+		require.NoError(t, err)
+		shape, assignment, err := definition.Decode(curve.id, example)
+		require.NoError(t, err)
+		p, err := prover.Compile(protocol, curve.name, "range", shape)
+		require.NoError(t, err)
+		require.NoError(t, p.Setup())
+		artifact, err := p.ProveAssignment(assignment)
+		require.NoError(t, err)
+
+		size, err := gnarkVerify.GetSizeLimit(transactionContext, protocol, curve.name)
+		require.NoError(t, err)
+		require.LessOrEqual(t, decodedLen(t, artifact.VK), size.MaxVKBytes)
+		require.LessOrEqual(t, decodedLen(t, artifact.Proof), size.MaxProofBytes)
+		require.LessOrEqual(t, decodedLen(t, artifact.WitnessPublic), size.MaxWitnessBytes)
+		require.NoError(t, checkInputSizes(transactionContext, protocol, curve.name, artifact.VK, artifact.Proof, artifact.WitnessPublic))
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	limitsJSON := `{"maxPublicInputs":8,"maxCommitments":1,"sizes":[{"protocol":"groth16","curve":"bn128","maxVkBytes":1000,"maxProofBytes":200,"maxWitnessBytes":100}]}`
+
+	// 默认 mock 身份没有证书, 普通成员也不能修改
+	require.ErrorContains(t, gnarkVerify.SetLimits(transactionContext, limitsJSON), "only admins")
+	withIdentity(transactionContext, "Org1MSP", "client")
+	require.ErrorContains(t, gnarkVerify.SetLimits(transactionContext, limitsJSON), "only admins")
+	// 其他组织的管理员不能修改全通道的限制
+	withIdentity(transactionContext, "Org2MSP", "admin")
+	err := gnarkVerify.SetLimits(transactionContext, limitsJSON)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.ErrorContains(t, err, "only admins of Org1MSP")
+
+	withIdentity(transactionContext, "Org1MSP", "admin")
+	require.NoError(t, gnarkVerify.SetLimits(transactionContext, limitsJSON))
+	limits, err := gnarkVerify.GetLimits(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, 8, limits.MaxPublicInputs)
+	require.Equal(t, "BN254", limits.Sizes[0].Curve)
+	require.Equal(t, []string{"Org1MSP"}, limits.GoverningMSPs)
+
+	size, err := gnarkVerify.GetSizeLimit(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	require.Equal(t, 200, size.MaxProofBytes)
+	size, err = gnarkVerify.GetSizeLimit(transactionContext, "plonk", "BN254")
+	require.NoError(t, err)
+	require.Equal(t, witnessHeaderBytes+8*32, size.MaxWitnessBytes)
+
+	for _, invalid := range []string{
+		"{",
+		`{"maxPublicInputs":0}`,
+		`{"maxPublicInputs":1,"maxCommitments":-1}`,
+		`{"maxPublicInputs":1,"governingMsps":[""]}`,
+		`{"maxPublicInputs":1,"sizes":[{"protocol":"marlin","curve":"BN254","maxVkBytes":1,"maxProofBytes":1,"maxWitnessBytes":1}]}`,
+		`{"maxPublicInputs":1,"sizes":[{"protocol":"plonk","curve":"BN256","maxVkBytes":1,"maxProofBytes":1,"maxWitnessBytes":1}]}`,
+		`{"maxPublicInputs":1,"sizes":[{"protocol":"plonk","curve":"BN254","maxVkBytes":1,"maxProofBytes":0,"maxWitnessBytes":1}]}`,
+		`{"maxPublicInputs":1,"sizes":[{"protocol":"plonk","curve":"BN254","maxVkBytes":1,"maxProofBytes":1,"maxWitnessBytes":1},{"protocol":"plonk","curve":"bn254","maxVkBytes":1,"maxProofBytes":1,"maxWitnessBytes":1}]}`,
+	} {
+		require.Error(t, gnarkVerify.SetLimits(transactionContext, invalid), invalid)
+	}
+
+	// 治理组织可以移交修改权限
+	require.NoError(t, gnarkVerify.SetLimits(transactionContext, `{"maxPublicInputs":8,"governingMsps":["Org2MSP"]}`))
+	require.ErrorContains(t, gnarkVerify.SetLimits(transactionContext, limitsJSON), "only admins of Org2MSP")
+	withIdentity(transactionContext, "Org2MSP", "admin")
+	require.NoError(t, gnarkVerify.SetLimits(transactionContext, limitsJSON))
+	limits, err = gnarkVerify.GetLimits(transactionContext)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Org2MSP"}, limits.GoverningMSPs)
+}
+
+func TestInputSizeLimits(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+
+	// 超长输入在解码前被拒绝, 即使不是合法的 base64
+	huge := strings.Repeat("!", 1<<20)
+	_, err := gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", huge, params.VK, params.WitnessPublic)
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	require.Equal(t, "INPUT_TOO_LARGE", ErrorCode(err))
+	require.Less(t, len(err.Error()), 200)
+	_, err = gnarkVerify.VerifyPlonkProof(transactionContext, "BN254", params.Proof, huge, params.WitnessPublic)
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	require.ErrorIs(t, registerVerifyingKey(transactionContext, "huge", "groth16", "BN254", huge, ""), ErrInputTooLarge)
+	_, err = gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", params.VK, marshalBatchItems(t, []BatchItem{
+		{Proof: params.Proof, WitnessPublic: params.WitnessPublic},
+		{Proof: params.Proof, WitnessPublic: huge},
+	}))
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	require.ErrorContains(t, err, "item 1")
+
+	// 收紧限制后, 原本合法的证明也被拒绝
+	withIdentity(transactionContext, "Org1MSP", "admin")
+	limits, err := json.Marshal(Limits{
+		MaxPublicInputs: 1,
+		Sizes: []SizeLimit{{
+			Protocol:        protocolGroth16,
+			Curve:           "BN254",
+			MaxVKBytes:      decodedLen(t, params.VK),
+			MaxProofBytes:   decodedLen(t, params.Proof) - 1,
+			MaxWitnessBytes: decodedLen(t, params.WitnessPublic),
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, gnarkVerify.SetLimits(transactionContext, string(limits)))
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", params.Proof, params.VK, params.WitnessPublic)
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	require.ErrorContains(t, err, "proof is")
+
+	// 直接携带的验证密钥与登记一样受公开输入个数限制
+	require.NoError(t, gnarkVerify.SetLimits(transactionContext, `{"maxPublicInputs":1,"maxCommitments":1}`))
+	for _, protocol := range []string{protocolGroth16, protocolPlonk} {
+		p, err := prover.Compile(protocol, "BN254", "two", &twoInputs{})
+		require.NoError(t, err)
+		require.NoError(t, p.Setup())
+		artifact, err := p.ProveAssignment(&twoInputs{A: 1, B: 1})
+		require.NoError(t, err)
+		verifyProof := gnarkVerify.VerifyGroth16Proof
+		if protocol == protocolPlonk {
+			verifyProof = gnarkVerify.VerifyPlonkProof
+		}
+		_, err = verifyProof(transactionContext, "BN254", params.Proof, artifact.VK, params.WitnessPublic)
+		require.ErrorIs(t, err, ErrInputTooLarge, protocol)
+		require.ErrorContains(t, err, "verifying key has 2 public inputs")
+		_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+			Protocol:      protocol,
+			Curve:         "BN254",
+			VK:            artifact.VK,
+			Proof:         params.Proof,
+			WitnessPublic: params.WitnessPublic,
+		}))
+		require.ErrorIs(t, err, ErrInputTooLarge, protocol)
+		if protocol == protocolGroth16 {
+			_, err = gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", artifact.VK, marshalBatchItems(t, []BatchItem{
+				{Proof: params.Proof, WitnessPublic: params.WitnessPublic},
+			}))
+			require.ErrorIs(t, err, ErrInputTooLarge)
+		}
+	}
+
+	// 公开输入个数只读取见证头部即可判断
+	plonkProof := plonkParams(t, "BN254")
+	_, err = gnarkVerify.VerifyPlonkProof(transactionContext, "BN254", plonkProof.Proof, plonkProof.VK, twoInputWitness(t))
+	require.ErrorIs(t, err, ErrInputTooLarge)
+	require.ErrorContains(t, checkPublicInputCount(twoInputWitness(t), 1), "2 inputs")
+	require.NoError(t, checkPublicInputCount(twoInputWitness(t), 2))
+	require.ErrorIs(t, checkEncodedSize("proof", "AAA=", 1), ErrInputTooLarge)
+	require.NoError(t, checkEncodedSize("proof", "AAA=", 2))
+	require.NoError(t, checkEncodedSize("proof", "AA==", 1))
+	msg, err := gnarkVerify.VerifyPlonkProof(transactionContext, "BN254", plonkProof.Proof, plonkProof.VK, plonkProof.WitnessPublic)
+	require.NoError(t, err, msg)
+}
@@ -0,0 +1,392 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+)
+
+const (
+	merkleTreeObjectType = "merkle"
+	merkleLeafObjectType = "merkle~leaf"
+	merkleRootObjectType = "merkle~root"
+	// merkleKnownObjectType 根到其对应叶子个数的索引, 用于 IsKnownRoot
+	merkleKnownObjectType = "merkle~known"
+
+	// maxRootHistory 保留的历史根个数上限
+	maxRootHistory = 1024
+	// maxLeavesPerQuery GetLeaves 一次返回的叶子个数上限
+	maxLeavesPerQuery = 1024
+)
+
+// MerkleTree 链上只追加的 MiMC Merkle 树, 与 circuits.MerkleProof 的约定一致: 内部节点为 MiMC(left, right),
+// 空位补 0. Frontier[i] 为第 i 层最近一个左侧节点, 追加叶子只需 O(depth) 次哈希
+type MerkleTree struct {
+	ID          string   `json:"id"`
+	Curve       string   `json:"curve"`
+	Depth       int      `json:"depth"`
+	HistorySize int      `json:"historySize"`
+	Size        uint64   `json:"size"`
+	Root        string   `json:"root"`
+	Frontier    []string `json:"frontier"`
+	Owner       string   `json:"owner"`
+}
+
+func merkleTreeKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(merkleTreeObjectType, []string{id})
+}
+
+// merkleIndex 定长的十进制下标, 使组合键按下标排序
+func merkleIndex(index uint64) string {
+	return fmt.Sprintf("%010d", index)
+}
+
+// zeroHashes 返回各层空子树的根, zeros[0] 为 0
+func zeroHashes(curve ecc.ID, depth int) ([]*big.Int, error) {
+	zeros := make([]*big.Int, depth+1)
+	zeros[0] = new(big.Int)
+	for i := 0; i < depth; i++ {
+		var err error
+		if zeros[i+1], err = gvcircuits.MiMC(curve, zeros[i], zeros[i]); err != nil {
+			return nil, err
+		}
+	}
+	return zeros, nil
+}
+
+func readMerkleTree(ctx contractapi.TransactionContextInterface, id string) (*MerkleTree, error) {
+	key, err := merkleTreeKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merkle tree %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("merkle tree %s does not exist", id)
+	}
+	var tree MerkleTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merkle tree %s: %v", id, err)
+	}
+	return &tree, nil
+}
+
+func putMerkleTree(ctx contractapi.TransactionContextInterface, tree *MerkleTree) error {
+	key, err := merkleTreeKey(ctx, tree.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// putRoot 记录当前叶子个数对应的根, 并删除超出历史长度的旧根. written 为本交易已写入的根,
+// 交易内读不到自己的写入, 旧根在其中时不从世界状态读取
+func putRoot(ctx contractapi.TransactionContextInterface, tree *MerkleTree, written map[uint64]string) error {
+	return putRootHistory(ctx, merkleTreeObjectType, tree.ID, tree.Size, tree.Root, tree.HistorySize, written)
+}
+
+// putRootHistory 在 prefix~root 下按序号 seq 记录 id 的根, 在 prefix~known 下记录根到序号的索引,
+// 并删除序号早于 seq - historySize 的旧根
+func putRootHistory(ctx contractapi.TransactionContextInterface, prefix string, id string, seq uint64, root string, historySize int, written map[uint64]string) error {
+	stub := ctx.GetStub()
+	rootType, knownType := prefix+"~root", prefix+"~known"
+	rootKey, err := stub.CreateCompositeKey(rootType, []string{id, merkleIndex(seq)})
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(rootKey, []byte(root)); err != nil {
+		return err
+	}
+	written[seq] = root
+	knownKey, err := stub.CreateCompositeKey(knownType, []string{id, root})
+	if err != nil {
+		return err
+	}
+	if err := stub.PutState(knownKey, []byte(strconv.FormatUint(seq, 10))); err != nil {
+		return err
+	}
+	if seq < uint64(historySize) {
+		return nil
+	}
+	expired := seq - uint64(historySize)
+	expiredKey, err := stub.CreateCompositeKey(rootType, []string{id, merkleIndex(expired)})
+	if err != nil {
+		return err
+	}
+	expiredRoot, ok := written[expired]
+	if !ok {
+		data, err := stub.GetState(expiredKey)
+		if err != nil {
+			return fmt.Errorf("failed to read root history of %s: %v", id, err)
+		}
+		if data == nil {
+			return nil
+		}
+		expiredRoot = string(data)
+	}
+	if err := stub.DelState(expiredKey); err != nil {
+		return err
+	}
+	knownKey, err = stub.CreateCompositeKey(knownType, []string{id, expiredRoot})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(knownKey)
+}
+
+// hasKnownRoot 判断 root 是否在 prefix~known 记录的 id 的最近历史中, root 须为十进制
+func hasKnownRoot(ctx contractapi.TransactionContextInterface, prefix string, id string, root string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(prefix+"~known", []string{id, root})
+	if err != nil {
+		return false, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read root history of %s: %v", id, err)
+	}
+	return data != nil, nil
+}
+
+// CreateMerkleTree 创建深度为 depth 的空树, 保留最近 historySize 个根. 只有创建者组织可以调用 AppendLeaf
+func (c *GnarkVerifyContract) CreateMerkleTree(ctx contractapi.TransactionContextInterface, id string, curveName string, depth int, historySize int) error {
+	owner, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	return createMerkleTree(ctx, id, curveName, depth, historySize, owner)
+}
+
+// createMerkleTree 创建空树, owner 为空时任何组织都不能调用 AppendLeaf, 只能由其他合约追加叶子
+func createMerkleTree(ctx contractapi.TransactionContextInterface, id string, curveName string, depth int, historySize int, owner string) error {
+	if id == "" {
+		return fmt.Errorf("merkle tree id must not be empty")
+	}
+	key, err := merkleTreeKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read merkle tree %s: %v", id, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("merkle tree %s already exists", id)
+	}
+	curveName, curve, err := parseCurve(curveName)
+	if err != nil {
+		return err
+	}
+	if depth < 1 || depth > gvcircuits.MaxMerkleDepth {
+		return fmt.Errorf("merkle tree depth %d out of range [1, %d]", depth, gvcircuits.MaxMerkleDepth)
+	}
+	if historySize < 1 || historySize > maxRootHistory {
+		return fmt.Errorf("root history size %d out of range [1, %d]", historySize, maxRootHistory)
+	}
+	zeros, err := zeroHashes(curve, depth)
+	if err != nil {
+		return err
+	}
+	tree := &MerkleTree{
+		ID:          id,
+		Curve:       curveName,
+		Depth:       depth,
+		HistorySize: historySize,
+		Root:        zeros[depth].String(),
+		Frontier:    make([]string, depth),
+		Owner:       owner,
+	}
+	for i := range tree.Frontier {
+		tree.Frontier[i] = zeros[i].String()
+	}
+	if err := putRoot(ctx, tree, map[uint64]string{}); err != nil {
+		return err
+	}
+	return putMerkleTree(ctx, tree)
+}
+
+// appendLeaves 依次追加叶子并返回第一个叶子的下标. 同一交易中读不到本交易的写入,
+// 因此同一交易内的多个叶子须一次追加
+func appendLeaves(ctx contractapi.TransactionContextInterface, id string, leaves []string) (uint64, error) {
+	tree, err := readMerkleTree(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	_, curve, err := parseCurve(tree.Curve)
+	if err != nil {
+		return 0, err
+	}
+	if tree.Size+uint64(len(leaves)) > 1<<tree.Depth {
+		return 0, fmt.Errorf("merkle tree %s is full", id)
+	}
+	zeros, err := zeroHashes(curve, tree.Depth)
+	if err != nil {
+		return 0, err
+	}
+	frontier := make([]*big.Int, tree.Depth)
+	for i, node := range tree.Frontier {
+		frontier[i], _ = new(big.Int).SetString(node, 10)
+	}
+	first := tree.Size
+	written := map[uint64]string{}
+	for _, leafStr := range leaves {
+		leaf, err := parseLeaf(curve, leafStr)
+		if err != nil {
+			return 0, err
+		}
+		leafKey, err := ctx.GetStub().CreateCompositeKey(merkleLeafObjectType, []string{id, merkleIndex(tree.Size)})
+		if err != nil {
+			return 0, err
+		}
+		if err := ctx.GetStub().PutState(leafKey, []byte(leaf.String())); err != nil {
+			return 0, err
+		}
+
+		node := leaf
+		for level := 0; level < tree.Depth; level++ {
+			if tree.Size>>level&1 == 0 {
+				frontier[level] = node
+				node, err = gvcircuits.MiMC(curve, node, zeros[level])
+			} else {
+				node, err = gvcircuits.MiMC(curve, frontier[level], node)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		tree.Size++
+		tree.Root = node.String()
+		if err := putRoot(ctx, tree, written); err != nil {
+			return 0, err
+		}
+	}
+	for i, node := range frontier {
+		tree.Frontier[i] = node.String()
+	}
+	return first, putMerkleTree(ctx, tree)
+}
+
+// parseLeaf 解析叶子, 须为非零的域元素, 0 表示空位
+func parseLeaf(curve ecc.ID, leafStr string) (*big.Int, error) {
+	decimal, err := normalizeFieldElement(leafStr)
+	if err != nil {
+		return nil, err
+	}
+	leaf, _ := new(big.Int).SetString(decimal, 10)
+	if leaf.Sign() == 0 {
+		return nil, fmt.Errorf("merkle leaf must not be zero")
+	}
+	if leaf.Cmp(curve.ScalarField()) >= 0 {
+		return nil, fmt.Errorf("merkle leaf is not in the scalar field of %s", curve)
+	}
+	return leaf, nil
+}
+
+// AppendLeaf 由树的创建者组织追加叶子, 返回叶子下标
+func (c *GnarkVerifyContract) AppendLeaf(ctx contractapi.TransactionContextInterface, id string, leaf string) (uint64, error) {
+	tree, err := readMerkleTree(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != tree.Owner {
+		return 0, fmt.Errorf("only %s can append to merkle tree %s", tree.Owner, id)
+	}
+	return appendLeaves(ctx, id, []string{leaf})
+}
+
+// GetMerkleTree 查询树的深度、叶子个数、当前根和 frontier
+func (c *GnarkVerifyContract) GetMerkleTree(ctx contractapi.TransactionContextInterface, id string) (*MerkleTree, error) {
+	return readMerkleTree(ctx, id)
+}
+
+// GetRoot 查询当前根, 十进制
+func (c *GnarkVerifyContract) GetRoot(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	tree, err := readMerkleTree(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return tree.Root, nil
+}
+
+// GetRootHistory 查询最近的 n 个根, 最新的在前, 最多返回 historySize 个
+func (c *GnarkVerifyContract) GetRootHistory(ctx contractapi.TransactionContextInterface, id string, n int) ([]string, error) {
+	tree, err := readMerkleTree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("root count %d must not be negative", n)
+	}
+	// 叶子个数为 0 时的空树根也计入历史
+	if available := min(tree.Size+1, uint64(tree.HistorySize)); uint64(n) > available {
+		n = int(available)
+	}
+	roots := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		key, err := ctx.GetStub().CreateCompositeKey(merkleRootObjectType, []string{id, merkleIndex(tree.Size - uint64(i))})
+		if err != nil {
+			return nil, err
+		}
+		root, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root of merkle tree %s: %v", id, err)
+		}
+		roots = append(roots, string(root))
+	}
+	return roots, nil
+}
+
+// isKnownRoot 判断 root 是否为最近 historySize 个根之一
+func isKnownRoot(ctx contractapi.TransactionContextInterface, id string, root string) (bool, error) {
+	if _, err := readMerkleTree(ctx, id); err != nil {
+		return false, err
+	}
+	normalized, err := normalizeFieldElement(root)
+	if err != nil {
+		return false, err
+	}
+	return hasKnownRoot(ctx, merkleTreeObjectType, id, normalized)
+}
+
+// IsKnownRoot 查询 root 是否为最近 historySize 个根之一, 证明可以引用其中任意一个
+func (c *GnarkVerifyContract) IsKnownRoot(ctx contractapi.TransactionContextInterface, id string, root string) (bool, error) {
+	return isKnownRoot(ctx, id, root)
+}
+
+// GetLeaves 按下标查询从 start 开始的至多 count 个叶子, 用于在链下构造成员证明的路径
+func (c *GnarkVerifyContract) GetLeaves(ctx contractapi.TransactionContextInterface, id string, start uint64, count int) ([]string, error) {
+	tree, err := readMerkleTree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 || count > maxLeavesPerQuery {
+		return nil, fmt.Errorf("leaf count %d out of range [0, %d]", count, maxLeavesPerQuery)
+	}
+	leaves := []string{}
+	for index := start; index < tree.Size && len(leaves) < count; index++ {
+		key, err := ctx.GetStub().CreateCompositeKey(merkleLeafObjectType, []string{id, merkleIndex(index)})
+		if err != nil {
+			return nil, err
+		}
+		leaf, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read leaf of merkle tree %s: %v", id, err)
+		}
+		leaves = append(leaves, string(leaf))
+	}
+	return leaves, nil
+}
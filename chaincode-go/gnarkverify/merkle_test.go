@@ -0,0 +1,152 @@
+package gnarkverify
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleTree(t *testing.T) {
+	transactionContext, _, ledger := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	require.Error(t, gnarkVerify.CreateMerkleTree(transactionContext, "tree", "BN254", 0, 3))
+	require.Error(t, gnarkVerify.CreateMerkleTree(transactionContext, "tree", "BN254", 33, 3))
+	require.Error(t, gnarkVerify.CreateMerkleTree(transactionContext, "tree", "BN254", 3, 0))
+	require.NoError(t, gnarkVerify.CreateMerkleTree(transactionContext, "tree", "BN254", 3, 3))
+	require.ErrorContains(t, gnarkVerify.CreateMerkleTree(transactionContext, "tree", "BN254", 3, 3), "already exists")
+
+	// 每次追加后的根与 circuits.MerkleProof 在链下计算的一致
+	var leaves []*big.Int
+	var roots []string
+	root := func() string {
+		if len(leaves) == 0 {
+			zeros, err := zeroHashes(ecc.BN254, 3)
+			require.NoError(t, err)
+			return zeros[3].String()
+		}
+		_, root, err := gvcircuits.MerkleProof(ecc.BN254, leaves, 3, 0)
+		require.NoError(t, err)
+		return root.String()
+	}
+	roots = append(roots, root())
+	for i := 1; i <= 3; i++ {
+		index, err := gnarkVerify.AppendLeaf(transactionContext, "tree", strconv.Itoa(i))
+		require.NoError(t, err)
+		require.Equal(t, uint64(i-1), index)
+		leaves = append(leaves, big.NewInt(int64(i)))
+		roots = append(roots, root())
+		current, err := gnarkVerify.GetRoot(transactionContext, "tree")
+		require.NoError(t, err)
+		require.Equal(t, roots[i], current)
+	}
+	// 一个交易中追加多个叶子, 超出历史长度的根在同一交易中删除
+	index, err := appendLeaves(transactionContext, "tree", []string{"4", "0x5", "6", "7"})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), index)
+	for i := 4; i <= 7; i++ {
+		leaves = append(leaves, big.NewInt(int64(i)))
+		roots = append(roots, root())
+	}
+	tree, err := gnarkVerify.GetMerkleTree(transactionContext, "tree")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), tree.Size)
+	require.Equal(t, roots[7], tree.Root)
+
+	history, err := gnarkVerify.GetRootHistory(transactionContext, "tree", 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{roots[7], roots[6], roots[5]}, history)
+	history, err = gnarkVerify.GetRootHistory(transactionContext, "tree", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{roots[7]}, history)
+	for i, r := range roots {
+		known, err := gnarkVerify.IsKnownRoot(transactionContext, "tree", r)
+		require.NoError(t, err)
+		require.Equal(t, i >= 5, known, i)
+	}
+	// 旧根从世界状态中删除, 只保留 historySize 个根
+	var rootKeys int
+	for key := range ledger.state {
+		objectType, _, err := splitCompositeKey(key)
+		require.NoError(t, err)
+		if objectType == merkleRootObjectType || objectType == merkleKnownObjectType {
+			rootKeys++
+		}
+	}
+	require.Equal(t, 6, rootKeys)
+
+	stored, err := gnarkVerify.GetLeaves(transactionContext, "tree", 2, 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"3", "4", "5"}, stored)
+	stored, err = gnarkVerify.GetLeaves(transactionContext, "tree", 6, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"7"}, stored)
+
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "tree", "0")
+	require.ErrorContains(t, err, "must not be zero")
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "tree", ecc.BN254.ScalarField().String())
+	require.ErrorContains(t, err, "scalar field")
+	_, err = appendLeaves(transactionContext, "tree", []string{"8", "9"})
+	require.ErrorContains(t, err, "is full")
+	otherContext, _, _ := newMockContext("Org2MSP")
+	otherContext.GetStubReturns(transactionContext.GetStub())
+	_, err = gnarkVerify.AppendLeaf(otherContext, "tree", "8")
+	require.ErrorContains(t, err, "only Org1MSP")
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "tree", "8")
+	require.NoError(t, err)
+}
+
+func TestMerkleRootBinding(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	// 示例赋值为深度 8 的树中 100..109 的第 5 个叶子
+	artifact := proveRegistered(t, "groth16", "merkle", "")
+	require.NoError(t, registerVerifyingKey(transactionContext, "member", "groth16", "BN254", artifact.VK, ""))
+	binding := `{"bindings":[{"input":"0","kind":"merkleRoot","key":"members"}]}`
+	require.ErrorContains(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "member", binding), "does not exist")
+	require.NoError(t, gnarkVerify.CreateMerkleTree(transactionContext, "other", "BLS12-381", 8, 2))
+	require.ErrorContains(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "member", `{"bindings":[{"input":"0","kind":"merkleRoot","key":"other"}]}`), "curve")
+	require.NoError(t, gnarkVerify.CreateMerkleTree(transactionContext, "members", "BN254", 8, 2))
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "member", binding))
+
+	verify := func(txID string) *VerifyResponse {
+		chaincodeStub.GetTxIDReturns(txID)
+		response, err := gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+			VKID:          "member",
+			Proof:         artifact.Proof,
+			WitnessPublic: artifact.WitnessPublic,
+		}))
+		require.NoError(t, err)
+		return response
+	}
+	var leaves []string
+	for i := 100; i < 110; i++ {
+		leaves = append(leaves, strconv.Itoa(i))
+	}
+	_, err := appendLeaves(transactionContext, "members", leaves[:9])
+	require.NoError(t, err)
+	response := verify("tx1")
+	require.False(t, response.Valid)
+	require.Equal(t, "BINDING_MISMATCH", response.ErrorCode)
+
+	_, err = appendLeaves(transactionContext, "members", leaves[9:])
+	require.NoError(t, err)
+	response = verify("tx2")
+	require.True(t, response.Valid, response.Reason)
+
+	// 其他证明者追加叶子后, 引用的根仍在历史中
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "members", "110")
+	require.NoError(t, err)
+	response = verify("tx3")
+	require.True(t, response.Valid, response.Reason)
+	_, err = gnarkVerify.AppendLeaf(transactionContext, "members", "111")
+	require.NoError(t, err)
+	response = verify("tx4")
+	require.False(t, response.Valid)
+	require.Equal(t, "BINDING_MISMATCH", response.ErrorCode)
+}
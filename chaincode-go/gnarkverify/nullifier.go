@@ -0,0 +1,96 @@
+package gnarkverify
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const nullifierObjectType = "nullifier"
+
+// NullifierConfig 指定作为 nullifier 的公开输入下标
+type NullifierConfig struct {
+	Index int `json:"index"`
+}
+
+// checkNullifierConfig nullifier 配置一经设置不可修改, 避免已消费的 nullifier 失效
+func checkNullifierConfig(record *VerifyingKeyRecord, config *NullifierConfig) error {
+	current := record.Config.Nullifier
+	if current != nil && (config == nil || *config != *current) {
+		return newError(ErrBadRequest, "nullifier config of verifying key %s cannot be changed", record.ID)
+	}
+	if config != nil && (config.Index < 0 || config.Index >= record.NbPublic) {
+		return newError(ErrBadRequest, "nullifier index %d out of range [0, %d)", config.Index, record.NbPublic)
+	}
+	return nil
+}
+
+// normalizeFieldElement 将十进制或 0x 前缀十六进制的整数统一为十进制字符串
+func normalizeFieldElement(str string) (string, error) {
+	value := new(big.Int)
+	var ok bool
+	if hexStr, isHex := strings.CutPrefix(strings.ToLower(str), "0x"); isHex {
+		_, ok = value.SetString(hexStr, 16)
+	} else {
+		_, ok = value.SetString(str, 10)
+	}
+	if !ok || value.Sign() < 0 {
+		return "", newError(ErrBadEncoding, "invalid field element (%s)", describeInput([]byte(str)))
+	}
+	return value.String(), nil
+}
+
+func nullifierKey(ctx contractapi.TransactionContextInterface, scope, nullifier string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(nullifierObjectType, []string{scope, nullifier})
+}
+
+func isNullifierSpent(ctx contractapi.TransactionContextInterface, scope, nullifier string) (bool, error) {
+	key, err := nullifierKey(ctx, scope, nullifier)
+	if err != nil {
+		return false, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read nullifier: %v", err)
+	}
+	return data != nil, nil
+}
+
+// spendNullifier 在 scope 下登记 nullifier, 已存在时拒绝
+func spendNullifier(ctx contractapi.TransactionContextInterface, scope, nullifier string) error {
+	spent, err := isNullifierSpent(ctx, scope, nullifier)
+	if err != nil {
+		return err
+	}
+	if spent {
+		return newError(ErrNullifierSpent, "nullifier %s already spent", nullifier)
+	}
+	key, err := nullifierKey(ctx, scope, nullifier)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(ctx.GetStub().GetTxID()))
+}
+
+// spendConfiguredNullifier 按验证密钥配置消费公开输入中的 nullifier
+func spendConfiguredNullifier(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, inputs []string) error {
+	config := record.Config.Nullifier
+	if config == nil {
+		return nil
+	}
+	if config.Index >= len(inputs) {
+		return newError(ErrWitnessMismatch, "nullifier index %d out of range, public witness has %d inputs", config.Index, len(inputs))
+	}
+	return spendNullifier(ctx, record.ID, inputs[config.Index])
+}
+
+// IsNullifierSpent 查询 nullifier 是否已在该验证密钥下被消费
+func (c *GnarkVerifyContract) IsNullifierSpent(ctx contractapi.TransactionContextInterface, vkID string, nullifier string) (bool, error) {
+	normalized, err := normalizeFieldElement(nullifier)
+	if err != nil {
+		return false, err
+	}
+	return isNullifierSpent(ctx, vkID, normalized)
+}
@@ -0,0 +1,142 @@
+package gnarkverify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullifier(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, ""))
+
+	err := gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"nullifier":{"index":1}}`)
+	require.ErrorContains(t, err, "out of range")
+
+	// 只有登记者可以修改配置
+	otherIdentity := &mocks.ClientIdentity{}
+	otherIdentity.GetMSPIDReturns("Org2MSP", nil)
+	transactionContext.GetClientIdentityReturns(otherIdentity)
+	err = gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"nullifier":{"index":0}}`)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.ErrorContains(t, err, "only Org1MSP")
+
+	ownerIdentity := &mocks.ClientIdentity{}
+	ownerIdentity.GetMSPIDReturns("Org1MSP", nil)
+	transactionContext.GetClientIdentityReturns(ownerIdentity)
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"nullifier":{"index":0}}`))
+	err = gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{}`)
+	require.ErrorContains(t, err, "cannot be changed")
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.NoError(t, err)
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	nullifier := record.PublicInputs[0]
+
+	spent, err := gnarkVerify.IsNullifierSpent(transactionContext, "product", nullifier)
+	require.NoError(t, err)
+	require.True(t, spent)
+	value, _ := new(big.Int).SetString(nullifier, 10)
+	spent, err = gnarkVerify.IsNullifierSpent(transactionContext, "product", fmt.Sprintf("0x%x", value))
+	require.NoError(t, err)
+	require.True(t, spent)
+	spent, err = gnarkVerify.IsNullifierSpent(transactionContext, "product", "0")
+	require.NoError(t, err)
+	require.False(t, spent)
+	_, err = gnarkVerify.IsNullifierSpent(transactionContext, "product", "abc")
+	require.ErrorIs(t, err, ErrBadEncoding)
+	require.ErrorContains(t, err, "invalid field element")
+	// 错误信息中不回显原始输入
+	_, err = gnarkVerify.IsNullifierSpent(transactionContext, "product", strings.Repeat("z", 1000))
+	require.ErrorIs(t, err, ErrBadEncoding)
+	require.Less(t, len(err.Error()), 200)
+
+	// 重放同一证明被拒绝, 且不产生验证记录
+	chaincodeStub.GetTxIDReturns("tx2")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.ErrorContains(t, err, "already spent")
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx2")
+	require.ErrorContains(t, err, "does not exist")
+}
+
+func TestNullifierInlineKey(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+
+	// 登记时一并设置配置, 不存在未检查 nullifier 的窗口
+	require.ErrorContains(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, `{"nullifier":{"index":1}}`), "out of range")
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, `{"nullifier":{"index":0}}`))
+	record, err := gnarkVerify.GetVerifyingKey(transactionContext, "product")
+	require.NoError(t, err)
+	require.Equal(t, &NullifierConfig{Index: 0}, record.Config.Nullifier)
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.NoError(t, err)
+
+	// 直接携带同一密钥重放证明会跳过 nullifier 检查, 因此被拒绝, 且不产生验证记录
+	chaincodeStub.GetTxIDReturns("tx2")
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", params.Proof, params.VK, params.WitnessPublic)
+	require.ErrorIs(t, err, ErrKeyRegistered)
+	require.Equal(t, "VK_REGISTERED", ErrorCode(err))
+	_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		Protocol:      "groth16",
+		Curve:         "BN254",
+		VK:            params.VK,
+		Proof:         params.Proof,
+		WitnessPublic: params.WitnessPublic,
+	}))
+	require.ErrorIs(t, err, ErrKeyRegistered)
+	_, err = gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", params.VK, marshalBatchItems(t, []BatchItem{{Proof: params.Proof, WitnessPublic: params.WitnessPublic}}))
+	require.ErrorIs(t, err, ErrKeyRegistered)
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx2")
+	require.ErrorContains(t, err, "does not exist")
+
+	// 未压缩编码的同一密钥哈希相同, 同样被拒绝
+	rawVK := rawVerifyingKey(t, params.VK)
+	require.NotEqual(t, params.VK, rawVK)
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", params.Proof, rawVK, params.WitnessPublic)
+	require.ErrorIs(t, err, ErrKeyRegistered)
+	_, err = gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		Protocol:      "groth16",
+		Curve:         "BN254",
+		VK:            rawVK,
+		Proof:         params.Proof,
+		WitnessPublic: params.WitnessPublic,
+	}))
+	require.ErrorIs(t, err, ErrKeyRegistered)
+	_, err = gnarkVerify.VerifyGroth16Batch(transactionContext, "BN254", rawVK, marshalBatchItems(t, []BatchItem{{Proof: params.Proof, WitnessPublic: params.WitnessPublic}}))
+	require.ErrorIs(t, err, ErrKeyRegistered)
+
+	// 未配置检查的已登记密钥仍可直接携带
+	other := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "other", "groth16", "BN254", other.VK, `{"schema":[{"name":"product","type":"uint64"}]}`))
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", other.Proof, other.VK, other.WitnessPublic)
+	require.NoError(t, err)
+}
+
+// rawVerifyingKey 将 base64 编码的 BN254 groth16 验证密钥改写为未压缩的点编码
+func rawVerifyingKey(t *testing.T, vkStr string) string {
+	data, err := base64.StdEncoding.DecodeString(vkStr)
+	require.NoError(t, err)
+	vk := groth16.NewVerifyingKey(ecc.BN254)
+	_, err = vk.ReadFrom(bytes.NewReader(data))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = vk.WriteRawTo(&buf)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
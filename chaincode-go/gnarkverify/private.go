@@ -0,0 +1,123 @@
+package gnarkverify
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const privateInputsObjectType = "inputs"
+
+// PrivateInputsRecord 写入私有数据集合的公开输入. 通道账本上的验证记录只保留 WitnessHash,
+// 即以 Salt (十六进制) 为密钥的公开见证 HMAC, 集合成员可以用 Salt 核对公开输入
+type PrivateInputsRecord struct {
+	TxID         string            `json:"txId"`
+	VKID         string            `json:"vkId"`
+	PublicInputs []string          `json:"publicInputs"`
+	Inputs       map[string]string `json:"inputs,omitempty"`
+	WitnessHash  string            `json:"witnessHash"`
+	Salt         string            `json:"salt"`
+}
+
+// checkPrivateCollection 检查集合在链码的集合配置中存在
+func checkPrivateCollection(ctx contractapi.TransactionContextInterface, collection string) error {
+	if collection == "" {
+		return nil
+	}
+	if _, err := ctx.GetStub().GetPrivateDataHash(collection, privateInputsObjectType); err != nil {
+		return fmt.Errorf("failed to access private collection %s: %v", collection, err)
+	}
+	return nil
+}
+
+func privateInputsKey(ctx contractapi.TransactionContextInterface, txID string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(privateInputsObjectType, []string{txID})
+}
+
+// putPrivateInputs 将公开输入和 salt 写入私有数据集合, 并在验证记录中只保留哈希
+func putPrivateInputs(ctx contractapi.TransactionContextInterface, collection string, record *VerificationRecord, witnessHash string, salt []byte) error {
+	private := PrivateInputsRecord{
+		TxID:         record.TxID,
+		VKID:         record.VKID,
+		PublicInputs: record.PublicInputs,
+		Inputs:       record.Inputs,
+		WitnessHash:  witnessHash,
+		Salt:         hex.EncodeToString(salt),
+	}
+	data, err := json.Marshal(private)
+	if err != nil {
+		return err
+	}
+	key, err := privateInputsKey(ctx, record.TxID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutPrivateData(collection, key, data); err != nil {
+		return fmt.Errorf("failed to put private inputs into %s: %v", collection, err)
+	}
+	record.hideInputs(witnessHash)
+	record.PrivateCollection = collection
+	return nil
+}
+
+// GetPrivateInputs 按交易 ID 读取验证记录对应的私有公开输入. 集合开启 memberOnlyRead 时,
+// 只有集合成员组织的客户端能读取, 且只能在成员节点上查询
+func (c *GnarkVerifyContract) GetPrivateInputs(ctx contractapi.TransactionContextInterface, txID string) (*PrivateInputsRecord, error) {
+	record, err := c.GetVerificationRecord(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if record.PrivateCollection == "" {
+		return nil, fmt.Errorf("verification record %s has no private inputs", txID)
+	}
+	key, err := privateInputsKey(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetPrivateData(record.PrivateCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private inputs from %s: %v", record.PrivateCollection, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("private inputs of %s are not available on this peer", txID)
+	}
+	var private PrivateInputsRecord
+	if err := json.Unmarshal(data, &private); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal private inputs of %s: %v", txID, err)
+	}
+	if err := checkPrivateInputs(record, &private); err != nil {
+		return nil, err
+	}
+	return &private, nil
+}
+
+// checkPrivateInputs 用私有数据中的 salt 重新计算公开见证哈希, 须与通道账本上的哈希一致
+func checkPrivateInputs(record *VerificationRecord, private *PrivateInputsRecord) error {
+	mismatch := fmt.Errorf("private inputs of %s do not match the hash on the ledger", record.TxID)
+	_, curve, err := parseCurve(record.Curve)
+	if err != nil {
+		return err
+	}
+	salt, err := hex.DecodeString(private.Salt)
+	if err != nil || len(salt) < minSaltBytes {
+		return mismatch
+	}
+	inputs, err := json.Marshal(private.PublicInputs)
+	if err != nil {
+		return err
+	}
+	publicWitness, err := witnessFromInputs(string(inputs), curve)
+	if err != nil {
+		return mismatch
+	}
+	hash, err := witnessHash(publicWitness, salt)
+	if err != nil {
+		return err
+	}
+	if hash != record.PublicInputsHash || private.WitnessHash != record.PublicInputsHash {
+		return mismatch
+	}
+	return nil
+}
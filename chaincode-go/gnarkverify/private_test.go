@@ -0,0 +1,114 @@
+package gnarkverify
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateInputs(t *testing.T) {
+	transactionContext, chaincodeStub, ledger := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	artifacts := proveProduct(t, "groth16", "BN254", [2]int{1000003, 1000033}, [2]int{1000037, 1000039})
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", artifacts[0].VK, ""))
+
+	chaincodeStub.GetPrivateDataHashReturns(nil, errors.New("collection missing could not be found"))
+	require.ErrorContains(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"privateCollection":"missing"}`), "failed to access private collection")
+	chaincodeStub.GetPrivateDataHashReturns(nil, nil)
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"privateCollection":"gnarkverifyInputs"}`))
+
+	// 交易参数中的公开输入已写入区块, 不能再放入私有数据集合
+	puts := chaincodeStub.PutStateCallCount()
+	_, err := gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		VKID:          "product",
+		Proof:         artifacts[0].Proof,
+		WitnessPublic: artifacts[0].WitnessPublic,
+	}))
+	require.ErrorIs(t, err, ErrInputsNotPrivate)
+	require.Equal(t, "INPUTS_NOT_PRIVATE", ErrorCode(err))
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", artifacts[0].Proof, artifacts[0].WitnessPublic)
+	require.ErrorIs(t, err, ErrInputsNotPrivate)
+	require.Equal(t, puts, chaincodeStub.PutStateCallCount())
+	require.Zero(t, chaincodeStub.PutPrivateDataCallCount())
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	chaincodeStub.GetTransientReturns(transientMap(t, artifacts[0], "product"), nil)
+	response, err := gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	require.True(t, response.Valid)
+	require.Empty(t, response.PublicInputs)
+	witnessBytes, err := base64.StdEncoding.DecodeString(artifacts[0].WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, saltedHash(testSalt, witnessBytes), response.PublicInputsHash)
+
+	// 通道账本和事件中只有哈希
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Empty(t, record.PublicInputs)
+	require.Equal(t, response.PublicInputsHash, record.PublicInputsHash)
+	require.Equal(t, "gnarkverifyInputs", record.PrivateCollection)
+	var event ProofVerifiedEvent
+	_, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Empty(t, event.PublicInputs)
+	for key, value := range ledger.state {
+		require.NotContains(t, key+string(value), "1000036000099")
+	}
+
+	private, err := gnarkVerify.GetPrivateInputs(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, "tx1", private.TxID)
+	require.Equal(t, "product", private.VKID)
+	require.Equal(t, []string{"1000036000099"}, private.PublicInputs)
+	require.Equal(t, record.PublicInputsHash, private.WitnessHash)
+	require.Equal(t, hex.EncodeToString(testSalt), private.Salt)
+
+	// 非集合成员的节点上没有私有数据
+	delete(ledger.private["gnarkverifyInputs"], mustPrivateInputsKey(t, "tx1"))
+	_, err = gnarkVerify.GetPrivateInputs(transactionContext, "tx1")
+	require.ErrorContains(t, err, "not available on this peer")
+
+	// 私有数据与账本上的哈希不一致
+	chaincodeStub.GetTxIDReturns("tx2")
+	chaincodeStub.GetTransientReturns(transientMap(t, artifacts[1], "product"), nil)
+	_, err = gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	key := mustPrivateInputsKey(t, "tx2")
+	tampered := ledger.private["gnarkverifyInputs"][key]
+	ledger.private["gnarkverifyInputs"][key] = []byte(`{"txId":"tx2","publicInputs":["1"],"witnessHash":"00"}`)
+	_, err = gnarkVerify.GetPrivateInputs(transactionContext, "tx2")
+	require.ErrorContains(t, err, "do not match")
+	// 改动公开输入后, 即使保留原有的哈希和 salt 也与账本不一致
+	var forged PrivateInputsRecord
+	require.NoError(t, json.Unmarshal(tampered, &forged))
+	forged.PublicInputs = []string{"1"}
+	forgedJSON, err := json.Marshal(forged)
+	require.NoError(t, err)
+	ledger.private["gnarkverifyInputs"][key] = forgedJSON
+	_, err = gnarkVerify.GetPrivateInputs(transactionContext, "tx2")
+	require.ErrorContains(t, err, "do not match")
+	ledger.private["gnarkverifyInputs"][key] = tampered
+
+	// 直接携带已登记的密钥也不能绕过私有数据集合
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", artifacts[1].Proof, artifacts[1].VK, artifacts[1].WitnessPublic)
+	require.ErrorIs(t, err, ErrKeyRegistered)
+
+	// 未配置私有数据集合的验证记录
+	chaincodeStub.GetTxIDReturns("tx3")
+	other := groth16Params(t, "BN254")
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", other.Proof, other.VK, other.WitnessPublic)
+	require.NoError(t, err)
+	_, err = gnarkVerify.GetPrivateInputs(transactionContext, "tx3")
+	require.ErrorContains(t, err, "has no private inputs")
+}
+
+func mustPrivateInputsKey(t *testing.T, txID string) string {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	key, err := privateInputsKey(transactionContext, txID)
+	require.NoError(t, err)
+	return key
+}
@@ -0,0 +1,195 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	recordObjectType      = "record"
+	recordProofObjectType = "record~proof"
+)
+
+// VerificationRecord 验证成功后写入世界状态的记录
+type VerificationRecord struct {
+	TxID         string   `json:"txId"`
+	VKID         string   `json:"vkId,omitempty"`
+	Protocol     string   `json:"protocol"`
+	Curve        string   `json:"curve"`
+	VKHash       string   `json:"vkHash"`
+	ProofHash    string   `json:"proofHash"`
+	PublicInputs []string `json:"publicInputs,omitempty"`
+	// Inputs 验证密钥配置了 schema 时按名称解码的公开输入
+	Inputs map[string]string `json:"inputs,omitempty"`
+	// PublicInputsHash 经 transient 提交时只记录以 salt 为密钥的公开见证 HMAC, 不记录公开输入
+	PublicInputsHash string `json:"publicInputsHash,omitempty"`
+	// PrivateCollection 公开输入写入的私有数据集合, 可用 GetPrivateInputs 读取
+	PrivateCollection string `json:"privateCollection,omitempty"`
+	// RecordID 批量验证的记录 ID <txID>#<index>, 同一交易的多个记录以此区分; 单个验证的记录以交易 ID 为 ID
+	RecordID  string `json:"recordId,omitempty"`
+	Creator   string `json:"creator"`
+	Timestamp string `json:"timestamp"`
+}
+
+// ID 记录的 ID, 单个验证为交易 ID, 批量验证为 <txID>#<index>
+func (r *VerificationRecord) ID() string {
+	if r.RecordID == "" {
+		return r.TxID
+	}
+	return r.RecordID
+}
+
+// verification 一次成功验证的摘要, 用于生成记录
+type verification struct {
+	vkID          string
+	protocol      string
+	curveName     string
+	vkHash        string
+	proofStr      string
+	publicWitness witness.Witness
+}
+
+// txTime 返回交易时间戳, 各背书节点一致
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get tx timestamp: %v", err)
+	}
+	return ts.AsTime().UTC(), nil
+}
+
+func newVerificationRecord(ctx contractapi.TransactionContextInterface, v *verification) (*VerificationRecord, error) {
+	proofBytes, err := decodeBase64("proof", v.proofStr)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := publicInputs(v.publicWitness)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationRecord{
+		TxID:         ctx.GetStub().GetTxID(),
+		VKID:         v.vkID,
+		Protocol:     v.protocol,
+		Curve:        v.curveName,
+		VKHash:       v.vkHash,
+		ProofHash:    hashHex(proofBytes),
+		PublicInputs: inputs,
+		Creator:      creator,
+		Timestamp:    ts.Format(time.RFC3339Nano),
+	}, nil
+}
+
+// hideInputs 以加盐的公开见证哈希代替公开输入, 使其不写入区块
+func (r *VerificationRecord) hideInputs(witnessHash string) {
+	r.PublicInputs = nil
+	r.Inputs = nil
+	r.PublicInputsHash = witnessHash
+}
+
+func putVerificationRecord(ctx contractapi.TransactionContextInterface, record *VerificationRecord) error {
+	if err := putRecord(ctx, record); err != nil {
+		return err
+	}
+	return indexProofHash(ctx, record)
+}
+
+func putRecord(ctx contractapi.TransactionContextInterface, record *VerificationRecord) error {
+	key, err := ctx.GetStub().CreateCompositeKey(recordObjectType, []string{record.ID()})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, data); err != nil {
+		return fmt.Errorf("failed to put verification record: %v", err)
+	}
+	return nil
+}
+
+// indexProofHash 证明哈希索引只指向第一次验证该证明的记录
+func indexProofHash(ctx contractapi.TransactionContextInterface, record *VerificationRecord) error {
+	stub := ctx.GetStub()
+	indexKey, err := stub.CreateCompositeKey(recordProofObjectType, []string{record.ProofHash})
+	if err != nil {
+		return err
+	}
+	existing, err := stub.GetState(indexKey)
+	if err != nil {
+		return fmt.Errorf("failed to read proof hash index: %v", err)
+	}
+	if existing != nil {
+		return nil
+	}
+	return stub.PutState(indexKey, []byte(record.ID()))
+}
+
+// commitVerification 写入验证记录并发出 ProofVerified 事件
+func commitVerification(ctx contractapi.TransactionContextInterface, record *VerificationRecord) error {
+	if err := putVerificationRecord(ctx, record); err != nil {
+		return err
+	}
+	return emitProofVerified(ctx, record)
+}
+
+// recordVerification 将成功的验证写入世界状态
+func recordVerification(ctx contractapi.TransactionContextInterface, v *verification) (*VerificationRecord, error) {
+	record, err := newVerificationRecord(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	if err := commitVerification(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetVerificationRecord 按记录 ID 查询验证记录, 单个验证为交易 ID, 批量验证为 <txID>#<index>
+func (c *GnarkVerifyContract) GetVerificationRecord(ctx contractapi.TransactionContextInterface, txID string) (*VerificationRecord, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(recordObjectType, []string{txID})
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verification record %s: %v", txID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("verification record %s does not exist", txID)
+	}
+	var record VerificationRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal verification record %s: %v", txID, err)
+	}
+	return &record, nil
+}
+
+// GetVerificationRecordByProofHash 按证明哈希查询首次验证该证明的记录
+func (c *GnarkVerifyContract) GetVerificationRecordByProofHash(ctx contractapi.TransactionContextInterface, proofHash string) (*VerificationRecord, error) {
+	indexKey, err := ctx.GetStub().CreateCompositeKey(recordProofObjectType, []string{proofHash})
+	if err != nil {
+		return nil, err
+	}
+	txID, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proof hash index: %v", err)
+	}
+	if txID == nil {
+		return nil, fmt.Errorf("no verification record for proof hash %s", proofHash)
+	}
+	return c.GetVerificationRecord(ctx, string(txID))
+}
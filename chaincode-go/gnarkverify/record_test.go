@@ -0,0 +1,53 @@
+package gnarkverify
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerificationRecord(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, ""))
+	vkRecord, err := gnarkVerify.GetVerifyingKey(transactionContext, "product")
+	require.NoError(t, err)
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.NoError(t, err)
+
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	proofBytes, err := base64.StdEncoding.DecodeString(params.Proof)
+	require.NoError(t, err)
+	require.Equal(t, "tx1", record.TxID)
+	require.Equal(t, "groth16", record.Protocol)
+	require.Equal(t, "BN254", record.Curve)
+	require.Equal(t, vkRecord.VKHash, record.VKHash)
+	require.Equal(t, hashHex(proofBytes), record.ProofHash)
+	require.Len(t, record.PublicInputs, 1)
+	require.Equal(t, "Org1MSP", record.Creator)
+	require.Equal(t, "2025-08-08T12:00:00Z", record.Timestamp)
+
+	// 重复提交同一证明, 哈希索引仍指向首次验证的交易
+	chaincodeStub.GetTxIDReturns("tx2")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", params.Proof, params.WitnessPublic)
+	require.NoError(t, err)
+	byHash, err := gnarkVerify.GetVerificationRecordByProofHash(transactionContext, record.ProofHash)
+	require.NoError(t, err)
+	require.Equal(t, "tx1", byHash.TxID)
+
+	// 验证失败不写记录
+	other := groth16Params(t, "BN254")
+	chaincodeStub.GetTxIDReturns("tx3")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", other.Proof, other.WitnessPublic)
+	require.Error(t, err)
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx3")
+	require.ErrorContains(t, err, "does not exist")
+
+	_, err = gnarkVerify.GetVerificationRecordByProofHash(transactionContext, "00")
+	require.ErrorContains(t, err, "no verification record")
+}
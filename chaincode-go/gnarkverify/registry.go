@@ -0,0 +1,372 @@
+package gnarkverify
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	protocolGroth16 = "groth16"
+	protocolPlonk   = "plonk"
+
+	vkObjectType     = "vk"
+	vkHashObjectType = "vk~hash"
+)
+
+// VerifyingKeyRecord 链上登记的验证密钥
+type VerifyingKeyRecord struct {
+	ID       string             `json:"id"`
+	Protocol string             `json:"protocol"`
+	Curve    string             `json:"curve"`
+	VK       string             `json:"vk"`
+	VKHash   string             `json:"vkHash"`
+	NbPublic int                `json:"nbPublic"`
+	Owner    string             `json:"owner"`
+	Config   VerifyingKeyConfig `json:"config"`
+}
+
+// VerifyingKeyConfig 验证密钥的附加配置, 由登记者设置
+type VerifyingKeyConfig struct {
+	Nullifier *NullifierConfig `json:"nullifier,omitempty"`
+	// PrivateCollection 设置后, 验证通过的公开输入写入该私有数据集合, 通道账本上只保留哈希
+	PrivateCollection string `json:"privateCollection,omitempty"`
+	// Schema 公开输入的名称和类型, 设置后验证结果、记录和事件按名称给出公开输入
+	Schema []InputSchema `json:"schema,omitempty"`
+	// Bindings 要求部分公开输入等于验证时的世界状态值或交易所在的时间段
+	Bindings []InputBinding `json:"bindings,omitempty"`
+}
+
+// enforced 配置中是否有直接携带验证密钥的验证路径不会执行的检查
+func (c *VerifyingKeyConfig) enforced() bool {
+	return c.Nullifier != nil || c.PrivateCollection != "" || len(c.Bindings) > 0
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// vkHashOf 验证密钥按 WriteTo 重新编码后的 sha256 十六进制哈希. 解码器同时接受压缩和未压缩的点编码,
+// 按提交的原始字节计算哈希时, 同一密钥换一种编码就能得到不同的哈希
+func vkHashOf(vk io.WriterTo) (string, error) {
+	var buf bytes.Buffer
+	if _, err := vk.WriteTo(&buf); err != nil {
+		return "", newError(ErrMalformedVK, "failed to encode verifying key: %v", err)
+	}
+	return hashHex(buf.Bytes()), nil
+}
+
+// checkVerifyingKey 按协议解析验证密钥, 返回规范编码的哈希和公开输入个数
+func checkVerifyingKey(protocol, curveName, vkStr string) (string, int, error) {
+	_, curve, err := parseCurve(curveName)
+	if err != nil {
+		return "", 0, err
+	}
+	var vk io.WriterTo
+	switch protocol {
+	case protocolGroth16:
+		if vk, err = readGroth16VK(vkStr, curve); err != nil {
+			return "", 0, err
+		}
+	case protocolPlonk:
+		if vk, err = readPlonkVK(vkStr, curve); err != nil {
+			return "", 0, err
+		}
+	default:
+		return "", 0, newError(ErrUnknownProtocol, "unknown protocol %.64q", protocol)
+	}
+	nbPublic, err := nbPublicWitness(vk)
+	if err != nil {
+		return "", 0, err
+	}
+	vkHash, err := vkHashOf(vk)
+	if err != nil {
+		return "", 0, err
+	}
+	return vkHash, nbPublic, nil
+}
+
+func vkKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(vkObjectType, []string{id})
+}
+
+func readVerifyingKeyRecord(ctx contractapi.TransactionContextInterface, id string) (*VerifyingKeyRecord, error) {
+	key, err := vkKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verifying key %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, newError(ErrKeyNotFound, "verifying key %.64q does not exist", id)
+	}
+	var record VerifyingKeyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal verifying key %s: %v", id, err)
+	}
+	return &record, nil
+}
+
+// RegisterVerifyingKey 由组织管理员校验并登记验证密钥, 调用者的组织成为所有者, 同一 id 只能登记一次.
+// configJSON 为空时不带附加配置, 需要 nullifier 或绑定的密钥应在登记时一并给出配置,
+// 之后用 ConfigureVerifyingKey 设置会留下未检查的窗口
+func (c *GnarkVerifyContract) RegisterVerifyingKey(ctx contractapi.TransactionContextInterface, id string, protocol string, curveName string, vkStr string, configJSON string) error {
+	admin, err := isAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return newError(ErrPermissionDenied, "only admins can register verifying keys")
+	}
+	if id == "" {
+		return newError(ErrBadRequest, "verifying key id must not be empty")
+	}
+	key, err := vkKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read verifying key %s: %v", id, err)
+	}
+	if existing != nil {
+		return newError(ErrKeyExists, "verifying key %s already exists", id)
+	}
+
+	curveName, _, err = parseCurve(curveName)
+	if err != nil {
+		return err
+	}
+	if err := checkInputSizes(ctx, protocol, curveName, vkStr, "", ""); err != nil {
+		return err
+	}
+	vkHash, nbPublic, err := checkVerifyingKey(protocol, curveName, vkStr)
+	if err != nil {
+		return err
+	}
+	if err := checkVKPublicInputs(ctx, nbPublic); err != nil {
+		return err
+	}
+	owner, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+
+	record := VerifyingKeyRecord{
+		ID:       id,
+		Protocol: protocol,
+		Curve:    curveName,
+		VK:       vkStr,
+		VKHash:   vkHash,
+		NbPublic: nbPublic,
+		Owner:    owner,
+	}
+	if configJSON != "" {
+		if err := applyVerifyingKeyConfig(ctx, &record, configJSON); err != nil {
+			return err
+		}
+	}
+	// 按哈希索引登记的密钥, 直接携带同一密钥验证时据此查找其配置
+	hashKey, err := ctx.GetStub().CreateCompositeKey(vkHashObjectType, []string{vkHash, id})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(hashKey, []byte(id)); err != nil {
+		return err
+	}
+	return putVerifyingKeyRecord(ctx, &record)
+}
+
+func putVerifyingKeyRecord(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord) error {
+	key, err := vkKey(ctx, record.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// checkInlineVerifyingKey 直接携带的验证密钥不执行 nullifier、绑定和私有数据集合的检查.
+// 同一密钥已登记且带有这些配置时拒绝, 否则可以绕过登记的密钥重放证明并得到验证记录和事件.
+// 公开输入个数与登记时一样受 MaxPublicInputs 限制
+func checkInlineVerifyingKey(ctx contractapi.TransactionContextInterface, vkHash string, nbPublic int) error {
+	if err := checkVKPublicInputs(ctx, nbPublic); err != nil {
+		return err
+	}
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(vkHashObjectType, []string{vkHash})
+	if err != nil {
+		return fmt.Errorf("failed to read verifying key hash index: %v", err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read verifying key hash index: %v", err)
+		}
+		record, err := readVerifyingKeyRecord(ctx, string(kv.Value))
+		if err != nil {
+			return err
+		}
+		if record.Config.enforced() {
+			return newError(ErrKeyRegistered, "verifying key is registered as %s with nullifier, binding or private collection config, verify by its id", record.ID)
+		}
+	}
+	return nil
+}
+
+// applyVerifyingKeyConfig 校验配置并设置到 record 上, 不写入账本
+func applyVerifyingKeyConfig(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, configJSON string) error {
+	var config VerifyingKeyConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return newError(ErrBadEncoding, "failed to unmarshal verifying key config (%s): %v", describeInput([]byte(configJSON)), err)
+	}
+	if err := checkNullifierConfig(record, config.Nullifier); err != nil {
+		return err
+	}
+	if err := checkPrivateCollection(ctx, config.PrivateCollection); err != nil {
+		return err
+	}
+	if err := checkSchemaConfig(record, config.Schema); err != nil {
+		return err
+	}
+	if err := checkBindingsConfig(ctx, record, &config); err != nil {
+		return err
+	}
+	record.Config = config
+	return nil
+}
+
+// ConfigureVerifyingKey 由登记者更新验证密钥的附加配置
+func (c *GnarkVerifyContract) ConfigureVerifyingKey(ctx contractapi.TransactionContextInterface, id string, configJSON string) error {
+	record, err := readVerifyingKeyRecord(ctx, id)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != record.Owner {
+		return newError(ErrPermissionDenied, "only %s can configure verifying key %s", record.Owner, id)
+	}
+	if err := applyVerifyingKeyConfig(ctx, record, configJSON); err != nil {
+		return err
+	}
+	return putVerifyingKeyRecord(ctx, record)
+}
+
+// GetVerifyingKey 查询已登记的验证密钥
+func (c *GnarkVerifyContract) GetVerifyingKey(ctx contractapi.TransactionContextInterface, id string) (*VerifyingKeyRecord, error) {
+	return readVerifyingKeyRecord(ctx, id)
+}
+
+// verifyWithKey 使用登记的验证密钥验证证明, 成功后消费 nullifier 并写入验证记录
+func verifyWithKey(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, proofStr string, pubWitnessStr string) (string, *VerificationRecord, error) {
+	return verifyAndRecord(ctx, record, proofStr, pubWitnessStr, nil)
+}
+
+// verifyAndRecord 同 verifyWithKey, salt 非空时验证记录和事件中只保留以 salt 为密钥的公开见证哈希.
+// 配置了私有数据集合的密钥只接受带 salt (transient) 的调用, 否则公开输入已随交易参数写入区块
+func verifyAndRecord(ctx contractapi.TransactionContextInterface, record *VerifyingKeyRecord, proofStr string, pubWitnessStr string, salt []byte) (string, *VerificationRecord, error) {
+	hashOnly := len(salt) > 0
+	if record.Config.PrivateCollection != "" && !hashOnly {
+		return "inputs not private", nil, newError(ErrInputsNotPrivate, "verifying key %s keeps public inputs in private collection %s, submit them through VerifyProofTransient", record.ID, record.Config.PrivateCollection)
+	}
+	_, curve, err := parseCurve(record.Curve)
+	if err != nil {
+		return "unknown curve", nil, err
+	}
+	if err := checkInputSizes(ctx, record.Protocol, record.Curve, "", proofStr, pubWitnessStr); err != nil {
+		return "input too large", nil, err
+	}
+
+	var (
+		msg           string
+		publicWitness witness.Witness
+	)
+	switch record.Protocol {
+	case protocolGroth16:
+		vk, err := readGroth16VK(record.VK, curve)
+		if err != nil {
+			return "read groth16 verifyingkey failed", nil, err
+		}
+		msg, publicWitness, err = verifyGroth16(vk, proofStr, pubWitnessStr, curve)
+		if err != nil {
+			return msg, nil, err
+		}
+	case protocolPlonk:
+		vk, err := readPlonkVK(record.VK, curve)
+		if err != nil {
+			return "read plonk verifyingkey failed", nil, err
+		}
+		msg, publicWitness, err = verifyPlonk(vk, proofStr, pubWitnessStr, curve)
+		if err != nil {
+			return msg, nil, err
+		}
+	default:
+		return "unknown protocol", nil, newError(ErrUnknownProtocol, "unknown protocol %.64q", record.Protocol)
+	}
+
+	verificationRecord, err := newVerificationRecord(ctx, &verification{
+		vkID:          record.ID,
+		protocol:      record.Protocol,
+		curveName:     record.Curve,
+		vkHash:        record.VKHash,
+		proofStr:      proofStr,
+		publicWitness: publicWitness,
+	})
+	if err != nil {
+		return "record verification failed", nil, err
+	}
+	if record.Config.Schema != nil {
+		if verificationRecord.Inputs, err = decodeInputs(record.Config.Schema, verificationRecord.PublicInputs); err != nil {
+			return "decode public inputs failed", nil, err
+		}
+	}
+	if err := checkBindings(ctx, record, verificationRecord.PublicInputs); err != nil {
+		return "binding check failed", nil, err
+	}
+	if err := spendConfiguredNullifier(ctx, record, verificationRecord.PublicInputs); err != nil {
+		return "nullifier check failed", nil, err
+	}
+	if hashOnly {
+		hash, err := witnessHash(publicWitness, salt)
+		if err != nil {
+			return "read public witness failed", nil, err
+		}
+		if record.Config.PrivateCollection != "" {
+			err = putPrivateInputs(ctx, record.Config.PrivateCollection, verificationRecord, hash, salt)
+		} else {
+			verificationRecord.hideInputs(hash)
+		}
+		if err != nil {
+			return "record private inputs failed", nil, err
+		}
+	}
+	if err := commitVerification(ctx, verificationRecord); err != nil {
+		return "record verification failed", nil, err
+	}
+	return msg, verificationRecord, nil
+}
+
+// VerifyProofByKeyID 使用已登记的验证密钥验证证明
+func (c *GnarkVerifyContract) VerifyProofByKeyID(ctx contractapi.TransactionContextInterface, id string, proofStr string, pubWitnessStr string) (string, error) {
+	record, err := readVerifyingKeyRecord(ctx, id)
+	if err != nil {
+		return "read verifying key failed", err
+	}
+	msg, _, err := verifyWithKey(ctx, record, proofStr, pubWitnessStr)
+	return msg, err
+}
@@ -0,0 +1,95 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// registerVerifyingKey 以调用者所在组织的管理员身份登记验证密钥, 与调用者共用账本
+func registerVerifyingKey(transactionContext *mocks.TransactionContext, id, protocol, curveName, vkStr, configJSON string) error {
+	mspID, err := transactionContext.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+	adminContext := &mocks.TransactionContext{}
+	adminContext.GetStubReturns(transactionContext.GetStub())
+	withIdentity(adminContext, mspID, adminOU)
+	return (&GnarkVerifyContract{}).RegisterVerifyingKey(adminContext, id, protocol, curveName, vkStr, configJSON)
+}
+
+// schemaConfig 只声明 schema 的验证密钥配置
+func schemaConfig(t *testing.T, schema []InputSchema) string {
+	data, err := json.Marshal(VerifyingKeyConfig{Schema: schema})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRegisterVerifyingKey(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	params := groth16Params(t, "BN254")
+
+	// 普通成员不能登记
+	withIdentity(transactionContext, "Org1MSP", "client")
+	err := gnarkVerify.RegisterVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, "")
+	require.ErrorContains(t, err, "only admins")
+	require.Equal(t, "PERMISSION_DENIED", ErrorCode(err))
+	_, err = gnarkVerify.GetVerifyingKey(transactionContext, "product")
+	require.ErrorContains(t, err, "does not exist")
+
+	withIdentity(transactionContext, "Org1MSP", adminOU)
+	err = gnarkVerify.RegisterVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, "")
+	require.NoError(t, err)
+
+	record, err := gnarkVerify.GetVerifyingKey(transactionContext, "product")
+	require.NoError(t, err)
+	require.Equal(t, "groth16", record.Protocol)
+	require.Equal(t, "BN254", record.Curve)
+	require.Equal(t, "Org1MSP", record.Owner)
+	require.Len(t, record.VKHash, 64)
+
+	err = gnarkVerify.RegisterVerifyingKey(transactionContext, "product", "groth16", "BN254", params.VK, "")
+	require.ErrorIs(t, err, ErrKeyExists)
+	require.ErrorContains(t, err, "already exists")
+
+	err = gnarkVerify.RegisterVerifyingKey(transactionContext, "bad-protocol", "marlin", "BN254", params.VK, "")
+	require.ErrorContains(t, err, "unknown protocol")
+
+	err = gnarkVerify.RegisterVerifyingKey(transactionContext, "bad-curve", "groth16", "bn256", params.VK, "")
+	require.ErrorContains(t, err, "unknown curve")
+
+	err = gnarkVerify.RegisterVerifyingKey(transactionContext, "bad-vk", "plonk", "BN254", params.VK, "")
+	require.Error(t, err)
+
+	_, err = gnarkVerify.GetVerifyingKey(transactionContext, "missing")
+	require.ErrorContains(t, err, "does not exist")
+}
+
+func TestVerifyProofByKeyID(t *testing.T) {
+	transactionContext, _, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+
+	groth16Proof := groth16Params(t, "BN254")
+	require.NoError(t, registerVerifyingKey(transactionContext, "groth16-product", "groth16", "BN254", groth16Proof.VK, ""))
+	plonkProof := plonkParams(t, "BLS12-381")
+	require.NoError(t, registerVerifyingKey(transactionContext, "plonk-product", "plonk", "BLS12-381", plonkProof.VK, ""))
+
+	response, err := gnarkVerify.VerifyProofByKeyID(transactionContext, "groth16-product", groth16Proof.Proof, groth16Proof.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, "verify groth16 proof success", response)
+
+	response, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "plonk-product", plonkProof.Proof, plonkProof.WitnessPublic)
+	require.NoError(t, err)
+	require.Equal(t, "verify plonk proof success", response)
+
+	// 另一组参数生成的证明不能通过已登记的密钥验证
+	other := groth16Params(t, "BN254")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "groth16-product", other.Proof, other.WitnessPublic)
+	require.Error(t, err)
+
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "missing", groth16Proof.Proof, groth16Proof.WitnessPublic)
+	require.ErrorContains(t, err, "does not exist")
+}
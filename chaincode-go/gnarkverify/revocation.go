@@ -0,0 +1,551 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+)
+
+const (
+	revocationObjectType     = "revocation"
+	revocationNodeObjectType = "revocation~node"
+	revokedObjectType        = "revocation~id"
+	holderObjectType         = "revocation~holder"
+	holderLeafObjectType     = "revocation~leaf"
+	replacedObjectType       = "revocation~replaced"
+
+	// nonMembershipNbPublic circuits.NonMembership 的公开输入: Root, Commitment
+	nonMembershipNbPublic = 2
+)
+
+// nonMembershipSchema circuits.NonMembership 公开输入的 schema
+var nonMembershipSchema = []InputSchema{
+	{Name: "root", Type: InputField},
+	{Name: "commitment", Type: InputField},
+}
+
+// RevocationContract 基于稀疏 Merkle 树的吊销登记. 发行方登记持有者的标识承诺和标识对应的叶子, 把吊销的标识插入树中,
+// 持有者用 circuits.NonMembership 电路证明已登记的承诺中的标识不在当前或最近的根对应的树中, 不暴露标识本身.
+// 每个叶子只分配给一个持有者, 否则吊销其中一个标识会使另一个也无法证明
+type RevocationContract struct {
+	contractapi.Contract
+}
+
+// RevocationRegistry 吊销登记, 稀疏 Merkle 树的约定见 circuits.NonMembership. Count 为已吊销的标识个数,
+// 每次吊销产生一个新根, 保留最近 HistorySize 个根; 被替换的根只在替换后 MaxRootAge 秒内有效
+type RevocationRegistry struct {
+	ID          string `json:"id"`
+	Issuer      string `json:"issuer"`
+	VKID        string `json:"vkId"`
+	Curve       string `json:"curve"`
+	Depth       int    `json:"depth"`
+	HistorySize int    `json:"historySize"`
+	MaxRootAge  int64  `json:"maxRootAge"`
+	Count       uint64 `json:"count"`
+	Root        string `json:"root"`
+}
+
+// Revocation 一次吊销
+type Revocation struct {
+	Identifier string `json:"identifier"`
+	Index      uint64 `json:"index"`
+	TxID       string `json:"txId"`
+	Timestamp  string `json:"timestamp"`
+}
+
+func revocationKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(revocationObjectType, []string{id})
+}
+
+func readRevocationRegistry(ctx contractapi.TransactionContextInterface, id string) (*RevocationRegistry, error) {
+	key, err := revocationKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revocation registry %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("revocation registry %s does not exist", id)
+	}
+	var registry RevocationRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal revocation registry %s: %v", id, err)
+	}
+	return &registry, nil
+}
+
+func putRevocationRegistry(ctx contractapi.TransactionContextInterface, registry *RevocationRegistry) error {
+	key, err := revocationKey(ctx, registry.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(registry)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+func revocationNodeKey(ctx contractapi.TransactionContextInterface, id string, level int, index uint64) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(revocationNodeObjectType, []string{id, strconv.Itoa(level), strconv.FormatUint(index, 10)})
+}
+
+// readRevocationNode 读取第 level 层第 index 个节点, 未写入的节点为空子树的根 zero
+func readRevocationNode(ctx contractapi.TransactionContextInterface, id string, level int, index uint64, zero *big.Int) (*big.Int, error) {
+	key, err := revocationNodeKey(ctx, id, level, index)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node of revocation registry %s: %v", id, err)
+	}
+	if data == nil {
+		return zero, nil
+	}
+	node, ok := new(big.Int).SetString(string(data), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid node of revocation registry %s", id)
+	}
+	return node, nil
+}
+
+// parseRegistryElement 解析标识或承诺, 须为标量域中的元素
+func parseRegistryElement(registry *RevocationRegistry, name string, str string) (*big.Int, error) {
+	_, curve, err := parseCurve(registry.Curve)
+	if err != nil {
+		return nil, err
+	}
+	decimal, err := normalizeFieldElement(str)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := new(big.Int).SetString(decimal, 10)
+	if value.Cmp(curve.ScalarField()) >= 0 {
+		return nil, fmt.Errorf("%s is not in the scalar field of %s", name, registry.Curve)
+	}
+	return value, nil
+}
+
+// CreateRevocationRegistry 创建空的吊销登记, 调用者的组织成为发行方. vkID 为登记时声明了 nonMembershipSchema 的
+// circuits.NonMembership 验证密钥, 电路的路径长度须等于 depth; 保留最近 historySize 个根, 被替换的根在 maxRootAge 秒后失效,
+// 为 0 时只接受当前的根
+func (c *RevocationContract) CreateRevocationRegistry(ctx contractapi.TransactionContextInterface, id string, vkID string, depth int, historySize int, maxRootAge int64) error {
+	if id == "" {
+		return fmt.Errorf("revocation registry id must not be empty")
+	}
+	key, err := revocationKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read revocation registry %s: %v", id, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("revocation registry %s already exists", id)
+	}
+	if depth < 1 || depth > gvcircuits.MaxSparseMerkleDepth {
+		return fmt.Errorf("sparse merkle tree depth %d out of range [1, %d]", depth, gvcircuits.MaxSparseMerkleDepth)
+	}
+	if historySize < 1 || historySize > maxRootHistory {
+		return fmt.Errorf("root history size %d out of range [1, %d]", historySize, maxRootHistory)
+	}
+	if maxRootAge < 0 {
+		return fmt.Errorf("max root age must not be negative")
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.NbPublic != nonMembershipNbPublic {
+		return fmt.Errorf("verifying key %s does not have %d public inputs", vkID, nonMembershipNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "nonmember", nonMembershipSchema); err != nil {
+		return err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return err
+	}
+	zeros, err := zeroHashes(curve, depth)
+	if err != nil {
+		return err
+	}
+	issuer, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	registry := &RevocationRegistry{
+		ID:          id,
+		Issuer:      issuer,
+		VKID:        vkID,
+		Curve:       vkRecord.Curve,
+		Depth:       depth,
+		HistorySize: historySize,
+		MaxRootAge:  maxRootAge,
+		Root:        zeros[depth].String(),
+	}
+	if err := putRootHistory(ctx, revocationObjectType, id, 0, registry.Root, historySize, map[uint64]string{}); err != nil {
+		return err
+	}
+	return putRevocationRegistry(ctx, registry)
+}
+
+// GetRevocationRegistry 查询吊销登记
+func (c *RevocationContract) GetRevocationRegistry(ctx contractapi.TransactionContextInterface, id string) (*RevocationRegistry, error) {
+	return readRevocationRegistry(ctx, id)
+}
+
+// Revoke 发行方吊销标识, 把标识对应的叶子置为 1 并更新根, 返回新根. 叶子须已由 RegisterHolder 分配,
+// 否则吊销未登记的标识会占用其他持有者的叶子. 同一交易读不到自己的写入, 每个交易只能吊销一个标识
+func (c *RevocationContract) Revoke(ctx contractapi.TransactionContextInterface, registryID string, identifier string) (string, error) {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return "", err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != registry.Issuer {
+		return "", fmt.Errorf("only %s can revoke in revocation registry %s", registry.Issuer, registryID)
+	}
+	id, err := parseRegistryElement(registry, "identifier", identifier)
+	if err != nil {
+		return "", err
+	}
+	_, curve, err := parseCurve(registry.Curve)
+	if err != nil {
+		return "", err
+	}
+	zeros, err := zeroHashes(curve, registry.Depth)
+	if err != nil {
+		return "", err
+	}
+	index, err := gvcircuits.SparseIndex(curve, id, registry.Depth)
+	if err != nil {
+		return "", err
+	}
+	assigned, err := isAssignedLeaf(ctx, registryID, index)
+	if err != nil {
+		return "", err
+	}
+	if !assigned {
+		return "", fmt.Errorf("leaf %d of revocation registry %s is not assigned to a registered holder", index, registryID)
+	}
+	leaf, err := readRevocationNode(ctx, registryID, 0, index, zeros[0])
+	if err != nil {
+		return "", err
+	}
+	if leaf.Sign() != 0 {
+		return "", fmt.Errorf("leaf %d of revocation registry %s is already revoked", index, registryID)
+	}
+
+	node := big.NewInt(1)
+	position := index
+	for level := 0; level <= registry.Depth; level++ {
+		key, err := revocationNodeKey(ctx, registryID, level, position)
+		if err != nil {
+			return "", err
+		}
+		if err := ctx.GetStub().PutState(key, []byte(node.String())); err != nil {
+			return "", err
+		}
+		if level == registry.Depth {
+			break
+		}
+		sibling, err := readRevocationNode(ctx, registryID, level, position^1, zeros[level])
+		if err != nil {
+			return "", err
+		}
+		if position&1 == 0 {
+			node, err = gvcircuits.MiMC(curve, node, sibling)
+		} else {
+			node, err = gvcircuits.MiMC(curve, sibling, node)
+		}
+		if err != nil {
+			return "", err
+		}
+		position >>= 1
+	}
+
+	ts, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	revokedKey, err := ctx.GetStub().CreateCompositeKey(revokedObjectType, []string{registryID, id.String()})
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(Revocation{
+		Identifier: id.String(),
+		Index:      index,
+		TxID:       ctx.GetStub().GetTxID(),
+		Timestamp:  ts.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(revokedKey, data); err != nil {
+		return "", err
+	}
+	// 记录旧根被替换的时间, 旧根只在此后 MaxRootAge 秒内有效
+	replacedKey, err := ctx.GetStub().CreateCompositeKey(replacedObjectType, []string{registryID, registry.Root})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(replacedKey, []byte(ts.Format(time.RFC3339Nano))); err != nil {
+		return "", err
+	}
+	registry.Count++
+	registry.Root = node.String()
+	if err := putRootHistory(ctx, revocationObjectType, registryID, registry.Count, registry.Root, registry.HistorySize, map[uint64]string{}); err != nil {
+		return "", err
+	}
+	return registry.Root, putRevocationRegistry(ctx, registry)
+}
+
+// RegisterHolder 发行方在签发时登记持有者的标识承诺 MiMC(id, blinding) 和标识对应的叶子下标 index
+// (circuits.SparseIndex). 只接受已登记承诺的非吊销证明, 否则被吊销的持有者可以换一个新的标识重新证明;
+// 叶子已分配给其他持有者时拒绝, 发行方应换一个标识重新签发
+func (c *RevocationContract) RegisterHolder(ctx contractapi.TransactionContextInterface, registryID string, commitment string, index uint64) error {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	if mspID != registry.Issuer {
+		return fmt.Errorf("only %s can register holders in revocation registry %s", registry.Issuer, registryID)
+	}
+	value, err := parseRegistryElement(registry, "commitment", commitment)
+	if err != nil {
+		return err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(holderObjectType, []string{registryID, value.String()})
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read holder of revocation registry %s: %v", registryID, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("commitment is already registered in revocation registry %s", registryID)
+	}
+	if index>>registry.Depth != 0 {
+		return fmt.Errorf("index %d does not fit a tree of depth %d", index, registry.Depth)
+	}
+	assigned, err := isAssignedLeaf(ctx, registryID, index)
+	if err != nil {
+		return err
+	}
+	if assigned {
+		return fmt.Errorf("leaf %d of revocation registry %s is already assigned to another holder", index, registryID)
+	}
+	leafKey, err := holderLeafKey(ctx, registryID, index)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(leafKey, []byte(ctx.GetStub().GetTxID())); err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(ctx.GetStub().GetTxID()))
+}
+
+func holderLeafKey(ctx contractapi.TransactionContextInterface, registryID string, index uint64) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(holderLeafObjectType, []string{registryID, strconv.FormatUint(index, 10)})
+}
+
+// isAssignedLeaf 叶子是否已由 RegisterHolder 分配给持有者
+func isAssignedLeaf(ctx contractapi.TransactionContextInterface, registryID string, index uint64) (bool, error) {
+	key, err := holderLeafKey(ctx, registryID, index)
+	if err != nil {
+		return false, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read holder leaf of revocation registry %s: %v", registryID, err)
+	}
+	return data != nil, nil
+}
+
+// IsRegisteredHolder 查询标识承诺是否已由发行方登记
+func (c *RevocationContract) IsRegisteredHolder(ctx contractapi.TransactionContextInterface, registryID string, commitment string) (bool, error) {
+	if _, err := readRevocationRegistry(ctx, registryID); err != nil {
+		return false, err
+	}
+	normalized, err := normalizeFieldElement(commitment)
+	if err != nil {
+		return false, err
+	}
+	return isRegisteredHolder(ctx, registryID, normalized)
+}
+
+// isRegisteredHolder commitment 须为十进制
+func isRegisteredHolder(ctx contractapi.TransactionContextInterface, registryID string, commitment string) (bool, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(holderObjectType, []string{registryID, commitment})
+	if err != nil {
+		return false, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read holder of revocation registry %s: %v", registryID, err)
+	}
+	return data != nil, nil
+}
+
+// GetRevocationRoot 查询当前根, 十进制
+func (c *RevocationContract) GetRevocationRoot(ctx contractapi.TransactionContextInterface, registryID string) (string, error) {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return "", err
+	}
+	return registry.Root, nil
+}
+
+// IsKnownRevocationRoot 查询 root 是否为当前的根, 或最近 historySize 个根中替换后未超过 maxRootAge 秒的根
+func (c *RevocationContract) IsKnownRevocationRoot(ctx contractapi.TransactionContextInterface, registryID string, root string) (bool, error) {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return false, err
+	}
+	normalized, err := normalizeFieldElement(root)
+	if err != nil {
+		return false, err
+	}
+	return isValidRevocationRoot(ctx, registry, normalized)
+}
+
+// isValidRevocationRoot root 须为十进制. 替换时间按交易时间戳比较, 各背书节点一致
+func isValidRevocationRoot(ctx contractapi.TransactionContextInterface, registry *RevocationRegistry, root string) (bool, error) {
+	if root == registry.Root {
+		return true, nil
+	}
+	known, err := hasKnownRoot(ctx, revocationObjectType, registry.ID, root)
+	if err != nil || !known {
+		return false, err
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(replacedObjectType, []string{registry.ID, root})
+	if err != nil {
+		return false, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read root history of %s: %v", registry.ID, err)
+	}
+	if data == nil {
+		return false, nil
+	}
+	replaced, err := time.Parse(time.RFC3339Nano, string(data))
+	if err != nil {
+		return false, fmt.Errorf("invalid root history of %s: %v", registry.ID, err)
+	}
+	ts, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return ts.Sub(replaced) <= time.Duration(registry.MaxRootAge)*time.Second, nil
+}
+
+// GetRevocations 查询全部吊销记录, 持有者可据此在链下用 circuits.SparseMerkleProof 构造路径
+func (c *RevocationContract) GetRevocations(ctx contractapi.TransactionContextInterface, registryID string) ([]*Revocation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(revokedObjectType, []string{registryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revocations: %v", err)
+	}
+	defer iterator.Close()
+	revocations := []*Revocation{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revocations: %v", err)
+		}
+		var revocation Revocation
+		if err := json.Unmarshal(kv.Value, &revocation); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal revocation: %v", err)
+		}
+		revocations = append(revocations, &revocation)
+	}
+	return revocations, nil
+}
+
+// GetRevocationPath 查询第 index 个叶子在当前树中的兄弟节点. 查询会向节点暴露下标,
+// 不希望暴露时可用 GetRevocations 在链下构造路径
+func (c *RevocationContract) GetRevocationPath(ctx contractapi.TransactionContextInterface, registryID string, index uint64) ([]string, error) {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return nil, err
+	}
+	if index>>registry.Depth != 0 {
+		return nil, fmt.Errorf("index %d does not fit a tree of depth %d", index, registry.Depth)
+	}
+	_, curve, err := parseCurve(registry.Curve)
+	if err != nil {
+		return nil, err
+	}
+	zeros, err := zeroHashes(curve, registry.Depth)
+	if err != nil {
+		return nil, err
+	}
+	path := make([]string, registry.Depth)
+	for level := range path {
+		sibling, err := readRevocationNode(ctx, registryID, level, (index>>level)^1, zeros[level])
+		if err != nil {
+			return nil, err
+		}
+		path[level] = sibling.String()
+	}
+	return path, nil
+}
+
+// VerifyNonRevocation 验证 commitment 承诺的标识未被吊销. commitment 须已由发行方用 RegisterHolder 登记,
+// root 为证明引用的根, 须为当前的根, 或最近 historySize 个根中替换后未超过 maxRootAge 秒的根, 否则返回 BINDING_MISMATCH
+func (c *RevocationContract) VerifyNonRevocation(ctx contractapi.TransactionContextInterface, registryID string, proofStr string, root string, commitment string) error {
+	registry, err := readRevocationRegistry(ctx, registryID)
+	if err != nil {
+		return err
+	}
+	root, err = normalizeFieldElement(root)
+	if err != nil {
+		return err
+	}
+	valid, err := isValidRevocationRoot(ctx, registry, root)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return newError(ErrBindingMismatch, "root is not the current or a recently replaced root of revocation registry %s", registryID)
+	}
+	commitment, err = normalizeFieldElement(commitment)
+	if err != nil {
+		return err
+	}
+	registered, err := isRegisteredHolder(ctx, registryID, commitment)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		return newError(ErrBindingMismatch, "commitment is not registered in revocation registry %s", registryID)
+	}
+	vkRecord, err := readVerifyingKeyRecord(ctx, registry.VKID)
+	if err != nil {
+		return err
+	}
+	witness, err := json.Marshal([]string{root, commitment})
+	if err != nil {
+		return err
+	}
+	_, _, err = verifyAndRecord(ctx, vkRecord, proofStr, string(witness), nil)
+	return err
+}
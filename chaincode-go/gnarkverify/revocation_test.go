@@ -0,0 +1,159 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const revocationDepth = 8
+
+// collidingID 返回与标识 id 共用深度为 revocationDepth 的叶子的最小的其他标识
+func collidingID(t *testing.T, id int64) int64 {
+	index, err := gvcircuits.SparseIndex(ecc.BN254, big.NewInt(id), revocationDepth)
+	require.NoError(t, err)
+	for other := int64(0); ; other++ {
+		i, err := gvcircuits.SparseIndex(ecc.BN254, big.NewInt(other), revocationDepth)
+		require.NoError(t, err)
+		if i == index && other != id {
+			return other
+		}
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	holderContext, _, _ := newMockContext("Org2MSP")
+	holderContext.GetStubReturns(chaincodeStub)
+	revocation := &RevocationContract{}
+
+	curve := ecc.BN254
+	p, err := prover.Compile("groth16", "BN254", "nonmember", gvcircuits.NewNonMembership(revocationDepth))
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	definition, err := gvcircuits.Lookup("nonmember")
+	require.NoError(t, err)
+	// prove 标识 id 在吊销 revoked 之后的树中未被吊销, 返回证明、根和承诺
+	prove := func(id int64, revoked ...int64) (*prover.Artifact, string, string) {
+		data, err := json.Marshal(map[string]any{"id": id, "blinding": 7, "depth": revocationDepth, "revoked": revoked})
+		require.NoError(t, err)
+		_, assignment, err := definition.Decode(curve, data)
+		require.NoError(t, err)
+		artifact, err := p.ProveAssignment(assignment)
+		require.NoError(t, err)
+		nonMembership := assignment.(*gvcircuits.NonMembership)
+		return artifact, nonMembership.Root.(*big.Int).String(), nonMembership.Commitment.(*big.Int).String()
+	}
+	// holder 标识 id 的承诺和叶子下标, 发行方据此登记持有者
+	holder := func(id int64) (string, uint64) {
+		commitment, err := gvcircuits.IdentifierCommitment(curve, big.NewInt(id), big.NewInt(7))
+		require.NoError(t, err)
+		index, err := gvcircuits.SparseIndex(curve, big.NewInt(id), revocationDepth)
+		require.NoError(t, err)
+		return commitment.String(), index
+	}
+
+	empty, emptyRoot, commitment := prove(4660)
+	require.NoError(t, registerVerifyingKey(transactionContext, "unnamed", "groth16", "BN254", empty.VK, ""))
+	require.ErrorContains(t, revocation.CreateRevocationRegistry(transactionContext, "gov", "unnamed", revocationDepth, 2, 600), "nonmember schema")
+	require.NoError(t, registerVerifyingKey(transactionContext, "nonmember", "groth16", "BN254", empty.VK, schemaConfig(t, nonMembershipSchema)))
+	require.ErrorContains(t, revocation.CreateRevocationRegistry(transactionContext, "gov", "nonmember", 65, 2, 600), "out of range")
+	require.NoError(t, revocation.CreateRevocationRegistry(transactionContext, "gov", "nonmember", revocationDepth, 2, 600))
+	require.ErrorContains(t, revocation.CreateRevocationRegistry(transactionContext, "gov", "nonmember", revocationDepth, 2, 600), "already exists")
+	root, err := revocation.GetRevocationRoot(transactionContext, "gov")
+	require.NoError(t, err)
+	require.Equal(t, emptyRoot, root)
+	holderCommitment, index := holder(4660)
+	require.Equal(t, commitment, holderCommitment)
+	require.ErrorContains(t, revocation.RegisterHolder(holderContext, "gov", commitment, index), "only Org1MSP")
+	require.ErrorContains(t, revocation.RegisterHolder(transactionContext, "gov", commitment, 1<<revocationDepth), "does not fit")
+	require.NoError(t, revocation.RegisterHolder(transactionContext, "gov", commitment, index))
+	require.ErrorContains(t, revocation.RegisterHolder(transactionContext, "gov", commitment, index), "already registered")
+	registered, err := revocation.IsRegisteredHolder(holderContext, "gov", commitment)
+	require.NoError(t, err)
+	require.True(t, registered)
+	// 标识的叶子已分配给其他持有者时拒绝登记, 否则吊销其中一个会使另一个无法证明, 或另一个无法被吊销
+	other, otherIndex := holder(collidingID(t, 4660))
+	require.Equal(t, index, otherIndex)
+	require.ErrorContains(t, revocation.RegisterHolder(transactionContext, "gov", other, otherIndex), "already assigned")
+	revokedIndices := []uint64{}
+	for _, id := range []int64{4661, 17, 99} {
+		c, i := holder(id)
+		require.NoError(t, revocation.RegisterHolder(transactionContext, "gov", c, i))
+		revokedIndices = append(revokedIndices, i)
+	}
+
+	_, err = revocation.Revoke(holderContext, "gov", "4661")
+	require.ErrorContains(t, err, "only Org1MSP")
+	// 未分配的叶子不能被吊销, 否则之后分配到该叶子的持有者无法证明
+	_, unassigned := holder(12345)
+	require.NotContains(t, append(revokedIndices, index), unassigned)
+	_, err = revocation.Revoke(transactionContext, "gov", "12345")
+	require.ErrorContains(t, err, "not assigned")
+	_, err = revocation.Revoke(transactionContext, "gov", "4661")
+	require.NoError(t, err)
+	root, err = revocation.Revoke(transactionContext, "gov", "17")
+	require.NoError(t, err)
+	// 链上的根和路径与链下由吊销列表计算的一致
+	path, offChainRoot, err := gvcircuits.SparseMerkleProof(curve, revocationDepth, revokedIndices[:2], index)
+	require.NoError(t, err)
+	require.Equal(t, offChainRoot.String(), root)
+	onChainPath, err := revocation.GetRevocationPath(holderContext, "gov", index)
+	require.NoError(t, err)
+	for i := range path {
+		require.Equal(t, path[i].String(), onChainPath[i])
+	}
+	revocations, err := revocation.GetRevocations(holderContext, "gov")
+	require.NoError(t, err)
+	require.Len(t, revocations, 2)
+
+	// 持有者基于当前根证明未被吊销
+	current, currentRoot, _ := prove(4660, 4661, 17)
+	require.Equal(t, root, currentRoot)
+	require.NoError(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, commitment))
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, "12345"), ErrBindingMismatch)
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, "12345", commitment), ErrBindingMismatch)
+	// 空树的根已超出 2 个根的历史
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", empty.Proof, emptyRoot, commitment), ErrBindingMismatch)
+
+	// 吊销持有者的标识后, 基于旧根的证明在 600 秒内仍被接受, 之后即使没有新的吊销也失效; 新根下无法再证明
+	_, err = revocation.Revoke(transactionContext, "gov", "4660")
+	require.NoError(t, err)
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2025, 8, 8, 12, 10, 0, 0, time.UTC)), nil)
+	require.NoError(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, commitment))
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2025, 8, 8, 12, 10, 1, 0, time.UTC)), nil)
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, commitment), ErrBindingMismatch)
+	known, err := revocation.IsKnownRevocationRoot(holderContext, "gov", currentRoot)
+	require.NoError(t, err)
+	require.False(t, known)
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(time.Date(2025, 8, 8, 12, 0, 0, 0, time.UTC)), nil)
+	require.NoError(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, commitment))
+	root, err = revocation.GetRevocationRoot(transactionContext, "gov")
+	require.NoError(t, err)
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, root, commitment), ErrProofInvalid)
+	_, _, err = definition.Decode(curve, []byte(`{"id": 4660, "blinding": 7, "depth": 8, "revoked": [4661, 17, 4660]}`))
+	require.ErrorContains(t, err, "id is revoked")
+	// 被吊销的持有者换一个新的标识, 证明本身有效, 但承诺未由发行方登记
+	fresh, _, freshCommitment := prove(4662, 4661, 17, 4660)
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", fresh.Proof, root, freshCommitment), ErrBindingMismatch)
+	_, err = revocation.Revoke(transactionContext, "gov", "99")
+	require.NoError(t, err)
+	known, err = revocation.IsKnownRevocationRoot(holderContext, "gov", currentRoot)
+	require.NoError(t, err)
+	require.False(t, known)
+	require.ErrorIs(t, revocation.VerifyNonRevocation(holderContext, "gov", current.Proof, currentRoot, commitment), ErrBindingMismatch)
+
+	// 重复吊销
+	_, err = revocation.Revoke(transactionContext, "gov", "4660")
+	require.ErrorContains(t, err, "already revoked")
+	registry, err := revocation.GetRevocationRegistry(holderContext, "gov")
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), registry.Count)
+}
@@ -0,0 +1,111 @@
+package gnarkverify
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+)
+
+// 公开输入的类型
+const (
+	InputField   = "field"
+	InputUint64  = "uint64"
+	InputBool    = "bool"
+	InputBytes32 = "bytes32"
+)
+
+// InputSchema 公开输入的名称和类型, 按公开见证中的顺序排列
+type InputSchema struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+var inputNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkCircuitSchema 基于特定电路的合约只接受登记时声明了该电路 schema 的验证密钥,
+// 公开输入个数相同的其他电路的密钥不能混用
+func checkCircuitSchema(record *VerifyingKeyRecord, circuit string, schema []InputSchema) error {
+	if !slices.Equal(record.Config.Schema, schema) {
+		return fmt.Errorf("verifying key %s is not registered with the %s schema", record.ID, circuit)
+	}
+	return nil
+}
+
+// checkSchemaConfig 检查 schema 与验证密钥的公开输入个数一致, 设置后不能修改
+func checkSchemaConfig(record *VerifyingKeyRecord, schema []InputSchema) error {
+	current := record.Config.Schema
+	if current != nil && !slices.Equal(current, schema) {
+		return fmt.Errorf("schema of verifying key %s cannot be changed", record.ID)
+	}
+	if schema == nil {
+		return nil
+	}
+	if len(schema) != record.NbPublic {
+		return fmt.Errorf("schema has %d inputs, verifying key %s has %d", len(schema), record.ID, record.NbPublic)
+	}
+	names := map[string]bool{}
+	for _, input := range schema {
+		if !inputNamePattern.MatchString(input.Name) {
+			return fmt.Errorf("invalid public input name %q", input.Name)
+		}
+		if names[input.Name] {
+			return fmt.Errorf("duplicate public input name %q", input.Name)
+		}
+		names[input.Name] = true
+		switch input.Type {
+		case InputField, InputUint64, InputBool, InputBytes32:
+		default:
+			return fmt.Errorf("unknown type %q of public input %s", input.Type, input.Name)
+		}
+	}
+	return nil
+}
+
+var (
+	maxUint64  = new(big.Int).SetUint64(^uint64(0))
+	maxBytes32 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+)
+
+// decodeInput 按类型解码十进制的公开输入: uint64 为十进制, bool 为 true/false, bytes32 为 0x 前缀的 64 位十六进制
+func decodeInput(input InputSchema, decimal string) (string, error) {
+	value, ok := new(big.Int).SetString(decimal, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid field element %q", decimal)
+	}
+	switch input.Type {
+	case InputUint64:
+		if value.Cmp(maxUint64) > 0 {
+			return "", newError(ErrWitnessMismatch, "public input %s is not a uint64", input.Name)
+		}
+		return value.String(), nil
+	case InputBool:
+		if value.Cmp(big.NewInt(1)) > 0 {
+			return "", newError(ErrWitnessMismatch, "public input %s is not a bool", input.Name)
+		}
+		return fmt.Sprint(value.Sign() == 1), nil
+	case InputBytes32:
+		if value.Cmp(maxBytes32) > 0 {
+			return "", newError(ErrWitnessMismatch, "public input %s does not fit in 32 bytes", input.Name)
+		}
+		return fmt.Sprintf("0x%064x", value), nil
+	default:
+		return value.String(), nil
+	}
+}
+
+// decodeInputs 按 schema 将公开输入转换为名称到值的映射, 个数不一致或类型不符时拒绝
+func decodeInputs(schema []InputSchema, inputs []string) (map[string]string, error) {
+	if len(schema) != len(inputs) {
+		return nil, newError(ErrWitnessMismatch, "public witness has %d inputs, schema expects %d", len(inputs), len(schema))
+	}
+	named := make(map[string]string, len(schema))
+	for i, input := range schema {
+		value, err := decodeInput(input, inputs[i])
+		if err != nil {
+			return nil, err
+		}
+		named[input.Name] = value
+	}
+	return named, nil
+}
@@ -0,0 +1,124 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+// proveRegistered 用注册电路的 json 赋值在 BN254 上生成证明, assignment 为空时使用示例赋值
+func proveRegistered(t *testing.T, protocol, name, assignment string) *prover.Artifact {
+	t.Helper()
+	definition, err := gvcircuits.Lookup(name)
+	require.NoError(t, err)
+	data := []byte(assignment)
+	if assignment == "" {
+		data, err = definition.Example(ecc.BN254)
+		require.NoError(t, err)
+	}
+	shape, circuit, err := definition.Decode(ecc.BN254, data)
+	require.NoError(t, err)
+	p, err := prover.Compile(protocol, "BN254", name, shape)
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	artifact, err := p.ProveAssignment(circuit)
+	require.NoError(t, err)
+	return artifact
+}
+
+func TestDecodeInput(t *testing.T) {
+	testCases := []struct {
+		typ      string
+		input    string
+		expected string
+	}{
+		{InputField, "123", "123"},
+		{InputUint64, "18446744073709551615", "18446744073709551615"},
+		{InputBool, "0", "false"},
+		{InputBool, "1", "true"},
+		{InputBytes32, "255", "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+	}
+	for _, tc := range testCases {
+		value, err := decodeInput(InputSchema{Name: "x", Type: tc.typ}, tc.input)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, value)
+	}
+
+	for _, tc := range []struct{ typ, input string }{
+		{InputUint64, "18446744073709551616"},
+		{InputBool, "2"},
+		{InputBytes32, "115792089237316195423570985008687907853269984665640564039457584007913129639936"},
+	} {
+		_, err := decodeInput(InputSchema{Name: "x", Type: tc.typ}, tc.input)
+		require.ErrorIs(t, err, ErrWitnessMismatch, tc)
+	}
+
+	_, err := decodeInputs([]InputSchema{{"a", InputField}, {"b", InputField}}, []string{"1"})
+	require.ErrorIs(t, err, ErrWitnessMismatch)
+}
+
+func TestVerifyingKeySchema(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	artifact := proveRegistered(t, "groth16", "range", "")
+	require.NoError(t, registerVerifyingKey(transactionContext, "range", "groth16", "BN254", artifact.VK, ""))
+
+	for _, invalid := range []string{
+		`{"schema":[{"name":"lower","type":"uint64"}]}`,
+		`{"schema":[{"name":"lower","type":"uint64"},{"name":"lower","type":"uint64"}]}`,
+		`{"schema":[{"name":"lower","type":"uint64"},{"name":"1upper","type":"uint64"}]}`,
+		`{"schema":[{"name":"lower","type":"uint64"},{"name":"upper","type":"int"}]}`,
+	} {
+		require.Error(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", invalid), invalid)
+	}
+	schema := `{"schema":[{"name":"lower","type":"uint64"},{"name":"upper","type":"field"}]}`
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", schema))
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", schema))
+	require.ErrorContains(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", `{"schema":[{"name":"lo","type":"uint64"},{"name":"hi","type":"field"}]}`), "cannot be changed")
+	require.ErrorContains(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "range", `{}`), "cannot be changed")
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	response, err := gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+		VKID:          "range",
+		Proof:         artifact.Proof,
+		WitnessPublic: artifact.WitnessPublic,
+	}))
+	require.NoError(t, err)
+	require.True(t, response.Valid, response.Reason)
+	expected := map[string]string{"lower": "18", "upper": "65"}
+	require.Equal(t, expected, response.Inputs)
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Equal(t, expected, record.Inputs)
+	require.Equal(t, []string{"18", "65"}, record.PublicInputs)
+	var event ProofVerifiedEvent
+	_, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Equal(t, expected, event.Inputs)
+
+	// 公开输入不符合类型时拒绝
+	big := proveRegistered(t, "groth16", "product", `{"p":"1099511627776","q":"1073741824"}`)
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", big.VK, ""))
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"schema":[{"name":"product","type":"uint64"}]}`))
+	chaincodeStub.GetTxIDReturns("tx2")
+	_, err = gnarkVerify.VerifyProofByKeyID(transactionContext, "product", big.Proof, big.WitnessPublic)
+	require.ErrorIs(t, err, ErrWitnessMismatch)
+	require.ErrorContains(t, err, "not a uint64")
+	_, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx2")
+	require.Error(t, err)
+
+	// transient 提交时按名称的公开输入同样不写入账本
+	chaincodeStub.GetTransientReturns(transientMap(t, artifact, "range"), nil)
+	chaincodeStub.GetTxIDReturns("tx3")
+	response, err = gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	require.True(t, response.Valid)
+	require.Empty(t, response.Inputs)
+	record, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx3")
+	require.NoError(t, err)
+	require.Empty(t, record.Inputs)
+}
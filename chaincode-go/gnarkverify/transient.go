@@ -0,0 +1,63 @@
+package gnarkverify
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// transient 中的字段名, 值为原始字节而非 base64; vk 与 vkId 二选一, witnessPublic 也可以是域元素的 json 数组,
+// salt 为客户端随机选取的盐, 用于计算账本上的公开见证哈希
+const (
+	transientVK      = "vk"
+	transientVKID    = "vkId"
+	transientProof   = "proof"
+	transientWitness = "witnessPublic"
+	transientSalt    = "salt"
+)
+
+// minSaltBytes salt 的最小长度, 使公开见证哈希不能被穷举
+const minSaltBytes = 16
+
+// readTransientRequest 由 transient 中的证明材料构造 VerifyRequest, 并返回 salt
+func readTransientRequest(ctx contractapi.TransactionContextInterface, protocol, curveName string) (*VerifyRequest, []byte, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get transient: %v", err)
+	}
+	for _, name := range []string{transientProof, transientWitness} {
+		if len(transient[name]) == 0 {
+			return nil, nil, newError(ErrBadRequest, "transient field %q must be set", name)
+		}
+	}
+	salt := transient[transientSalt]
+	if len(salt) < minSaltBytes {
+		return nil, nil, newError(ErrBadRequest, "transient field %q must have at least %d bytes", transientSalt, minSaltBytes)
+	}
+	request := &VerifyRequest{
+		Protocol:      protocol,
+		Curve:         curveName,
+		VKID:          string(transient[transientVKID]),
+		Proof:         base64.StdEncoding.EncodeToString(transient[transientProof]),
+		WitnessPublic: base64.StdEncoding.EncodeToString(transient[transientWitness]),
+	}
+	if witness := string(transient[transientWitness]); isPublicInputsJSON(witness) {
+		request.WitnessPublic = witness
+	}
+	if vk := transient[transientVK]; len(vk) > 0 {
+		request.VK = base64.StdEncoding.EncodeToString(vk)
+	}
+	return request, salt, nil
+}
+
+// VerifyProofTransient 与 VerifyProof 相同, 但 vk (或 vkId)、proof、witnessPublic 和 salt 从 transient 读取,
+// 不出现在交易参数中. 验证记录、事件和返回结果只包含以 salt 为密钥的公开见证哈希, 不包含公开输入;
+// salt 写入私有数据集合 (若配置), 否则由客户端自行保存. 配置了 nullifier 时, nullifier 仍以明文写入世界状态以便与其他交易比较
+func (c *GnarkVerifyContract) VerifyProofTransient(ctx contractapi.TransactionContextInterface, protocol string, curveName string) (*VerifyResponse, error) {
+	request, salt, err := readTransientRequest(ctx, protocol, curveName)
+	if err != nil {
+		return nil, err
+	}
+	return verifyRequest(ctx, request, salt)
+}
@@ -0,0 +1,166 @@
+package gnarkverify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+)
+
+// testSalt 测试中 transient 的 salt
+var testSalt = []byte("0123456789abcdef")
+
+// saltedHash 以 salt 为密钥的公开见证编码的 HMAC-SHA256
+func saltedHash(salt, witnessBytes []byte) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(witnessBytes)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// transientMap 将产物中的 base64 字段解码为 transient 中的原始字节, 与 peer CLI 的 --transient 一致
+func transientMap(t *testing.T, artifact *prover.Artifact, vkID string) map[string][]byte {
+	transient := map[string][]byte{transientSalt: testSalt}
+	for name, value := range map[string]string{
+		transientProof:   artifact.Proof,
+		transientWitness: artifact.WitnessPublic,
+	} {
+		data, err := base64.StdEncoding.DecodeString(value)
+		require.NoError(t, err)
+		transient[name] = data
+	}
+	if vkID != "" {
+		transient[transientVKID] = []byte(vkID)
+	} else {
+		vk, err := base64.StdEncoding.DecodeString(artifact.VK)
+		require.NoError(t, err)
+		transient[transientVK] = vk
+	}
+	return transient
+}
+
+// requireNotWritten 检查世界状态和事件中不包含公开输入和证明
+func requireNotWritten(t *testing.T, ledger *mockLedger, chaincodeStub *mocks.ChaincodeStub, artifact *prover.Artifact, inputs []string) {
+	var written []string
+	for key, value := range ledger.state {
+		written = append(written, key, string(value))
+	}
+	for i := 0; i < chaincodeStub.SetEventCallCount(); i++ {
+		_, payload := chaincodeStub.SetEventArgsForCall(i)
+		written = append(written, string(payload))
+	}
+	all := strings.Join(written, "\n")
+	require.NotContains(t, all, artifact.Proof)
+	require.NotContains(t, all, artifact.WitnessPublic)
+	for _, input := range inputs {
+		require.NotContains(t, all, `"`+input+`"`)
+	}
+}
+
+func TestVerifyProofTransient(t *testing.T) {
+	transactionContext, chaincodeStub, ledger := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	artifact := proveProduct(t, "groth16", "BN254", [2]int{1000003, 1000033})[0]
+	witnessBytes, err := base64.StdEncoding.DecodeString(artifact.WitnessPublic)
+	require.NoError(t, err)
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	chaincodeStub.GetTransientReturns(transientMap(t, artifact, ""), nil)
+	response, err := gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	require.True(t, response.Valid)
+	require.Empty(t, response.PublicInputs)
+	require.Equal(t, saltedHash(testSalt, witnessBytes), response.PublicInputsHash)
+	require.NotEqual(t, hashHex(witnessBytes), response.PublicInputsHash)
+
+	record, err := gnarkVerify.GetVerificationRecord(transactionContext, "tx1")
+	require.NoError(t, err)
+	require.Empty(t, record.PublicInputs)
+	require.Equal(t, response.PublicInputsHash, record.PublicInputsHash)
+	proofBytes, err := base64.StdEncoding.DecodeString(artifact.Proof)
+	require.NoError(t, err)
+	require.Equal(t, hashHex(proofBytes), record.ProofHash)
+
+	var event ProofVerifiedEvent
+	_, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Empty(t, event.PublicInputs)
+	require.Equal(t, record.PublicInputsHash, event.PublicInputsHash)
+	requireNotWritten(t, ledger, chaincodeStub, artifact, []string{"1000003", "1000033", "1000036000099"})
+
+	// 同一公开输入换一个 salt 得到不同的哈希, 缺少 salt 或 salt 过短时拒绝
+	resalted := transientMap(t, artifact, "")
+	resalted[transientSalt] = []byte("fedcba9876543210")
+	chaincodeStub.GetTxIDReturns("tx3")
+	chaincodeStub.GetTransientReturns(resalted, nil)
+	other, err := gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+	require.NoError(t, err)
+	require.Equal(t, saltedHash(resalted[transientSalt], witnessBytes), other.PublicInputsHash)
+	require.NotEqual(t, response.PublicInputsHash, other.PublicInputsHash)
+	for _, salt := range [][]byte{nil, []byte("short")} {
+		unsalted := transientMap(t, artifact, "")
+		unsalted[transientSalt] = salt
+		chaincodeStub.GetTransientReturns(unsalted, nil)
+		_, err = gnarkVerify.VerifyProofTransient(transactionContext, "groth16", "BN254")
+		require.ErrorIs(t, err, ErrBadRequest)
+		require.ErrorContains(t, err, `"salt" must have at least 16 bytes`)
+	}
+
+	// 同样的证明通过参数提交时记录公开输入
+	chaincodeStub.GetTxIDReturns("tx2")
+	_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, "BN254", artifact.Proof, artifact.VK, artifact.WitnessPublic)
+	require.NoError(t, err)
+	record, err = gnarkVerify.GetVerificationRecord(transactionContext, "tx2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1000036000099"}, record.PublicInputs)
+	require.Empty(t, record.PublicInputsHash)
+}
+
+func TestVerifyProofTransientByKeyID(t *testing.T) {
+	transactionContext, chaincodeStub, ledger := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	artifacts := proveProduct(t, "plonk", "BN254", [2]int{1000003, 1000033}, [2]int{1000037, 1000039})
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "plonk", "BN254", artifacts[0].VK, ""))
+	require.NoError(t, gnarkVerify.ConfigureVerifyingKey(transactionContext, "product", `{"nullifier":{"index":0}}`))
+
+	chaincodeStub.GetTxIDReturns("tx1")
+	chaincodeStub.GetTransientReturns(transientMap(t, artifacts[0], "product"), nil)
+	response, err := gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "")
+	require.NoError(t, err)
+	require.True(t, response.Valid)
+	require.Equal(t, "BN254", response.Curve)
+	requireNotWritten(t, ledger, chaincodeStub, artifacts[0], []string{"1000003", "1000033"})
+
+	// nullifier 仍然生效
+	response, err = gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "")
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "NULLIFIER_SPENT", response.ErrorCode)
+
+	// 证明与公开见证不匹配
+	invalid := transientMap(t, artifacts[0], "product")
+	invalid[transientWitness] = transientMap(t, artifacts[1], "product")[transientWitness]
+	chaincodeStub.GetTransientReturns(invalid, nil)
+	response, err = gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "")
+	require.NoError(t, err)
+	require.False(t, response.Valid)
+	require.Equal(t, "PROOF_INVALID", response.ErrorCode)
+
+	missing := transientMap(t, artifacts[1], "product")
+	delete(missing, transientProof)
+	chaincodeStub.GetTransientReturns(missing, nil)
+	_, err = gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "")
+	require.ErrorContains(t, err, `"proof" must be set`)
+
+	both := transientMap(t, artifacts[1], "product")
+	both[transientVK] = []byte("vk")
+	chaincodeStub.GetTransientReturns(both, nil)
+	_, err = gnarkVerify.VerifyProofTransient(transactionContext, "plonk", "")
+	require.ErrorContains(t, err, "must not both be set")
+}
@@ -3,14 +3,14 @@ package gnarkverify
 import (
 	"bytes"
 	"encoding/base64"
-	"fmt"
+	"math/big"
+	"reflect"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/plonk"
 	"github.com/consensys/gnark/backend/witness"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
-	"github.com/oliverustc/gnarkabc/utils"
 )
 
 // GnarkVerifyContract 定义智能合约结构
@@ -21,7 +21,7 @@ type GnarkVerifyContract struct {
 func decodeBase64(name, str string) ([]byte, error) {
 	strBytes, err := base64.StdEncoding.DecodeString(str)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode %s from base64 %v: %v", name, str, err)
+		return nil, newError(ErrBadEncoding, "failed to decode %s from base64 (%s): %v", name, describeInput([]byte(str)), err)
 	}
 	return strBytes, nil
 }
@@ -31,12 +31,14 @@ func readGroth16VK(vkStr string, curve ecc.ID) (groth16.VerifyingKey, error) {
 	if err != nil {
 		return nil, err
 	}
-	vk := groth16.NewVerifyingKey(curve)
-	_, err = vk.ReadFrom(bytes.NewReader(vkStrBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read vk from bytes %v: %v", vkStrBytes, err)
-	}
-	return vk, nil
+	return cachedVK(protocolGroth16, curve, vkStrBytes, func() (groth16.VerifyingKey, error) {
+		vk := groth16.NewVerifyingKey(curve)
+		_, err := vk.ReadFrom(bytes.NewReader(vkStrBytes))
+		if err != nil {
+			return nil, newError(ErrMalformedVK, "failed to read %s verifying key (%s): %v", curve, describeInput(vkStrBytes), err)
+		}
+		return vk, nil
+	})
 }
 
 func readGroth16Proof(proofStr string, curve ecc.ID) (groth16.Proof, error) {
@@ -47,51 +49,146 @@ func readGroth16Proof(proofStr string, curve ecc.ID) (groth16.Proof, error) {
 	proof := groth16.NewProof(curve)
 	_, err = proof.ReadFrom(bytes.NewReader(proofStrBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read proof from bytes %v: %v", proofStrBytes, err)
+		return nil, newError(ErrMalformedProof, "failed to read %s proof (%s): %v", curve, describeInput(proofStrBytes), err)
 	}
 	return proof, nil
 }
 
+// readPublicWitness 读取 base64 编码的 gnark 公开见证, 或十进制/十六进制域元素的 json 数组
 func readPublicWitness(pubWitnessStr string, curve ecc.ID) (witness.Witness, error) {
+	if isPublicInputsJSON(pubWitnessStr) {
+		return witnessFromInputs(pubWitnessStr, curve)
+	}
 	pubWitnessStrBytes, err := decodeBase64("publicWitness", pubWitnessStr)
 	if err != nil {
 		return nil, err
 	}
 	publicWitness, err := witness.New(curve.ScalarField())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create public witness from bytes %v: %v", pubWitnessStrBytes, err)
+		return nil, newError(ErrUnknownCurve, "failed to create public witness for %s: %v", curve, err)
 	}
 	_, err = publicWitness.ReadFrom(bytes.NewReader(pubWitnessStrBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read public witness from bytes %v: %v", pubWitnessStrBytes, err)
+		return nil, newError(ErrBadEncoding, "failed to read %s public witness (%s): %v", curve, describeInput(pubWitnessStrBytes), err)
 	}
 	return publicWitness, nil
 }
 
-func (c *GnarkVerifyContract) VerifyGroth16Proof(ctx contractapi.TransactionContextInterface, curveName string, proofStr string, vkStr string, pubWitnessStr string) (string, error) {
+// publicWitnessCounter 各曲线的 groth16 与 plonk 验证密钥都实现 NbPublicWitness
+type publicWitnessCounter interface {
+	NbPublicWitness() int
+}
 
-	curve := utils.CurveMap[curveName]
-	proof, err := readGroth16Proof(proofStr, curve)
+// nbPublicWitness 验证密钥期望的公开见证元素个数. groth16 的 NbPublicWitness 包含承诺对应的线,
+// 需要减去 PublicAndCommitmentCommitted 的长度, 与 groth16.Verify 的检查一致
+func nbPublicWitness(vk any) (int, error) {
+	counter, ok := vk.(publicWitnessCounter)
+	if !ok {
+		return 0, newError(ErrMalformedVK, "unsupported verifying key type %T", vk)
+	}
+	nbPublic := counter.NbPublicWitness()
+	value := reflect.Indirect(reflect.ValueOf(vk))
+	if value.Kind() == reflect.Struct {
+		if committed := value.FieldByName("PublicAndCommitmentCommitted"); committed.Kind() == reflect.Slice {
+			nbPublic -= committed.Len()
+		}
+	}
+	return nbPublic, nil
+}
+
+// checkWitnessSize 公开见证的元素个数须与验证密钥的公开输入个数一致
+func checkWitnessSize(vk any, publicWitness witness.Witness) error {
+	expected, err := nbPublicWitness(vk)
 	if err != nil {
-		return "", err
+		return err
 	}
+	vector := reflect.ValueOf(publicWitness.Vector())
+	if vector.Kind() == reflect.Slice && vector.Len() != expected {
+		return newError(ErrWitnessMismatch, "public witness has %d inputs, verifying key expects %d", vector.Len(), expected)
+	}
+	return nil
+}
 
-	vk, err := readGroth16VK(vkStr, curve)
+// publicInputs 将公开见证中的域元素转换为十进制字符串
+func publicInputs(publicWitness witness.Witness) ([]string, error) {
+	vector := reflect.ValueOf(publicWitness.Vector())
+	if vector.Kind() != reflect.Slice {
+		return nil, newError(ErrWitnessMismatch, "unexpected public witness vector type %T", publicWitness.Vector())
+	}
+	inputs := make([]string, vector.Len())
+	for i := range inputs {
+		element, ok := vector.Index(i).Addr().Interface().(interface{ BigInt(*big.Int) *big.Int })
+		if !ok {
+			return nil, newError(ErrWitnessMismatch, "unexpected public witness element type %s", vector.Index(i).Type())
+		}
+		inputs[i] = element.BigInt(new(big.Int)).String()
+	}
+	return inputs, nil
+}
+
+func verifyGroth16(vk groth16.VerifyingKey, proofStr string, pubWitnessStr string, curve ecc.ID) (string, witness.Witness, error) {
+	proof, err := readGroth16Proof(proofStr, curve)
 	if err != nil {
-		return "read groth16 verifyingkey failed", err
+		return "read groth16 proof failed", nil, err
 	}
 
 	publicWitness, err := readPublicWitness(pubWitnessStr, curve)
 	if err != nil {
-		return "read groth16 public witness failed", err
+		return "read groth16 public witness failed", nil, err
+	}
+	if err := checkWitnessSize(vk, publicWitness); err != nil {
+		return "check groth16 public witness failed", nil, err
 	}
 
 	// 验证证明
 	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
-		return "verify groth16 proof failed", fmt.Errorf("failed to verify proof: %v", err)
+		return "verify groth16 proof failed", nil, newError(ErrProofInvalid, "failed to verify groth16 proof: %v", err)
+	}
+
+	return "verify groth16 proof success", publicWitness, nil
+}
+
+func (c *GnarkVerifyContract) VerifyGroth16Proof(ctx contractapi.TransactionContextInterface, curveName string, proofStr string, vkStr string, pubWitnessStr string) (string, error) {
+
+	curveName, curve, err := parseCurve(curveName)
+	if err != nil {
+		return "unknown curve", err
+	}
+	if err := checkInputSizes(ctx, protocolGroth16, curveName, vkStr, proofStr, pubWitnessStr); err != nil {
+		return "input too large", err
+	}
+	vk, err := readGroth16VK(vkStr, curve)
+	if err != nil {
+		return "read groth16 verifyingkey failed", err
+	}
+	vkHash, err := vkHashOf(vk)
+	if err != nil {
+		return "read groth16 verifyingkey failed", err
+	}
+	nbPublic, err := nbPublicWitness(vk)
+	if err != nil {
+		return "read groth16 verifyingkey failed", err
+	}
+	if err := checkInlineVerifyingKey(ctx, vkHash, nbPublic); err != nil {
+		return "check inline verifying key failed", err
 	}
 
-	return "verify groth16 proof success", nil
+	msg, publicWitness, err := verifyGroth16(vk, proofStr, pubWitnessStr, curve)
+	if err != nil {
+		return msg, err
+	}
+
+	_, err = recordVerification(ctx, &verification{
+		protocol:      protocolGroth16,
+		curveName:     curveName,
+		vkHash:        vkHash,
+		proofStr:      proofStr,
+		publicWitness: publicWitness,
+	})
+	if err != nil {
+		return "record verification failed", err
+	}
+	return msg, nil
 }
 
 func readPlonkVK(vkStr string, curve ecc.ID) (plonk.VerifyingKey, error) {
@@ -99,12 +196,14 @@ func readPlonkVK(vkStr string, curve ecc.ID) (plonk.VerifyingKey, error) {
 	if err != nil {
 		return nil, err
 	}
-	vk := plonk.NewVerifyingKey(curve)
-	_, err = vk.ReadFrom(bytes.NewReader(vkStrBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read vk from bytes %v: %v", vkStrBytes, err)
-	}
-	return vk, nil
+	return cachedVK(protocolPlonk, curve, vkStrBytes, func() (plonk.VerifyingKey, error) {
+		vk := plonk.NewVerifyingKey(curve)
+		_, err := vk.ReadFrom(bytes.NewReader(vkStrBytes))
+		if err != nil {
+			return nil, newError(ErrMalformedVK, "failed to read %s verifying key (%s): %v", curve, describeInput(vkStrBytes), err)
+		}
+		return vk, nil
+	})
 }
 
 func readPlonkProof(proofStr string, curve ecc.ID) (plonk.Proof, error) {
@@ -115,38 +214,72 @@ func readPlonkProof(proofStr string, curve ecc.ID) (plonk.Proof, error) {
 	proof := plonk.NewProof(curve)
 	_, err = proof.ReadFrom(bytes.NewReader(proofStrBytes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read proof from bytes %v: %v", proofStrBytes, err)
+		return nil, newError(ErrMalformedProof, "failed to read %s proof (%s): %v", curve, describeInput(proofStrBytes), err)
 	}
 	return proof, nil
 }
 
-func (c *GnarkVerifyContract) VerifyPlonkProof(ctx contractapi.TransactionContextInterface, curveName string, proofStr string, vkStr string, pubWitnessStr string) (string, error) {
-
-	curve := utils.CurveMap[curveName]
-	vk, err := readPlonkVK(vkStr, curve)
-	if err != nil {
-		return "read plonk verifyingkey failed", err
-	}
-
+func verifyPlonk(vk plonk.VerifyingKey, proofStr string, pubWitnessStr string, curve ecc.ID) (string, witness.Witness, error) {
 	proof, err := readPlonkProof(proofStr, curve)
 	if err != nil {
-		return "read plonk proof failed", err
+		return "read plonk proof failed", nil, err
 	}
 
 	publicWitness, err := readPublicWitness(pubWitnessStr, curve)
 	if err != nil {
-		return "read plonk public witness failed", err
+		return "read plonk public witness failed", nil, err
+	}
+	if err := checkWitnessSize(vk, publicWitness); err != nil {
+		return "check plonk public witness failed", nil, err
 	}
 
 	// 验证证明
 	if err := plonk.Verify(proof, vk, publicWitness); err != nil {
-		return "verify plonk proof failed", fmt.Errorf("failed to verify proof: %v", err)
+		return "verify plonk proof failed", nil, newError(ErrProofInvalid, "failed to verify plonk proof: %v", err)
 	}
 
-	return "verify plonk proof success", nil
+	return "verify plonk proof success", publicWitness, nil
 }
 
-// GetContractInfo 获取合约信息
-func (c *GnarkVerifyContract) GetContractInfo(ctx contractapi.TransactionContextInterface) (string, error) {
-	return "Gnark Verification Contract", nil
+func (c *GnarkVerifyContract) VerifyPlonkProof(ctx contractapi.TransactionContextInterface, curveName string, proofStr string, vkStr string, pubWitnessStr string) (string, error) {
+
+	curveName, curve, err := parseCurve(curveName)
+	if err != nil {
+		return "unknown curve", err
+	}
+	if err := checkInputSizes(ctx, protocolPlonk, curveName, vkStr, proofStr, pubWitnessStr); err != nil {
+		return "input too large", err
+	}
+	vk, err := readPlonkVK(vkStr, curve)
+	if err != nil {
+		return "read plonk verifyingkey failed", err
+	}
+	vkHash, err := vkHashOf(vk)
+	if err != nil {
+		return "read plonk verifyingkey failed", err
+	}
+	nbPublic, err := nbPublicWitness(vk)
+	if err != nil {
+		return "read plonk verifyingkey failed", err
+	}
+	if err := checkInlineVerifyingKey(ctx, vkHash, nbPublic); err != nil {
+		return "check inline verifying key failed", err
+	}
+
+	msg, publicWitness, err := verifyPlonk(vk, proofStr, pubWitnessStr, curve)
+	if err != nil {
+		return msg, err
+	}
+
+	_, err = recordVerification(ctx, &verification{
+		protocol:      protocolPlonk,
+		curveName:     curveName,
+		vkHash:        vkHash,
+		proofStr:      proofStr,
+		publicWitness: publicWitness,
+	})
+	if err != nil {
+		return "record verification failed", err
+	}
+	return msg, nil
 }
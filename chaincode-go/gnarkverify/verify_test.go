@@ -1,19 +1,21 @@
 package gnarkverify
 
 import (
-	"encoding/json"
 	"os"
 	"testing"
-	"time"
 
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
 	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify/mocks"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
 	"github.com/oliverustc/gnarkabc/circuits"
-	"github.com/oliverustc/gnarkabc/logger"
 	"github.com/oliverustc/gnarkabc/utils"
-	"github.com/oliverustc/gnarkabc/wrapper/groth16wrapper"
-	"github.com/oliverustc/gnarkabc/wrapper/plonkwrapper"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,10 +36,9 @@ type stateQueryIterator interface {
 	shim.StateQueryIteratorInterface
 }
 
-type GnarkParams struct {
-	Vk            string `json:"vk"`
-	Proof         string `json:"proof"`
-	WitnessPublic string `json:"witnessPublic"`
+//go:generate counterfeiter -o mocks/clientidentity.go -fake-name ClientIdentity . clientIdentity
+type clientIdentity interface {
+	cid.ClientIdentity
 }
 
 func TestGetContractInfo(t *testing.T) {
@@ -47,240 +48,173 @@ func TestGetContractInfo(t *testing.T) {
 	gnarkVerify := &GnarkVerifyContract{}
 	response, err := gnarkVerify.GetContractInfo(transactionContext)
 	require.NoError(t, err)
-	require.Equal(t, "Gnark Verification Contract", response)
+	require.Equal(t, "Gnark Verification Contract", response.Name)
+	require.Len(t, response.Capabilities.Schemes, 2)
 }
 
-type ZKSNARKParams struct {
-	VK            string `json:"vk"`
-	Proof         string `json:"proof"`
-	WitnessPublic string `json:"witnessPublic"`
+// proveProduct 在同一次 setup 下为每组 (p, q) 生成 Product 电路的证明
+func proveProduct(t *testing.T, protocol string, curveName string, pqs ...[2]int) []*prover.Artifact {
+	t.Helper()
+	p, err := prover.Compile(protocol, curveName, "product", &circuits.Product{})
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	artifacts := make([]*prover.Artifact, len(pqs))
+	for i, pq := range pqs {
+		artifacts[i], err = p.ProveAssignment(&circuits.Product{P: pq[0], Q: pq[1], N: pq[0] * pq[1]})
+		require.NoError(t, err)
+	}
+	return artifacts
 }
 
-func groth16Generate(date string, curveName string) error {
-	var circuit circuits.Product
-	circuit.PreCompile(nil)
-	curve := utils.CurveMap[curveName]
-	zk := groth16wrapper.NewWrapper(&circuit, curve)
-	zk.Compile()
-	zk.Setup()
-	p := utils.RandInt(1, 100)
-	q := utils.RandInt(1, 100)
-	assignParams := []any{p, q}
-	circuit.Assign(assignParams)
-	zk.SetAssignment(&circuit)
-	zk.Prove()
-	zk.Verify()
+func randomPQ() [2]int {
+	return [2]int{utils.RandInt(1, 100), utils.RandInt(1, 100)}
+}
 
-	logger.Debug("Groth16 done")
-	vkStr, _ := zk.MarshalVKToStr()
-	proofStr, _ := zk.MarshalProofToStr()
-	witnessPublicStr, _ := zk.MarshalWitnessToStr(true)
-	logger.Debug("vk: %s", vkStr)
-	logger.Debug("proof: %s", proofStr)
-	logger.Debug("witnessPublic: %s", witnessPublicStr)
+func groth16Params(t *testing.T, curveName string) *prover.Artifact {
+	return proveProduct(t, prover.ProtocolGroth16, curveName, randomPQ())[0]
+}
 
-	params := ZKSNARKParams{
-		VK:            vkStr,
-		Proof:         proofStr,
-		WitnessPublic: witnessPublicStr,
-	}
-	// write params into json file
-	utils.EnsureDirExists("output")
-	jsonFile, err := os.Create("output/groth16_" + curveName + "_" + date + ".json")
-	if err != nil {
-		return err
-	}
-	defer jsonFile.Close()
-	json.NewEncoder(jsonFile).Encode(params)
-	return nil
+func plonkParams(t *testing.T, curveName string) *prover.Artifact {
+	return proveProduct(t, prover.ProtocolPlonk, curveName, randomPQ())[0]
 }
 
-func groth16Verify(date string, curveName string) error {
-	circuit := circuits.Product{}
-	curve := utils.CurveMap[curveName]
-	zk := groth16wrapper.NewWrapper(&circuit, curve)
-	// read params from groth16.json
-	jsonFile, err := os.Open("output/groth16_" + curveName + "_" + date + ".json")
-	if err != nil {
-		return err
-	}
-	defer jsonFile.Close()
-	var params ZKSNARKParams
-	json.NewDecoder(jsonFile).Decode(&params)
-	zk.UnmarshalVKFromStr(params.VK)
-	zk.UnmarshalProofFromStr(params.Proof)
-	zk.UnmarshalWitnessFromStr(params.WitnessPublic, true)
-	zk.Verify()
-	logger.Debug("Groth16 verify done")
-	return nil
+// writeAndReadArtifact 写出产物后重新读取, 确认文件内容可直接用于链码验证
+func writeAndReadArtifact(t *testing.T, artifact *prover.Artifact) *prover.Artifact {
+	t.Helper()
+	path, err := artifact.Write(t.TempDir())
+	require.NoError(t, err)
+	read, err := prover.ReadArtifact(path)
+	require.NoError(t, err)
+	return read
 }
 
 func TestGroth16(t *testing.T) {
-	// 使用UTC+8时区(北京时间)
-	loc := time.FixedZone("CST", 8*3600) // 东八区，偏移量为8小时
-	dateStr := time.Now().In(loc).Format("2006-01-02_15-04-05")
 	for _, curveName := range utils.CurveNameList {
 		t.Logf("generating groth16 proof... curve: [%s]", curveName)
-		err := groth16Generate(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		t.Logf("generate groth16 proof done, curve: [%s]", curveName)
-	}
-	for _, curveName := range utils.CurveNameList {
-		t.Logf("verifying groth16 proof... curve: [%s]", curveName)
-		err := groth16Verify(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
+		params := writeAndReadArtifact(t, groth16Params(t, curveName))
+		_, curve, err := parseCurve(curveName)
+		require.NoError(t, err)
+		vk, err := readGroth16VK(params.VK, curve)
+		require.NoError(t, err)
+		msg, _, err := verifyGroth16(vk, params.Proof, params.WitnessPublic, curve)
+		require.NoError(t, err)
+		require.Equal(t, "verify groth16 proof success", msg)
 		t.Logf("verify groth16 proof done, curve: [%s]", curveName)
 	}
 }
 
 func TestVerifyGroth16Proof(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
-	loc := time.FixedZone("CST", 8*3600) // 东八区，偏移量为8小时
-	dateStr := time.Now().In(loc).Format("2006-01-02_15-04-05")
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
 
 	for _, curveName := range utils.CurveNameList {
 		t.Logf("verifying groth16 proof on chaincode... curve: [%s]", curveName)
-		err := groth16Generate(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		err = groth16Verify(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		groth16File, err := os.ReadFile("output/groth16_" + dateStr + ".json")
-		if err != nil {
-			t.Fatal(err)
-		}
-		var gnarkParams GnarkParams
-		if err := json.Unmarshal(groth16File, &gnarkParams); err != nil {
-			t.Fatal(err)
-		}
-		logger.Debug("Gnark params: %v", gnarkParams)
-		gnarkVerify := &GnarkVerifyContract{}
-		_, err = gnarkVerify.VerifyGroth16Proof(transactionContext, curveName, gnarkParams.Proof, gnarkParams.Vk, gnarkParams.WitnessPublic)
+		params := writeAndReadArtifact(t, groth16Params(t, curveName))
+		chaincodeStub.GetTxIDReturns("groth16-" + curveName)
+		_, err := gnarkVerify.VerifyGroth16Proof(transactionContext, curveName, params.Proof, params.VK, params.WitnessPublic)
+		require.NoError(t, err)
+		record, err := gnarkVerify.GetVerificationRecord(transactionContext, "groth16-"+curveName)
 		require.NoError(t, err)
+		require.Equal(t, curveName, record.Curve)
 		t.Logf("verify groth16 proof on chaincode done, curve: [%s]", curveName)
 	}
-
-}
-
-func plonkGenerate(date string, curveName string) error {
-	var circuit circuits.Product
-	circuit.PreCompile(nil)
-	curve := utils.CurveMap[curveName]
-	zk := plonkwrapper.NewWrapper(&circuit, curve)
-	zk.Compile()
-	zk.Setup()
-	p := utils.RandInt(1, 100)
-	q := utils.RandInt(1, 100)
-	assignParams := []any{p, q}
-	circuit.Assign(assignParams)
-	zk.SetAssignment(&circuit)
-	zk.Prove()
-	zk.Verify()
-
-	logger.Debug("Plonk done")
-	vkStr, _ := zk.MarshalVKToStr()
-	proofStr, _ := zk.MarshalProofToStr()
-	witnessPublicStr, _ := zk.MarshalWitnessToStr(true)
-	logger.Debug("vk: %s", vkStr)
-	logger.Debug("proof: %s", proofStr)
-	logger.Debug("witnessPublic: %s", witnessPublicStr)
-
-	params := ZKSNARKParams{
-		VK:            vkStr,
-		Proof:         proofStr,
-		WitnessPublic: witnessPublicStr,
-	}
-	// write params into json file
-	utils.EnsureDirExists("output")
-	jsonFile, err := os.Create("output/plonk_" + curveName + "_" + date + ".json")
-	if err != nil {
-		return err
-	}
-	defer jsonFile.Close()
-	json.NewEncoder(jsonFile).Encode(params)
-	return nil
-}
-
-func plonkVerify(date string, curveName string) error {
-	circuit := circuits.Product{}
-	curve := utils.CurveMap[curveName]
-	zk := plonkwrapper.NewWrapper(&circuit, curve)
-	// read params from plonk.json
-	jsonFile, err := os.Open("output/plonk_" + curveName + "_" + date + ".json")
-	if err != nil {
-		return err
-	}
-	defer jsonFile.Close()
-	var params ZKSNARKParams
-	json.NewDecoder(jsonFile).Decode(&params)
-	zk.UnmarshalVKFromStr(params.VK)
-	zk.UnmarshalProofFromStr(params.Proof)
-	zk.UnmarshalWitnessFromStr(params.WitnessPublic, true)
-	zk.Verify()
-	logger.Debug("Plonk verify done")
-	return nil
 }
 
 func TestPlonk(t *testing.T) {
-	loc := time.FixedZone("CST", 8*3600) // 东八区，偏移量为8小时
-	dateStr := time.Now().In(loc).Format("2006-01-02_15-04-05")
 	for _, curveName := range utils.CurveNameList {
 		t.Logf("generating plonk proof... curve: [%s]", curveName)
-		err := plonkGenerate(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		t.Logf("generate plonk proof done, curve: [%s]", curveName)
-	}
-	for _, curveName := range utils.CurveNameList {
-		t.Logf("verifying plonk proof... curve: [%s]", curveName)
-		err := plonkVerify(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
+		params := writeAndReadArtifact(t, plonkParams(t, curveName))
+		_, curve, err := parseCurve(curveName)
+		require.NoError(t, err)
+		vk, err := readPlonkVK(params.VK, curve)
+		require.NoError(t, err)
+		msg, _, err := verifyPlonk(vk, params.Proof, params.WitnessPublic, curve)
+		require.NoError(t, err)
+		require.Equal(t, "verify plonk proof success", msg)
 		t.Logf("verify plonk proof done, curve: [%s]", curveName)
 	}
 }
 
 func TestVerifyPlonkProof(t *testing.T) {
-	chaincodeStub := &mocks.ChaincodeStub{}
-	transactionContext := &mocks.TransactionContext{}
-	transactionContext.GetStubReturns(chaincodeStub)
-
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
 	gnarkVerify := &GnarkVerifyContract{}
-	loc := time.FixedZone("CST", 8*3600) // 东八区，偏移量为8小时
-	dateStr := time.Now().In(loc).Format("2006-01-02_15-04-05")
+
 	for _, curveName := range utils.CurveNameList {
 		t.Logf("verifying plonk proof on chaincode... curve: [%s]", curveName)
-		err := plonkGenerate(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		err = plonkVerify(dateStr, curveName)
-		if err != nil {
-			t.Fatal(err)
-		}
-		plonkFile, err := os.ReadFile("output/plonk_" + dateStr + ".json")
-		if err != nil {
-			t.Fatal(err)
-		}
-		var gnarkParams GnarkParams
-		if err := json.Unmarshal(plonkFile, &gnarkParams); err != nil {
-			t.Fatal(err)
-		}
-		logger.Debug("Gnark params: %v", gnarkParams)
-		_, err = gnarkVerify.VerifyPlonkProof(transactionContext, curveName, gnarkParams.Proof, gnarkParams.Vk, gnarkParams.WitnessPublic)
+		params := writeAndReadArtifact(t, plonkParams(t, curveName))
+		chaincodeStub.GetTxIDReturns("plonk-" + curveName)
+		_, err := gnarkVerify.VerifyPlonkProof(transactionContext, curveName, params.Proof, params.VK, params.WitnessPublic)
+		require.NoError(t, err)
+		record, err := gnarkVerify.GetVerificationRecord(transactionContext, "plonk-"+curveName)
 		require.NoError(t, err)
+		require.Equal(t, curveName, record.Curve)
 		t.Logf("verify plonk proof on chaincode done, curve: [%s]", curveName)
 	}
+}
+
+func TestNbPublicWitness(t *testing.T) {
+	// 每个支持的曲线和协议组合的验证密钥类型都能得到公开输入个数
+	for _, curve := range supportedCurves {
+		if protocolSupportsCurve(protocolGroth16, curve.id) {
+			_, err := nbPublicWitness(groth16.NewVerifyingKey(curve.id))
+			require.NoError(t, err, curve.name)
+		}
+		if protocolSupportsCurve(protocolPlonk, curve.id) {
+			_, err := nbPublicWitness(plonk.NewVerifyingKey(curve.id))
+			require.NoError(t, err, curve.name)
+		}
+	}
+	// groth16 承诺对应的线不计入公开输入
+	vk := &groth16_bn254.VerifyingKey{}
+	vk.G1.K = make([]bn254.G1Affine, 4)
+	vk.PublicAndCommitmentCommitted = [][]int{{1}}
+	nbPublic, err := nbPublicWitness(vk)
+	require.NoError(t, err)
+	require.Equal(t, 2, nbPublic)
+
+	_, err = nbPublicWitness(struct{}{})
+	require.ErrorIs(t, err, ErrMalformedVK)
+	require.ErrorContains(t, err, "unsupported verifying key type")
+}
+
+// TestVerifyRegisteredCircuits 用每个已注册电路的示例赋值生成证明并在链码中验证.
+// 默认只覆盖 BN254, 设置 GNARKVERIFY_ALL_CURVES=1 时覆盖全部曲线 (耗时数分钟)
+func TestVerifyRegisteredCircuits(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	curveNames := []string{"BN254"}
+	if os.Getenv("GNARKVERIFY_ALL_CURVES") != "" {
+		curveNames = utils.CurveNameList
+	}
 
+	for _, name := range gvcircuits.Names() {
+		definition, err := gvcircuits.Lookup(name)
+		require.NoError(t, err)
+		for _, protocol := range []string{prover.ProtocolGroth16, prover.ProtocolPlonk} {
+			for _, curveName := range curveNames {
+				curve := utils.CurveMap[curveName]
+				example, err := definition.Example(curve)
+				require.NoError(t, err)
+				shape, assignment, err := definition.Decode(curve, example)
+				require.NoError(t, err)
+				p, err := prover.Compile(protocol, curveName, name, shape)
+				require.NoError(t, err)
+				require.NoError(t, p.Setup())
+				artifact, err := p.ProveAssignment(assignment)
+				require.NoError(t, err)
+
+				txID := artifact.FileName()
+				chaincodeStub.GetTxIDReturns(txID)
+				response, err := gnarkVerify.VerifyProof(transactionContext, verifyRequestJSON(t, VerifyRequest{
+					Protocol:      protocol,
+					Curve:         curveName,
+					VK:            artifact.VK,
+					Proof:         artifact.Proof,
+					WitnessPublic: artifact.WitnessPublic,
+				}))
+				require.NoError(t, err)
+				require.True(t, response.Valid, "%s: %s", txID, response.Reason)
+			}
+		}
+	}
 }
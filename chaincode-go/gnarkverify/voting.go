@@ -0,0 +1,311 @@
+package gnarkverify
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+)
+
+const (
+	electionObjectType = "election"
+	voteObjectType     = "vote"
+
+	// voteNbPublic circuits.Vote 的公开输入: Root, Nullifier, Election, Choice
+	voteNbPublic = 4
+)
+
+// voteSchema circuits.Vote 公开输入的 schema
+var voteSchema = []InputSchema{
+	{Name: "root", Type: InputField},
+	{Name: "nullifier", Type: InputField},
+	{Name: "election", Type: InputField},
+	{Name: "choice", Type: InputUint64},
+}
+
+// VotingContract 基于 circuits.Vote 电路的匿名投票. 选民以 MiMC(secret) 登记在选民树中,
+// 投票时证明自己在树中并给出选举内唯一的 nullifier, 合约按 nullifier 防止重复投票
+type VotingContract struct {
+	contractapi.Contract
+}
+
+// Election 选举, Tag 为电路中的 Election 公开输入, 由选举 ID 确定
+type Election struct {
+	ID       string       `json:"id"`
+	VKID     string       `json:"vkId"`
+	Root     string       `json:"root"`
+	Tag      string       `json:"tag"`
+	Options  []string     `json:"options"`
+	Deadline string       `json:"deadline"`
+	Owner    string       `json:"owner"`
+	Result   *TallyResult `json:"result,omitempty"`
+}
+
+// TallyResult 计票结果, Counts 与 Options 一一对应
+type TallyResult struct {
+	Counts []int `json:"counts"`
+	Total  int   `json:"total"`
+}
+
+// domainTag 将 domain:id 的 sha256 对标量域取模, 用作电路中的公开输入
+func domainTag(curve ecc.ID, domain string, id string) *big.Int {
+	sum := sha256.Sum256([]byte(domain + ":" + id))
+	return new(big.Int).Mod(new(big.Int).SetBytes(sum[:]), curve.ScalarField())
+}
+
+// callerTag 由调用者身份和 parts 确定的 tag, 作为挑战绑定到证明中, 其他调用者得到不同的值
+func callerTag(ctx contractapi.TransactionContextInterface, curve ecc.ID, domain string, parts ...string) (string, error) {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	id, err := json.Marshal(append(parts, mspID, clientID))
+	if err != nil {
+		return "", err
+	}
+	return domainTag(curve, domain, string(id)).String(), nil
+}
+
+// electionTag 由选举 ID 确定的 tag, 使 nullifier 在不同选举之间不可关联
+func electionTag(curve ecc.ID, id string) *big.Int {
+	return domainTag(curve, electionObjectType, id)
+}
+
+// electionNullifierScope 选举 nullifier 的作用域, 与验证密钥的 nullifier 分开
+func electionNullifierScope(id string) string {
+	return electionObjectType + "~" + id
+}
+
+func electionKey(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(electionObjectType, []string{id})
+}
+
+func readElection(ctx contractapi.TransactionContextInterface, id string) (*Election, error) {
+	key, err := electionKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read election %s: %v", id, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("election %s does not exist", id)
+	}
+	var election Election
+	if err := json.Unmarshal(data, &election); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal election %s: %v", id, err)
+	}
+	return &election, nil
+}
+
+func putElection(ctx contractapi.TransactionContextInterface, election *Election) error {
+	key, err := electionKey(ctx, election.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(election)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// isOpen 判断交易时间是否早于截止时间
+func (e *Election) isOpen(ctx contractapi.TransactionContextInterface) (bool, error) {
+	deadline, err := time.Parse(time.RFC3339, e.Deadline)
+	if err != nil {
+		return false, fmt.Errorf("invalid deadline of election %s: %v", e.ID, err)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return false, err
+	}
+	return now.Before(deadline), nil
+}
+
+// CreateElection 创建选举. vkID 为已登记的 circuits.Vote 的 Groth16 验证密钥, root 为选民树的根 (可取自 GetRoot),
+// optionsJSON 为选项名称的 json 数组, deadline 为 RFC3339 格式的截止时间, 与交易时间戳比较
+func (c *VotingContract) CreateElection(ctx contractapi.TransactionContextInterface, id string, vkID string, root string, optionsJSON string, deadline string) error {
+	if id == "" {
+		return fmt.Errorf("election id must not be empty")
+	}
+	key, err := electionKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read election %s: %v", id, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("election %s already exists", id)
+	}
+
+	vkRecord, err := readVerifyingKeyRecord(ctx, vkID)
+	if err != nil {
+		return err
+	}
+	if vkRecord.Protocol != protocolGroth16 || vkRecord.NbPublic != voteNbPublic {
+		return fmt.Errorf("verifying key %s is not a groth16 key with %d public inputs", vkID, voteNbPublic)
+	}
+	if err := checkCircuitSchema(vkRecord, "vote", voteSchema); err != nil {
+		return err
+	}
+	_, curve, err := parseCurve(vkRecord.Curve)
+	if err != nil {
+		return err
+	}
+	root, err = normalizeFieldElement(root)
+	if err != nil {
+		return err
+	}
+	if rootInt, _ := new(big.Int).SetString(root, 10); rootInt.Cmp(curve.ScalarField()) >= 0 {
+		return fmt.Errorf("voter root is not in the scalar field of %s", vkRecord.Curve)
+	}
+
+	var options []string
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return fmt.Errorf("failed to unmarshal election options: %v", err)
+	}
+	if len(options) < 2 || len(options) > gvcircuits.MaxVoteOptions {
+		return fmt.Errorf("election must have between 2 and %d options", gvcircuits.MaxVoteOptions)
+	}
+	seen := map[string]bool{}
+	for _, option := range options {
+		if option == "" || seen[option] {
+			return fmt.Errorf("election options must be non-empty and unique")
+		}
+		seen[option] = true
+	}
+
+	election := &Election{
+		ID:       id,
+		VKID:     vkID,
+		Root:     root,
+		Tag:      electionTag(curve, id).String(),
+		Options:  options,
+		Deadline: deadline,
+	}
+	open, err := election.isOpen(ctx)
+	if err != nil {
+		return err
+	}
+	if !open {
+		return fmt.Errorf("deadline %s of election %s has already passed", deadline, id)
+	}
+	if election.Owner, err = ctx.GetClientIdentity().GetMSPID(); err != nil {
+		return fmt.Errorf("failed to get client msp id: %v", err)
+	}
+	return putElection(ctx, election)
+}
+
+// GetElection 查询选举, 证明者从中取得 tag 和选民树的根
+func (c *VotingContract) GetElection(ctx contractapi.TransactionContextInterface, id string) (*Election, error) {
+	return readElection(ctx, id)
+}
+
+// CastVote 验证投票证明并记录选项. 公开输入由选举的根、tag 和参数中的 nullifier、choice 构造,
+// 证明只对这些值有效; nullifier 已使用时返回 NULLIFIER_SPENT
+func (c *VotingContract) CastVote(ctx contractapi.TransactionContextInterface, electionID string, proofStr string, nullifier string, choice int) error {
+	election, err := readElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	open, err := election.isOpen(ctx)
+	if err != nil {
+		return err
+	}
+	if !open || election.Result != nil {
+		return fmt.Errorf("election %s is closed", electionID)
+	}
+	if choice < 0 || choice >= len(election.Options) {
+		return fmt.Errorf("choice %d out of range [0, %d)", choice, len(election.Options))
+	}
+	nullifier, err = normalizeFieldElement(nullifier)
+	if err != nil {
+		return err
+	}
+
+	vkRecord, err := readVerifyingKeyRecord(ctx, election.VKID)
+	if err != nil {
+		return err
+	}
+	inputs, err := json.Marshal([]string{election.Root, nullifier, election.Tag, strconv.Itoa(choice)})
+	if err != nil {
+		return err
+	}
+	if _, _, err := verifyAndRecord(ctx, vkRecord, proofStr, string(inputs), nil); err != nil {
+		return err
+	}
+	if err := spendNullifier(ctx, electionNullifierScope(electionID), nullifier); err != nil {
+		return err
+	}
+	// 每张选票单独一个键, 并发投票之间没有读写冲突
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, nullifier})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(strconv.Itoa(choice)))
+}
+
+// HasVoted 查询 nullifier 是否已在选举中投票
+func (c *VotingContract) HasVoted(ctx contractapi.TransactionContextInterface, electionID string, nullifier string) (bool, error) {
+	normalized, err := normalizeFieldElement(nullifier)
+	if err != nil {
+		return false, err
+	}
+	return isNullifierSpent(ctx, electionNullifierScope(electionID), normalized)
+}
+
+// Tally 截止时间之后统计选票, 首次提交时将结果写入选举, 之后不再接受投票
+func (c *VotingContract) Tally(ctx contractapi.TransactionContextInterface, electionID string) (*TallyResult, error) {
+	election, err := readElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Result != nil {
+		return election.Result, nil
+	}
+	open, err := election.isOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, fmt.Errorf("election %s is open until %s", electionID, election.Deadline)
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list votes of election %s: %v", electionID, err)
+	}
+	defer iterator.Close()
+	result := &TallyResult{Counts: make([]int, len(election.Options))}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list votes of election %s: %v", electionID, err)
+		}
+		choice, err := strconv.Atoi(string(kv.Value))
+		if err != nil || choice < 0 || choice >= len(result.Counts) {
+			return nil, fmt.Errorf("invalid vote %q in election %s", kv.Value, electionID)
+		}
+		result.Counts[choice]++
+		result.Total++
+	}
+	election.Result = result
+	if err := putElection(ctx, election); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
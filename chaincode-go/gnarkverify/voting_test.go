@@ -0,0 +1,138 @@
+package gnarkverify
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	gvcircuits "github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/circuits"
+	"github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/prover"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// voter 测试用的选民, 在深度为 8 的选民树中的下标为 index
+type voter struct {
+	secret *big.Int
+	index  int
+}
+
+// proveVote 用同一组密钥为选民生成投票证明, 返回证明产物和 nullifier
+func proveVote(t *testing.T, p *prover.Prover, leaves []*big.Int, v voter, election string, choice int) (*prover.Artifact, string) {
+	t.Helper()
+	definition, err := gvcircuits.Lookup("vote")
+	require.NoError(t, err)
+	path, _, err := gvcircuits.MerkleProof(ecc.BN254, leaves, 8, v.index)
+	require.NoError(t, err)
+	tag, _ := new(big.Int).SetString(election, 10)
+	input := map[string]any{
+		"secret":   gvcircuits.NewElement(v.secret),
+		"election": gvcircuits.NewElement(tag),
+		"choice":   choice,
+		"index":    v.index,
+		"path":     path,
+	}
+	data, err := json.Marshal(input)
+	require.NoError(t, err)
+	_, assignment, err := definition.Decode(ecc.BN254, data)
+	require.NoError(t, err)
+	artifact, err := p.ProveAssignment(assignment)
+	require.NoError(t, err)
+	nullifier, err := gvcircuits.VoteNullifier(ecc.BN254, v.secret, tag)
+	require.NoError(t, err)
+	return artifact, nullifier.String()
+}
+
+func TestVoting(t *testing.T) {
+	transactionContext, chaincodeStub, _ := newMockContext("Org1MSP")
+	gnarkVerify := &GnarkVerifyContract{}
+	voting := &VotingContract{}
+
+	// 选民以 MiMC(secret) 登记在链上的选民树中
+	require.NoError(t, gnarkVerify.CreateMerkleTree(transactionContext, "voters", "BN254", 8, 16))
+	var voters []voter
+	var leaves []*big.Int
+	var commitments []string
+	for i := 0; i < 5; i++ {
+		secret := big.NewInt(int64(1000 + i))
+		leaf, err := gvcircuits.VoterCommitment(ecc.BN254, secret)
+		require.NoError(t, err)
+		voters = append(voters, voter{secret: secret, index: i})
+		leaves = append(leaves, leaf)
+		commitments = append(commitments, leaf.String())
+	}
+	_, err := appendLeaves(transactionContext, "voters", commitments)
+	require.NoError(t, err)
+	root, err := gnarkVerify.GetRoot(transactionContext, "voters")
+	require.NoError(t, err)
+
+	p, err := prover.Compile("groth16", "BN254", "vote", gvcircuits.NewVote(8))
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+	sample, _ := proveVote(t, p, leaves, voters[0], "7", 0)
+	require.NoError(t, registerVerifyingKey(transactionContext, "vote", "groth16", "BN254", sample.VK, schemaConfig(t, voteSchema)))
+
+	now := time.Date(2025, 8, 8, 12, 0, 0, 0, time.UTC)
+	deadline := now.Add(time.Hour).Format(time.RFC3339)
+	options := `["yes","no","abstain"]`
+	product := proveProduct(t, "groth16", "BN254", [2]int{3, 5})[0]
+	require.NoError(t, registerVerifyingKey(transactionContext, "product", "groth16", "BN254", product.VK, ""))
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "product", root, options, deadline), "4 public inputs")
+	// 公开输入个数相同但未声明 vote schema 的密钥不能用于选举
+	require.NoError(t, registerVerifyingKey(transactionContext, "unnamed", "groth16", "BN254", sample.VK, `{"schema":[{"name":"a","type":"field"},{"name":"b","type":"field"},{"name":"c","type":"field"},{"name":"d","type":"uint64"}]}`))
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "unnamed", root, options, deadline), "vote schema")
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "vote", root, `["yes","yes"]`, deadline), "unique")
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "vote", root, `["yes"]`, deadline), "between 2")
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "vote", root, options, now.Format(time.RFC3339)), "already passed")
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "vote", root, options, "tomorrow"), "invalid deadline")
+	require.NoError(t, voting.CreateElection(transactionContext, "e1", "vote", root, options, deadline))
+	require.ErrorContains(t, voting.CreateElection(transactionContext, "e1", "vote", root, options, deadline), "already exists")
+	election, err := voting.GetElection(transactionContext, "e1")
+	require.NoError(t, err)
+	require.Equal(t, electionTag(ecc.BN254, "e1").String(), election.Tag)
+
+	var proof string
+	choices := []int{0, 1, 0, 2}
+	for i, choice := range choices {
+		artifact, nullifier := proveVote(t, p, leaves, voters[i], election.Tag, choice)
+		proof = artifact.Proof
+		chaincodeStub.GetTxIDReturns(fmt.Sprintf("vote%d", i))
+		require.NoError(t, voting.CastVote(transactionContext, "e1", proof, nullifier, choice))
+		voted, err := voting.HasVoted(transactionContext, "e1", nullifier)
+		require.NoError(t, err)
+		require.True(t, voted)
+
+		if i == 0 {
+			// 重复投票, 以及把证明中的选项改成别的选项, 都被拒绝
+			err = voting.CastVote(transactionContext, "e1", proof, nullifier, choice)
+			require.ErrorIs(t, err, ErrNullifierSpent)
+			err = voting.CastVote(transactionContext, "e1", proof, nullifier, 1)
+			require.ErrorIs(t, err, ErrProofInvalid)
+		}
+	}
+	artifact, nullifier := proveVote(t, p, leaves, voters[4], election.Tag, 1)
+	proof = artifact.Proof
+	require.ErrorContains(t, voting.CastVote(transactionContext, "e1", proof, nullifier, 3), "out of range")
+	// 另一个选举中的 nullifier 不同, 同一证明不能用于其他选举
+	require.NoError(t, voting.CreateElection(transactionContext, "e2", "vote", root, options, deadline))
+	require.ErrorIs(t, voting.CastVote(transactionContext, "e2", proof, nullifier, 1), ErrProofInvalid)
+
+	_, err = voting.Tally(transactionContext, "e1")
+	require.ErrorContains(t, err, "is open until")
+
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(now.Add(time.Hour)), nil)
+	require.ErrorContains(t, voting.CastVote(transactionContext, "e1", proof, nullifier, 1), "is closed")
+	result, err := voting.Tally(transactionContext, "e1")
+	require.NoError(t, err)
+	require.Equal(t, &TallyResult{Counts: []int{2, 1, 1}, Total: 4}, result)
+	election, err = voting.GetElection(transactionContext, "e1")
+	require.NoError(t, err)
+	require.Equal(t, result, election.Result)
+
+	result, err = voting.Tally(transactionContext, "e2")
+	require.NoError(t, err)
+	require.Equal(t, &TallyResult{Counts: []int{0, 0, 0}}, result)
+}
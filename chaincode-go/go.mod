@@ -11,6 +11,7 @@ require (
 	github.com/hyperledger/fabric-protos-go v0.3.7
 	github.com/oliverustc/gnarkabc v0.0.0-20250806122357-929d396b4db1
 	github.com/stretchr/testify v1.10.0
+	google.golang.org/protobuf v1.36.3
 )
 
 require (
@@ -51,6 +52,5 @@ require (
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
 	google.golang.org/grpc v1.67.3 // indirect
-	google.golang.org/protobuf v1.36.3 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,338 @@
+// Package prover 编译电路、生成或加载密钥并生成证明, 产物可直接提交给 gnarkverify 链码验证
+package prover
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+	"github.com/consensys/gnark/test/unsafekzg"
+	"github.com/oliverustc/gnarkabc/utils"
+)
+
+const (
+	ProtocolGroth16 = "groth16"
+	ProtocolPlonk   = "plonk"
+)
+
+// Artifact 证明产物, vk/proof/witnessPublic 为 base64 编码, 与链码的输入格式一致
+type Artifact struct {
+	Protocol      string `json:"protocol"`
+	Curve         string `json:"curve"`
+	Circuit       string `json:"circuit"`
+	VK            string `json:"vk"`
+	Proof         string `json:"proof"`
+	WitnessPublic string `json:"witnessPublic"`
+}
+
+// key 证明密钥和验证密钥共同的序列化接口
+type key interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// Prover 某个电路在指定协议和曲线上编译得到的约束系统及密钥
+type Prover struct {
+	Protocol string
+	Curve    string
+	Circuit  string
+
+	curve   ecc.ID
+	circuit frontend.Circuit
+	ccs     constraint.ConstraintSystem
+	pk      key
+	vk      key
+}
+
+// BaseName 产物和密钥文件的文件名 (不含扩展名), 同一协议、曲线和电路总是相同
+func BaseName(protocol, curveName, circuitName string) string {
+	return protocol + "_" + curveName + "_" + circuitName
+}
+
+// Compile 按协议编译电路, circuit 只用于确定电路结构, 不需要赋值
+func Compile(protocol, curveName, circuitName string, circuit frontend.Circuit) (*Prover, error) {
+	curve, ok := utils.CurveMap[curveName]
+	if !ok {
+		return nil, fmt.Errorf("unknown curve %q", curveName)
+	}
+	var builder frontend.NewBuilder
+	switch protocol {
+	case ProtocolGroth16:
+		builder = r1cs.NewBuilder
+	case ProtocolPlonk:
+		builder = scs.NewBuilder
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", protocol)
+	}
+	ccs, err := frontend.Compile(curve.ScalarField(), builder, circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile circuit %s: %v", circuitName, err)
+	}
+	return &Prover{
+		Protocol: protocol,
+		Curve:    curveName,
+		Circuit:  circuitName,
+		curve:    curve,
+		circuit:  circuit,
+		ccs:      ccs,
+	}, nil
+}
+
+// Setup 生成新的密钥. PLONK 使用 unsafekzg 生成的 SRS, 只适用于开发和测试
+func (p *Prover) Setup() error {
+	switch p.Protocol {
+	case ProtocolGroth16:
+		pk, vk, err := groth16.Setup(p.ccs)
+		if err != nil {
+			return fmt.Errorf("failed to setup groth16: %v", err)
+		}
+		p.pk, p.vk = pk, vk
+	case ProtocolPlonk:
+		srs, srsLagrange, err := unsafekzg.NewSRS(p.ccs)
+		if err != nil {
+			return fmt.Errorf("failed to create kzg srs: %v", err)
+		}
+		pk, vk, err := plonk.Setup(p.ccs, srs, srsLagrange)
+		if err != nil {
+			return fmt.Errorf("failed to setup plonk: %v", err)
+		}
+		p.pk, p.vk = pk, vk
+	}
+	return nil
+}
+
+// ShapeHash 约束系统序列化后的 sha256 前 8 字节的十六进制. 同名电路的结构 (例如 Merkle 树深度、
+// 赋值决定的数组长度) 不同时约束系统不同, 密钥也不能通用
+func (p *Prover) ShapeHash() (string, error) {
+	h := sha256.New()
+	if _, err := p.ccs.WriteTo(h); err != nil {
+		return "", fmt.Errorf("failed to hash constraint system: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// keyPaths 密钥文件名包含约束系统的哈希, 电路结构变化后不会加载到旧的密钥
+func (p *Prover) keyPaths(dir string) (string, string, error) {
+	shape, err := p.ShapeHash()
+	if err != nil {
+		return "", "", err
+	}
+	base := filepath.Join(dir, BaseName(p.Protocol, p.Curve, p.Circuit)+"_"+shape)
+	return base + ".pk", base + ".vk", nil
+}
+
+// SaveKeys 将密钥写入 dir 下的 <protocol>_<curve>_<circuit>_<shape>.pk/.vk, shape 为 ShapeHash
+func (p *Prover) SaveKeys(dir string) error {
+	if p.pk == nil {
+		return errors.New("keys are not set up")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create key directory: %v", err)
+	}
+	pkPath, vkPath, err := p.keyPaths(dir)
+	if err != nil {
+		return err
+	}
+	if err := writeKey(pkPath, p.pk); err != nil {
+		return err
+	}
+	return writeKey(vkPath, p.vk)
+}
+
+// LoadKeys 从 dir 读取 SaveKeys 为同一约束系统写入的密钥
+func (p *Prover) LoadKeys(dir string) error {
+	pkPath, vkPath, err := p.keyPaths(dir)
+	if err != nil {
+		return err
+	}
+	var pk, vk key
+	switch p.Protocol {
+	case ProtocolGroth16:
+		pk, vk = groth16.NewProvingKey(p.curve), groth16.NewVerifyingKey(p.curve)
+	case ProtocolPlonk:
+		pk, vk = plonk.NewProvingKey(p.curve), plonk.NewVerifyingKey(p.curve)
+	}
+	if err := readKey(pkPath, pk); err != nil {
+		return err
+	}
+	if err := readKey(vkPath, vk); err != nil {
+		return err
+	}
+	p.pk, p.vk = pk, vk
+	return nil
+}
+
+// SetupOrLoadKeys 同一约束系统的密钥文件存在时加载, 否则重新生成并保存, 使同一目录下同一电路结构的证明共用一个验证密钥
+func (p *Prover) SetupOrLoadKeys(dir string) error {
+	pkPath, vkPath, err := p.keyPaths(dir)
+	if err != nil {
+		return err
+	}
+	if utils.CheckFileExists(pkPath) && utils.CheckFileExists(vkPath) {
+		return p.LoadKeys(dir)
+	}
+	if err := p.Setup(); err != nil {
+		return err
+	}
+	return p.SaveKeys(dir)
+}
+
+func writeKey(path string, k key) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %v", err)
+	}
+	defer file.Close()
+	if _, err := k.WriteTo(file); err != nil {
+		return fmt.Errorf("failed to write key %s: %v", path, err)
+	}
+	return nil
+}
+
+func readKey(path string, k key) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open key file: %v", err)
+	}
+	defer file.Close()
+	if _, err := k.ReadFrom(file); err != nil {
+		return fmt.Errorf("failed to read key %s: %v", path, err)
+	}
+	return nil
+}
+
+// WitnessFromAssignment 由电路赋值生成完整见证
+func (p *Prover) WitnessFromAssignment(assignment frontend.Circuit) (witness.Witness, error) {
+	fullWitness, err := frontend.NewWitness(assignment, p.curve.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %v", err)
+	}
+	return fullWitness, nil
+}
+
+// WitnessFromJSON 按电路结构解析 json 赋值, 字段名与电路结构体字段 (或 gnark 标签) 一致
+func (p *Prover) WitnessFromJSON(data []byte) (witness.Witness, error) {
+	schema, err := frontend.NewSchema(p.curve.ScalarField(), p.circuit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness schema: %v", err)
+	}
+	fullWitness, err := witness.New(p.curve.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %v", err)
+	}
+	if err := fullWitness.FromJSON(schema, data); err != nil {
+		return nil, fmt.Errorf("failed to parse assignment: %v", err)
+	}
+	return fullWitness, nil
+}
+
+// Prove 生成证明并在本地验证, 返回可提交给链码的产物
+func (p *Prover) Prove(fullWitness witness.Witness) (*Artifact, error) {
+	if p.pk == nil {
+		return nil, errors.New("keys are not set up")
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public witness: %v", err)
+	}
+
+	var proof io.WriterTo
+	switch p.Protocol {
+	case ProtocolGroth16:
+		groth16Proof, err := groth16.Prove(p.ccs, p.pk.(groth16.ProvingKey), fullWitness)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prove: %v", err)
+		}
+		if err := groth16.Verify(groth16Proof, p.vk.(groth16.VerifyingKey), publicWitness); err != nil {
+			return nil, fmt.Errorf("failed to verify proof: %v", err)
+		}
+		proof = groth16Proof
+	case ProtocolPlonk:
+		plonkProof, err := plonk.Prove(p.ccs, p.pk.(plonk.ProvingKey), fullWitness)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prove: %v", err)
+		}
+		if err := plonk.Verify(plonkProof, p.vk.(plonk.VerifyingKey), publicWitness); err != nil {
+			return nil, fmt.Errorf("failed to verify proof: %v", err)
+		}
+		proof = plonkProof
+	}
+
+	artifact := &Artifact{Protocol: p.Protocol, Curve: p.Curve, Circuit: p.Circuit}
+	if artifact.VK, err = marshalBase64(p.vk); err != nil {
+		return nil, err
+	}
+	if artifact.Proof, err = marshalBase64(proof); err != nil {
+		return nil, err
+	}
+	if artifact.WitnessPublic, err = marshalBase64(publicWitness); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// ProveAssignment 由电路赋值生成证明
+func (p *Prover) ProveAssignment(assignment frontend.Circuit) (*Artifact, error) {
+	fullWitness, err := p.WitnessFromAssignment(assignment)
+	if err != nil {
+		return nil, err
+	}
+	return p.Prove(fullWitness)
+}
+
+func marshalBase64(w io.WriterTo) (string, error) {
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return "", fmt.Errorf("failed to marshal %T: %v", w, err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// FileName 产物文件名 <protocol>_<curve>_<circuit>.json
+func (a *Artifact) FileName() string {
+	return BaseName(a.Protocol, a.Curve, a.Circuit) + ".json"
+}
+
+// Write 将产物写入 dir, 同名文件会被覆盖, 返回文件路径
+func (a *Artifact) Write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal artifact: %v", err)
+	}
+	path := filepath.Join(dir, a.FileName())
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write artifact: %v", err)
+	}
+	return path, nil
+}
+
+// ReadArtifact 读取 Write 写入的产物
+func ReadArtifact(path string) (*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %v", err)
+	}
+	var artifact Artifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal artifact %s: %v", path, err)
+	}
+	return &artifact, nil
+}
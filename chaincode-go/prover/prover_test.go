@@ -0,0 +1,109 @@
+package prover
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/oliverustc/gnarkabc/circuits"
+	"github.com/oliverustc/gnarkabc/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProveAllCurves(t *testing.T) {
+	for _, protocol := range []string{ProtocolGroth16, ProtocolPlonk} {
+		for _, curveName := range utils.CurveNameList {
+			p, err := Compile(protocol, curveName, "product", &circuits.Product{})
+			require.NoError(t, err)
+			require.NoError(t, p.Setup())
+			artifact, err := p.ProveAssignment(&circuits.Product{P: 3, Q: 5, N: 15})
+			require.NoError(t, err, "%s %s", protocol, curveName)
+			require.Equal(t, protocol+"_"+curveName+"_product.json", artifact.FileName())
+			require.NotEmpty(t, artifact.VK)
+			require.NotEmpty(t, artifact.Proof)
+			require.NotEmpty(t, artifact.WitnessPublic)
+		}
+	}
+}
+
+func TestProveInvalidAssignment(t *testing.T) {
+	p, err := Compile(ProtocolGroth16, "BN254", "product", &circuits.Product{})
+	require.NoError(t, err)
+	_, err = p.ProveAssignment(&circuits.Product{P: 3, Q: 5, N: 15})
+	require.ErrorContains(t, err, "not set up")
+
+	require.NoError(t, p.Setup())
+	_, err = p.ProveAssignment(&circuits.Product{P: 3, Q: 5, N: 16})
+	require.Error(t, err)
+
+	_, err = Compile("marlin", "BN254", "product", &circuits.Product{})
+	require.ErrorContains(t, err, "unknown protocol")
+	_, err = Compile(ProtocolGroth16, "bn256", "product", &circuits.Product{})
+	require.ErrorContains(t, err, "unknown curve")
+}
+
+func TestSetupOrLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	for _, protocol := range []string{ProtocolGroth16, ProtocolPlonk} {
+		first, err := Compile(protocol, "BN254", "product", &circuits.Product{})
+		require.NoError(t, err)
+		require.NoError(t, first.SetupOrLoadKeys(dir))
+		shape, err := first.ShapeHash()
+		require.NoError(t, err)
+		require.Len(t, shape, 16)
+		require.FileExists(t, filepath.Join(dir, protocol+"_BN254_product_"+shape+".pk"))
+		firstArtifact, err := first.ProveAssignment(&circuits.Product{P: 3, Q: 5, N: 15})
+		require.NoError(t, err)
+
+		// 第二次加载已保存的密钥, 验证密钥不变
+		second, err := Compile(protocol, "BN254", "product", &circuits.Product{})
+		require.NoError(t, err)
+		require.NoError(t, second.SetupOrLoadKeys(dir))
+		secondArtifact, err := second.ProveAssignment(&circuits.Product{P: 7, Q: 11, N: 77})
+		require.NoError(t, err)
+		require.Equal(t, firstArtifact.VK, secondArtifact.VK)
+
+		// 同名但结构不同的电路不会加载到旧的密钥
+		other, err := Compile(protocol, "BN254", "product", &squareProduct{})
+		require.NoError(t, err)
+		otherShape, err := other.ShapeHash()
+		require.NoError(t, err)
+		require.NotEqual(t, shape, otherShape)
+		require.NoError(t, other.SetupOrLoadKeys(dir))
+		otherArtifact, err := other.ProveAssignment(&squareProduct{P: 3, Q: 5, N: 45})
+		require.NoError(t, err)
+		require.NotEqual(t, firstArtifact.VK, otherArtifact.VK)
+	}
+}
+
+// squareProduct 与 product 同名使用时结构不同的电路: N = P * P * Q
+type squareProduct struct {
+	P, Q frontend.Variable
+	N    frontend.Variable `gnark:",public"`
+}
+
+func (c *squareProduct) Define(api frontend.API) error {
+	api.AssertIsEqual(c.N, api.Mul(c.P, c.P, c.Q))
+	return nil
+}
+
+func TestWitnessFromJSON(t *testing.T) {
+	p, err := Compile(ProtocolPlonk, "BLS12-377", "product", &circuits.Product{})
+	require.NoError(t, err)
+	require.NoError(t, p.Setup())
+
+	fullWitness, err := p.WitnessFromJSON([]byte(`{"P": 3, "Q": 5, "N": 15}`))
+	require.NoError(t, err)
+	artifact, err := p.Prove(fullWitness)
+	require.NoError(t, err)
+
+	path, err := artifact.Write(t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "plonk_BLS12-377_product.json", filepath.Base(path))
+	read, err := ReadArtifact(path)
+	require.NoError(t, err)
+	require.Equal(t, artifact, read)
+
+	_, err = p.WitnessFromJSON([]byte(`{"P": 3}`))
+	require.Error(t, err)
+}
@@ -8,7 +8,15 @@ import (
 )
 
 func main() {
-	gnarkVerifyCode, err := contractapi.NewChaincode(&gnarkverify.GnarkVerifyContract{})
+	gnarkVerifyCode, err := contractapi.NewChaincode(
+		&gnarkverify.GnarkVerifyContract{},
+		&gnarkverify.VotingContract{Contract: contractapi.Contract{Name: "voting"}},
+		&gnarkverify.AssetContract{Contract: contractapi.Contract{Name: "assets"}},
+		&gnarkverify.AttestationContract{Contract: contractapi.Contract{Name: "attestation"}},
+		&gnarkverify.CredentialContract{Contract: contractapi.Contract{Name: "credentials"}},
+		&gnarkverify.AuthContract{Contract: contractapi.Contract{Name: "auth"}},
+		&gnarkverify.RevocationContract{Contract: contractapi.Contract{Name: "revocation"}},
+	)
 	if err != nil {
 		log.Panicf("Error creating ZK proof chaincode: %v", err)
 	}
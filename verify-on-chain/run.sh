@@ -12,10 +12,12 @@ function networkDown() {
     popd
 }
 
+# 部署后预热一次验证密钥缓存; 链码不会自行预热, 链码容器重启后需再次执行 ./run.sh warmup
 function deployCC() {
     pushd ../../test-network
-    ./network.sh deployCC -ccn gnarkverify -ccp ../fabric-gnark-dev/chaincode-go -ccl go
+    ./network.sh deployCC -ccn gnarkverify -ccp ../fabric-gnark-dev/chaincode-go -ccl go -cccg ../fabric-gnark-dev/verify-on-chain/collections_config.json
     popd
+    warmupCache
 }
 
 #  use setEnv after pushd
@@ -45,6 +47,32 @@ function queryChainCode() {
     popd
 }
 
+# 链码进程启动后, 在每个背书节点上预先解析已登记的验证密钥
+function warmupCache() {
+    pushd ../../test-network
+    setEnv
+    peer chaincode query -C mychannel -n gnarkverify -c '{"Args":["WarmVerifyingKeyCache"]}'
+    export CORE_PEER_LOCALMSPID="Org2MSP"
+    export CORE_PEER_TLS_ROOTCERT_FILE=${PWD}/organizations/peerOrganizations/org2.example.com/peers/peer0.org2.example.com/tls/ca.crt
+    export CORE_PEER_MSPCONFIGPATH=${PWD}/organizations/peerOrganizations/org2.example.com/users/Admin@org2.example.com/msp
+    export CORE_PEER_ADDRESS=localhost:9051
+    peer chaincode query -C mychannel -n gnarkverify -c '{"Args":["WarmVerifyingKeyCache"]}'
+    popd
+}
+
+# 不带参数时查询输入大小限制, 带 json 参数时以 Org1 管理员身份修改
+function limits() {
+    limitsJSON=$1
+    pushd ../../test-network
+    setEnv
+    if [ -z "$limitsJSON" ]; then
+        peer chaincode query -C mychannel -n gnarkverify -c '{"Args":["GetLimits"]}'
+    else
+        peer chaincode invoke -o localhost:7050 --ordererTLSHostnameOverride orderer.example.com --tls --cafile ${PWD}/organizations/ordererOrganizations/example.com/orderers/orderer.example.com/msp/tlscacerts/tlsca.example.com-cert.pem -C mychannel -n gnarkverify --peerAddresses localhost:7051 --tlsRootCertFiles ${PWD}/organizations/peerOrganizations/org1.example.com/peers/peer0.org1.example.com/tls/ca.crt --peerAddresses localhost:9051 --tlsRootCertFiles ${PWD}/organizations/peerOrganizations/org2.example.com/peers/peer0.org2.example.com/tls/ca.crt -c '{"function":"SetLimits","Args":['"$(printf '%s' "$limitsJSON" | jq -Rs .)"']}'
+    fi
+    popd
+}
+
 function invokeChainCode() {
     funcName=$1
     curveName=$2
@@ -57,12 +85,15 @@ function invokeChainCode() {
     popd
 }
 
+# 产物写入 gnarkverify/output/<protocol>_<curve>_product.json, 密钥保存在 output/keys 中重复使用
 function generateJson() {
     pushd ../chaincode-go
-    mkdir -p gnarkverify/output/.backup
-    mv gnarkverify/output/*.json gnarkverify/output/.backup
-    go test -v -run TestGroth16$ github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify
-    go test -v -run TestPlonk$ github.com/infolab-bcg/fabric-gnark-dev/chaincode-go/gnarkverify
+    for curveName in "BN254" "BLS12-381" "BLS12-377" "BLS24-315" "BLS24-317" "BW6-633" "BW6-761"; do
+        go run ./cmd/prove --protocol groth16 --curve ${curveName} --circuit product --assignment ../verify-on-chain/assignments/product.json --keys gnarkverify/output/keys --out gnarkverify/output
+    done
+    for curveName in "BN254" "BLS12-381" "BLS12-377" "BLS24-315" "BLS24-317"; do
+        go run ./cmd/prove --protocol plonk --curve ${curveName} --circuit product --assignment ../verify-on-chain/assignments/product.json --keys gnarkverify/output/keys --out gnarkverify/output
+    done
     popd
 }
 
@@ -71,7 +102,7 @@ function groth16() {
     for curveName in ${curveNameList[@]}; do
         echo "============ groth16_${curveName} ============"
         pushd ../chaincode-go/gnarkverify/output
-        jsonFile=$(ls | grep groth16_${curveName})
+        jsonFile=groth16_${curveName}_product.json
         proofStr=$(cat ${jsonFile} | jq -r '.proof')
         vkStr=$(cat ${jsonFile} | jq -r '.vk')
         pubWitnessStr=$(cat ${jsonFile} | jq -r '.witnessPublic')
@@ -85,7 +116,7 @@ function plonk() {
     for curveName in ${curveNameList[@]}; do
         echo "============ plonk_${curveName} ============"
         pushd ../chaincode-go/gnarkverify/output
-        jsonFile=$(ls | grep plonk_${curveName})
+        jsonFile=plonk_${curveName}_product.json
         proofStr=$(cat ${jsonFile} | jq -r '.proof')
         vkStr=$(cat ${jsonFile} | jq -r '.vk')
         pubWitnessStr=$(cat ${jsonFile} | jq -r '.witnessPublic')
@@ -112,11 +143,15 @@ elif [ "$args" == "setenv" ]; then
     setEnv
 elif [ "$args" == "query" ]; then
     queryChainCode
+elif [ "$args" == "warmup" ]; then
+    warmupCache
+elif [ "$args" == "limits" ]; then
+    limits "$2"
 elif [ "$args" == "generate" ]; then
     generateJson
 elif [ "$args" == "verify" ]; then
     main
 else
-    echo "Usage: ./run.sh [up|down|deploy|setenv|query]"
+    echo "Usage: ./run.sh [up|down|deploy|setenv|query|warmup|limits|generate|verify]"
     exit 1
 fi
\ No newline at end of file